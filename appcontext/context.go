@@ -31,6 +31,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/student3671/app-functions-sdk-go/internal/security"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
 	"github.com/student3671/app-functions-sdk-go/pkg/util"
 )
 
@@ -45,6 +46,8 @@ type Context struct {
 	EventChecksum string
 	// This is the ID used to track the EdgeX event through entire EdgeX framework.
 	CorrelationID string
+	// ReceivedTopic is the topic the message was received on. Will be empty for triggers that are not topic based.
+	ReceivedTopic string
 	// OutputData is used for specifying the data that is to be outputted. Leverage the .Complete() function to set.
 	OutputData []byte
 	// This holds the configuration for your service. This is the preferred way to access your custom application settings that have been set in the configuration.
@@ -63,6 +66,39 @@ type Context struct {
 	RetryData []byte
 	// SecretProvider exposes the support for getting and storing secrets
 	SecretProvider security.SecretProvider
+
+	requestCtx          syscontext.Context
+	receivedPayload     []byte
+	receivedContentType string
+	codecs              *codec.Registry
+	output              outputMetadata
+	values              map[string]string
+}
+
+// RequestContext returns the context.Context scoped to the current pipeline execution. It is cancelled when the
+// pipeline's timeout expires or the service is shutting down, and should be passed to any blocking calls.
+func (context *Context) RequestContext() syscontext.Context {
+	if context.requestCtx == nil {
+		return syscontext.Background()
+	}
+	return context.requestCtx
+}
+
+// SetRequestContext sets the context.Context scoped to the current pipeline execution.
+func (context *Context) SetRequestContext(ctx syscontext.Context) {
+	context.requestCtx = ctx
+}
+
+// ReceivedMessage returns the payload and content type of the message received by the trigger, if set.
+func (context *Context) ReceivedMessage() ([]byte, string) {
+	return context.receivedPayload, context.receivedContentType
+}
+
+// SetReceivedMessage sets the payload and content type of the message received by the trigger. It is stored along
+// with the RetryData so the message can be replayed from the start of the pipeline if the pipeline changes.
+func (context *Context) SetReceivedMessage(payload []byte, contentType string) {
+	context.receivedPayload = payload
+	context.receivedContentType = contentType
 }
 
 // Complete is optional and provides a way to return the specified data.
@@ -73,6 +109,33 @@ func (context *Context) Complete(output []byte) {
 	context.OutputData = output
 }
 
+// CompleteAs encodes the data using the codec registered for the content type and sets it as the data to return,
+// as with Complete, along with the output content type. An error is returned if no codec is registered for the content type or the data can't be encoded.
+func (context *Context) CompleteAs(contentType string, data interface{}) error {
+	output, err := context.Codecs().Encode(contentType, data)
+	if err != nil {
+		return fmt.Errorf("unable to encode output data: %w", err)
+	}
+
+	context.Complete(output)
+	context.SetOutputContentType(contentType)
+	return nil
+}
+
+// Codecs returns the registry of codecs used to decode the received message, which is also used to encode the output
+// data. A registry with the default codecs is returned if not set.
+func (context *Context) Codecs() *codec.Registry {
+	if context.codecs == nil {
+		context.codecs = codec.NewRegistry()
+	}
+	return context.codecs
+}
+
+// SetCodecs sets the registry of codecs used to decode the received message and encode the output data.
+func (context *Context) SetCodecs(codecs *codec.Registry) {
+	context.codecs = codecs
+}
+
 // MarkAsPushed will make a request to CoreData to mark the event that triggered the pipeline as pushed.
 func (context *Context) MarkAsPushed() error {
 	context.LoggingClient.Debug("Marking event as pushed")
@@ -81,9 +144,9 @@ func (context *Context) MarkAsPushed() error {
 	}
 
 	if context.EventID != "" {
-		return context.EventClient.MarkPushed(syscontext.WithValue(syscontext.Background(), clients.CorrelationHeader, context.CorrelationID), context.EventID)
+		return context.EventClient.MarkPushed(syscontext.WithValue(context.RequestContext(), clients.CorrelationHeader, context.CorrelationID), context.EventID)
 	} else if context.EventChecksum != "" {
-		return context.EventClient.MarkPushedByChecksum(syscontext.WithValue(syscontext.Background(), clients.CorrelationHeader, context.CorrelationID), context.EventChecksum)
+		return context.EventClient.MarkPushedByChecksum(syscontext.WithValue(context.RequestContext(), clients.CorrelationHeader, context.CorrelationID), context.EventChecksum)
 	} else {
 		return errors.New("No EventID or EventChecksum Provided")
 	}
@@ -125,7 +188,7 @@ func (context *Context) PushToCoreData(deviceName string, readingName string, va
 	}
 
 	correlation := uuid.New().String()
-	ctx := syscontext.WithValue(syscontext.Background(), clients.CorrelationHeader, correlation)
+	ctx := syscontext.WithValue(context.RequestContext(), clients.CorrelationHeader, correlation)
 	result, err := context.EventClient.Add(ctx, newEdgeXEvent)
 	if err != nil {
 		return nil, err
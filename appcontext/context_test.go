@@ -17,6 +17,8 @@
 package appcontext
 
 import (
+	syscontext "context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -28,6 +30,7 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
 )
 
 func TestComplete(t *testing.T) {
@@ -37,6 +40,18 @@ func TestComplete(t *testing.T) {
 	assert.Equal(t, []byte(testData), ctx.OutputData)
 }
 
+func TestCompleteAs(t *testing.T) {
+	ctx := Context{}
+	err := ctx.CompleteAs(clients.ContentTypeJSON, map[string]string{"device": "thermostat"})
+	require.NoError(t, err)
+	assert.Equal(t, `{"device":"thermostat"}`, string(ctx.OutputData))
+
+	err = ctx.CompleteAs("application/x-unknown", "output data")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, codec.ErrUnsupportedContentType))
+	assert.Equal(t, `{"device":"thermostat"}`, string(ctx.OutputData), "OutputData should be unchanged")
+}
+
 var eventClient coredata.EventClient
 var lc logger.LoggingClient
 
@@ -172,3 +187,13 @@ func TestSetRetryData(t *testing.T) {
 	ctx.SetRetryData([]byte(testData))
 	assert.Equal(t, []byte(testData), ctx.RetryData)
 }
+
+func TestRequestContext(t *testing.T) {
+	ctx := Context{}
+	assert.Equal(t, syscontext.Background(), ctx.RequestContext(), "Expected Background when not set")
+
+	expected, cancel := syscontext.WithCancel(syscontext.Background())
+	defer cancel()
+	ctx.SetRequestContext(expected)
+	assert.Equal(t, expected, ctx.RequestContext())
+}
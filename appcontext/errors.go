@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"errors"
+)
+
+// ErrorKind classifies an error returned by a pipeline function so the runtime and triggers know how to handle it.
+type ErrorKind int
+
+const (
+	// ErrorKindUnclassified is used for plain errors. These are stored for later retry when the function set RetryData.
+	ErrorKindUnclassified ErrorKind = iota
+	// ErrorKindRetryable is used when the failure is transient, i.e. the export target is unavailable.
+	// The data is stored for later retry when Store and Forward is enabled and the function set RetryData.
+	ErrorKindRetryable
+	// ErrorKindPermanent is used when the failure will occur again if retried, i.e. the data is invalid.
+	// The data is never stored for later retry.
+	ErrorKindPermanent
+	// ErrorKindFiltered is used when the function intentionally stopped the pipeline for the data.
+	// This is not treated as a failure.
+	ErrorKindFiltered
+)
+
+// String returns the name of the error kind for logging
+func (kind ErrorKind) String() string {
+	switch kind {
+	case ErrorKindRetryable:
+		return "retryable"
+	case ErrorKindPermanent:
+		return "permanent"
+	case ErrorKindFiltered:
+		return "filtered"
+	default:
+		return "unclassified"
+	}
+}
+
+// PipelineError is an error returned by a pipeline function along with its classification
+type PipelineError struct {
+	Kind ErrorKind
+	Err  error
+}
+
+// Error returns the message of the wrapped error
+func (pipelineError *PipelineError) Error() string {
+	return pipelineError.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (pipelineError *PipelineError) Unwrap() error {
+	return pipelineError.Err
+}
+
+// NewRetryableError classifies the error as transient so that it is stored for later retry
+func NewRetryableError(err error) error {
+	return newPipelineError(ErrorKindRetryable, err)
+}
+
+// NewPermanentError classifies the error as one that will occur again if retried
+func NewPermanentError(err error) error {
+	return newPipelineError(ErrorKindPermanent, err)
+}
+
+// NewFilteredError returns an error which stops the pipeline without it being treated as a failure
+func NewFilteredError(reason string) error {
+	return newPipelineError(ErrorKindFiltered, errors.New(reason))
+}
+
+func newPipelineError(kind ErrorKind, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &PipelineError{Kind: kind, Err: err}
+}
+
+// ErrorKindOf returns the classification of the error, which is ErrorKindUnclassified for plain errors
+func ErrorKindOf(err error) ErrorKind {
+	var pipelineError *PipelineError
+	if errors.As(err, &pipelineError) {
+		return pipelineError.Kind
+	}
+
+	return ErrorKindUnclassified
+}
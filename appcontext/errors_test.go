@@ -0,0 +1,58 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorKindOf(t *testing.T) {
+	cause := errors.New("failed")
+
+	tests := []struct {
+		name     string
+		err      error
+		expected ErrorKind
+	}{
+		{"Plain error", cause, ErrorKindUnclassified},
+		{"Retryable", NewRetryableError(cause), ErrorKindRetryable},
+		{"Permanent", NewPermanentError(cause), ErrorKindPermanent},
+		{"Filtered", NewFilteredError("not interested"), ErrorKindFiltered},
+		{"Wrapped", fmt.Errorf("export: %w", NewPermanentError(cause)), ErrorKindPermanent},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, ErrorKindOf(test.err))
+		})
+	}
+}
+
+func TestPipelineErrorUnwrap(t *testing.T) {
+	cause := errors.New("failed")
+
+	err := NewRetryableError(cause)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, cause))
+	assert.Equal(t, cause.Error(), err.Error())
+	assert.Nil(t, NewRetryableError(nil))
+}
@@ -0,0 +1,56 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"errors"
+)
+
+// FanOut is returned as the result of a pipeline function to split the flow into parallel branches.
+// Each branch is a sub-pipeline which receives Data as its input and has its own error and Store and Forward handling.
+// A FanOut ends the flow it was returned from, so functions following it in that flow are not executed.
+// The branches are recorded when the FanOut is returned, so that a branch retried by Store and Forward is resolved
+// without invoking the function again. Once the service has restarted, the function is invoked with BranchResolution
+// as its data instead, so it must always return the same branches and should not have side effects.
+type FanOut struct {
+	// Data is passed to the first function of each branch
+	Data interface{}
+	// Branches are the sub-pipelines which are executed in parallel
+	Branches [][]AppFunction
+}
+
+// BranchResolution is passed as the data to a function returning a FanOut when its branches are needed to retry one
+// of them, since the data the function originally received isn't stored. The function must return a FanOut with the
+// same branches, i.e. by checking for BranchResolution before using its data. The FanOut's Data isn't used.
+type BranchResolution struct{}
+
+// NewFanOut returns a pipeline function which passes the result of the previous function to each of the
+// specified branches, which are then executed in parallel.
+func NewFanOut(branches ...[]AppFunction) AppFunction {
+	return func(edgexcontext *Context, params ...interface{}) (bool, interface{}) {
+		if len(params) < 1 {
+			// We didn't receive a result
+			return false, errors.New("No Data Received")
+		}
+
+		if _, ok := params[0].(BranchResolution); ok {
+			return true, FanOut{Branches: branches}
+		}
+
+		return true, FanOut{Data: params[0], Branches: branches}
+	}
+}
@@ -0,0 +1,64 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFanOut(t *testing.T) {
+	branch := func(edgexcontext *Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+
+	fanOut := NewFanOut([]AppFunction{branch}, []AppFunction{branch, branch})
+
+	continuePipeline, result := fanOut(&Context{LoggingClient: lc}, "data")
+	require.True(t, continuePipeline)
+	actual, ok := result.(FanOut)
+	require.True(t, ok, "Expected FanOut result")
+	assert.Equal(t, "data", actual.Data)
+	require.Equal(t, 2, len(actual.Branches))
+	assert.Equal(t, 1, len(actual.Branches[0]))
+	assert.Equal(t, 2, len(actual.Branches[1]))
+}
+
+func TestNewFanOutBranchResolution(t *testing.T) {
+	branch := func(edgexcontext *Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+
+	fanOut := NewFanOut([]AppFunction{branch}, []AppFunction{branch, branch})
+
+	continuePipeline, result := fanOut(&Context{LoggingClient: lc}, BranchResolution{})
+	require.True(t, continuePipeline)
+	actual, ok := result.(FanOut)
+	require.True(t, ok, "Expected FanOut result")
+	assert.Nil(t, actual.Data)
+	assert.Equal(t, 2, len(actual.Branches))
+}
+
+func TestNewFanOutNoData(t *testing.T) {
+	fanOut := NewFanOut()
+
+	continuePipeline, result := fanOut(&Context{LoggingClient: lc})
+	assert.False(t, continuePipeline)
+	assert.Error(t, result.(error))
+}
@@ -0,0 +1,286 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+)
+
+// Placeholders which can be used in the output topic template passed to ExpandTopic
+const (
+	TopicPlaceholderDeviceName    = "devicename"
+	TopicPlaceholderEventID       = "eventid"
+	TopicPlaceholderOrigin        = "origin"
+	TopicPlaceholderCorrelationID = "correlationid"
+	TopicPlaceholderReceivedTopic = "receivedtopic"
+)
+
+var topicPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Output is a message output by the pipeline, which is published by the MessageBus trigger or returned by the
+// HTTP trigger
+type Output struct {
+	// Data is the message's payload
+	Data []byte
+	// ContentType is the content type of the data. The Context's OutputContentType is used if empty.
+	ContentType string
+	// Topic is the topic the MessageBus trigger publishes the message to. The Context's OutputTopic is used if empty.
+	Topic string
+}
+
+// outputMetadata describes how the trigger returns the OutputData
+type outputMetadata struct {
+	contentType string
+	topic       string
+	statusCode  int
+	headers     http.Header
+	outputs     []Output
+	// statusSet and headersSet track the status code and headers set since the context was created or branched,
+	// including those merged from its branches, so that only they are merged into the parent context
+	statusSet  bool
+	headersSet map[string]bool
+	// mergedStatus and mergedHeaders track the status code and headers merged from the branches, so that the first
+	// branch to set them wins
+	mergedStatus  bool
+	mergedHeaders map[string]bool
+}
+
+// AddOutput adds a message to be output in addition to the OutputData, so that a single execution of the pipeline
+// can output multiple messages, i.e. one per reading, possibly to different topics.
+func (context *Context) AddOutput(output Output) {
+	context.output.outputs = append(context.output.outputs, output)
+}
+
+// AddOutputAs encodes the data using the codec registered for the content type and adds it as an output message
+// published to the topic, or the Context's OutputTopic if empty.
+func (context *Context) AddOutputAs(contentType string, data interface{}, topic string) error {
+	payload, err := context.Codecs().Encode(contentType, data)
+	if err != nil {
+		return fmt.Errorf("unable to encode output data: %w", err)
+	}
+
+	context.AddOutput(Output{Data: payload, ContentType: contentType, Topic: topic})
+	return nil
+}
+
+// Branch returns a copy of the context for a fan-out branch, so that branches executed in parallel each have their
+// own OutputData, RetryData, outputs and values. Use MergeBranch once the branch has completed. Values set by the
+// branch are not merged.
+func (context *Context) Branch() *Context {
+	branch := *context
+	branch.OutputData = nil
+	branch.RetryData = nil
+	branch.output.outputs = nil
+	if context.output.headers != nil {
+		branch.output.headers = context.output.headers.Clone()
+	}
+	branch.output.statusSet = false
+	branch.output.headersSet = nil
+	branch.output.mergedStatus = false
+	branch.output.mergedHeaders = nil
+	branch.SetValues(context.values)
+
+	return &branch
+}
+
+// MergeBranch adds the outputs of the completed fan-out branch to the context. The branch's OutputData, along with
+// its content type and topic, is only set on the context when its OutputData isn't already set. Likewise, the
+// response status code and headers set by the branch replace those of the context, unless already set by a branch
+// merged before it, so the first branch in branch order wins on a conflict.
+func (context *Context) MergeBranch(branch *Context) {
+	if branch.output.statusSet && !context.output.mergedStatus {
+		context.output.statusCode = branch.output.statusCode
+		context.output.statusSet = true
+		context.output.mergedStatus = true
+	}
+
+	for name := range branch.output.headersSet {
+		if context.output.mergedHeaders[name] {
+			continue
+		}
+		if context.output.mergedHeaders == nil {
+			context.output.mergedHeaders = make(map[string]bool)
+		}
+		context.output.mergedHeaders[name] = true
+
+		if context.output.headers == nil {
+			context.output.headers = make(http.Header)
+		}
+		context.output.headers[name] = branch.output.headers[name]
+		context.markHeaderSet(name)
+	}
+
+	if context.OutputData == nil && branch.OutputData != nil {
+		context.OutputData = branch.OutputData
+		if len(branch.output.contentType) > 0 {
+			context.output.contentType = branch.output.contentType
+		}
+		if len(branch.output.topic) > 0 {
+			context.output.topic = branch.output.topic
+		}
+	}
+
+	for _, output := range branch.output.outputs {
+		if len(output.ContentType) == 0 {
+			output.ContentType = branch.output.contentType
+		}
+		if len(output.Topic) == 0 {
+			output.Topic = branch.output.topic
+		}
+		context.AddOutput(output)
+	}
+}
+
+// Outputs returns the messages to be output by the trigger: the OutputData, if set, followed by those added with
+// AddOutput. Empty content types and topics are set to the Context's OutputContentType and OutputTopic.
+func (context *Context) Outputs() []Output {
+	outputs := make([]Output, 0, len(context.output.outputs)+1)
+	if context.OutputData != nil {
+		outputs = append(outputs, Output{Data: context.OutputData})
+	}
+	outputs = append(outputs, context.output.outputs...)
+
+	for index := range outputs {
+		if len(outputs[index].ContentType) == 0 {
+			outputs[index].ContentType = context.output.contentType
+		}
+		if len(outputs[index].Topic) == 0 {
+			outputs[index].Topic = context.output.topic
+		}
+	}
+
+	return outputs
+}
+
+// SetOutputContentType sets the content type of the OutputData. The MessageBus trigger publishes the data with this
+// content type, defaulting to JSON, and the HTTP trigger sets it as the response's Content-Type header.
+func (context *Context) SetOutputContentType(contentType string) {
+	context.output.contentType = contentType
+}
+
+// OutputContentType returns the content type of the OutputData, empty if not set
+func (context *Context) OutputContentType() string {
+	return context.output.contentType
+}
+
+// SetOutputTopic sets the topic the MessageBus trigger publishes the OutputData to, overriding the Binding
+// PublishTopic. Use ExpandTopic to build the topic from the fields of the Event.
+func (context *Context) SetOutputTopic(topic string) {
+	context.output.topic = topic
+}
+
+// OutputTopic returns the topic the OutputData is published to, empty if the Binding PublishTopic is to be used
+func (context *Context) OutputTopic() string {
+	return context.output.topic
+}
+
+// SetResponseStatusCode sets the status code of the HTTP trigger's response when the pipeline succeeds.
+// Defaults to 200 if not set.
+func (context *Context) SetResponseStatusCode(statusCode int) {
+	context.output.statusCode = statusCode
+	context.output.statusSet = true
+}
+
+// ResponseStatusCode returns the status code of the HTTP trigger's response, 0 if not set
+func (context *Context) ResponseStatusCode() int {
+	return context.output.statusCode
+}
+
+// SetResponseHeader sets the header of the HTTP trigger's response, replacing any existing values
+func (context *Context) SetResponseHeader(name string, value string) {
+	if context.output.headers == nil {
+		context.output.headers = make(http.Header)
+	}
+	context.output.headers.Set(name, value)
+	context.markHeaderSet(http.CanonicalHeaderKey(name))
+}
+
+func (context *Context) markHeaderSet(name string) {
+	if context.output.headersSet == nil {
+		context.output.headersSet = make(map[string]bool)
+	}
+	context.output.headersSet[name] = true
+}
+
+// ResponseHeaders returns the headers of the HTTP trigger's response, nil if none have been set
+func (context *Context) ResponseHeaders() http.Header {
+	return context.output.headers
+}
+
+// ExpandTopic replaces the placeholders in the topic template, i.e. 'events/{devicename}', with their values.
+// The devicename, eventid and origin placeholders are replaced with the fields of the Event passed as data, while
+// correlationid and receivedtopic are replaced with those of the Context. Placeholders are case insensitive. An error
+// is returned if a placeholder is unknown or has no value.
+func (context *Context) ExpandTopic(template string, data interface{}) (string, error) {
+	var event *models.Event
+	switch typed := data.(type) {
+	case models.Event:
+		event = &typed
+	case *models.Event:
+		event = typed
+	}
+
+	var expandErr error
+	topic := topicPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := strings.ToLower(placeholder[1 : len(placeholder)-1])
+		value, err := context.topicValue(name, event)
+		if err == nil && len(value) == 0 {
+			err = fmt.Errorf("topic placeholder '%s' has no value", placeholder)
+		}
+		if err != nil && expandErr == nil {
+			expandErr = err
+		}
+		return value
+	})
+
+	if expandErr != nil {
+		return "", expandErr
+	}
+
+	return topic, nil
+}
+
+func (context *Context) topicValue(name string, event *models.Event) (string, error) {
+	requiresEvent := name == TopicPlaceholderDeviceName || name == TopicPlaceholderEventID || name == TopicPlaceholderOrigin
+	if requiresEvent && event == nil {
+		return "", fmt.Errorf("topic placeholder '{%s}' requires an Event", name)
+	}
+
+	switch name {
+	case TopicPlaceholderCorrelationID:
+		return context.CorrelationID, nil
+	case TopicPlaceholderReceivedTopic:
+		return context.ReceivedTopic, nil
+	case TopicPlaceholderDeviceName:
+		return event.Device, nil
+	case TopicPlaceholderEventID:
+		return event.ID, nil
+	case TopicPlaceholderOrigin:
+		if event.Origin == 0 {
+			return "", nil
+		}
+		return strconv.FormatInt(event.Origin, 10), nil
+	default:
+		return "", fmt.Errorf("topic placeholder '{%s}' is not supported", name)
+	}
+}
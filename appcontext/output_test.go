@@ -0,0 +1,171 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputMetadata(t *testing.T) {
+	ctx := Context{}
+	assert.Empty(t, ctx.OutputContentType())
+	assert.Empty(t, ctx.OutputTopic())
+	assert.Zero(t, ctx.ResponseStatusCode())
+	assert.Nil(t, ctx.ResponseHeaders())
+
+	ctx.SetOutputContentType(clients.ContentTypeCBOR)
+	ctx.SetOutputTopic("events")
+	ctx.SetResponseStatusCode(http.StatusAccepted)
+	ctx.SetResponseHeader("X-Device", "thermostat")
+
+	assert.Equal(t, clients.ContentTypeCBOR, ctx.OutputContentType())
+	assert.Equal(t, "events", ctx.OutputTopic())
+	assert.Equal(t, http.StatusAccepted, ctx.ResponseStatusCode())
+	assert.Equal(t, "thermostat", ctx.ResponseHeaders().Get("X-Device"))
+}
+
+func TestExpandTopic(t *testing.T) {
+	ctx := Context{CorrelationID: "123", ReceivedTopic: "edgex/events"}
+	event := models.Event{ID: "1234", Device: "thermostat", Origin: 1471806386919}
+
+	tests := []struct {
+		Name          string
+		Template      string
+		Data          interface{}
+		Expected      string
+		ErrorExpected bool
+	}{
+		{"No placeholders", "events", nil, "events", false},
+		{"Event fields", "events/{devicename}/{eventid}/{origin}", event, "events/thermostat/1234/1471806386919", false},
+		{"Event pointer and case", "events/{DeviceName}", &event, "events/thermostat", false},
+		{"Context fields", "{receivedtopic}/{correlationid}", nil, "edgex/events/123", false},
+		{"Not an Event", "events/{devicename}", []byte{}, "", true},
+		{"Nil Event pointer", "events/{devicename}", (*models.Event)(nil), "", true},
+		{"No value", "events/{eventid}", models.Event{Device: "thermostat"}, "", true},
+		{"Unknown", "events/{readingname}", event, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual, err := ctx.ExpandTopic(test.Template, test.Data)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	ctx := Context{}
+	assert.Empty(t, ctx.Outputs())
+
+	ctx.Complete([]byte("output data"))
+	ctx.SetOutputContentType("text/plain")
+	ctx.SetOutputTopic("events")
+	ctx.AddOutput(Output{Data: []byte("reading 1")})
+	require.NoError(t, ctx.AddOutputAs(clients.ContentTypeJSON, map[string]string{"name": "temperature"}, "readings"))
+	assert.Error(t, ctx.AddOutputAs("application/x-unknown", "reading 3", ""))
+
+	expected := []Output{
+		{Data: []byte("output data"), ContentType: "text/plain", Topic: "events"},
+		{Data: []byte("reading 1"), ContentType: "text/plain", Topic: "events"},
+		{Data: []byte(`{"name":"temperature"}`), ContentType: clients.ContentTypeJSON, Topic: "readings"},
+	}
+	assert.Equal(t, expected, ctx.Outputs())
+}
+
+func TestBranch(t *testing.T) {
+	ctx := &Context{CorrelationID: "123"}
+	ctx.SetResponseHeader("X-Device", "thermostat")
+	ctx.AddOutput(Output{Data: []byte("before fan-out"), ContentType: clients.ContentTypeJSON})
+
+	branch1 := ctx.Branch()
+	branch2 := ctx.Branch()
+	assert.Equal(t, "123", branch1.CorrelationID)
+	assert.Empty(t, branch1.Outputs())
+
+	branch1.SetResponseHeader("X-Device", "branch")
+	assert.Equal(t, "thermostat", ctx.ResponseHeaders().Get("X-Device"), "Branch headers should be a copy")
+
+	branch1.SetOutputContentType("application/xml")
+	branch1.Complete([]byte("branch 1"))
+	branch1.AddOutput(Output{Data: []byte("branch 1 reading"), Topic: "readings"})
+	branch2.Complete([]byte("branch 2"))
+
+	ctx.MergeBranch(branch1)
+	ctx.MergeBranch(branch2)
+
+	expected := []Output{
+		{Data: []byte("branch 1"), ContentType: "application/xml"},
+		{Data: []byte("before fan-out"), ContentType: clients.ContentTypeJSON},
+		{Data: []byte("branch 1 reading"), ContentType: "application/xml", Topic: "readings"},
+	}
+	assert.Equal(t, expected, ctx.Outputs())
+}
+
+func TestMergeBranchResponse(t *testing.T) {
+	ctx := &Context{CorrelationID: "123"}
+	ctx.SetResponseStatusCode(http.StatusOK)
+	ctx.SetResponseHeader("X-Device", "thermostat")
+	ctx.SetResponseHeader("X-Before", "fan-out")
+
+	branch1 := ctx.Branch()
+	branch2 := ctx.Branch()
+	branch3 := ctx.Branch()
+
+	branch2.SetResponseStatusCode(http.StatusAccepted)
+	branch2.SetResponseHeader("X-Device", "branch 2")
+	branch2.SetResponseHeader("X-Branch", "2")
+	branch3.SetResponseStatusCode(http.StatusCreated)
+	branch3.SetResponseHeader("x-branch", "3")
+	branch3.SetResponseHeader("X-Other", "3")
+
+	ctx.MergeBranch(branch1)
+	ctx.MergeBranch(branch2)
+	ctx.MergeBranch(branch3)
+
+	// The first branch in branch order to set the status code or a header wins
+	assert.Equal(t, http.StatusAccepted, ctx.ResponseStatusCode())
+	assert.Equal(t, "branch 2", ctx.ResponseHeaders().Get("X-Device"))
+	assert.Equal(t, "2", ctx.ResponseHeaders().Get("X-Branch"))
+	assert.Equal(t, "3", ctx.ResponseHeaders().Get("X-Other"))
+	assert.Equal(t, "fan-out", ctx.ResponseHeaders().Get("X-Before"), "Headers not set by a branch should be kept")
+}
+
+func TestMergeNestedBranchResponse(t *testing.T) {
+	ctx := &Context{}
+	branch := ctx.Branch()
+	nested := branch.Branch()
+	nested.SetResponseStatusCode(http.StatusAccepted)
+	nested.SetResponseHeader("X-Nested", "true")
+
+	branch.MergeBranch(nested)
+	ctx.MergeBranch(branch)
+
+	assert.Equal(t, http.StatusAccepted, ctx.ResponseStatusCode())
+	assert.Equal(t, "true", ctx.ResponseHeaders().Get("X-Nested"))
+}
@@ -0,0 +1,195 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// ErrStopPipeline is returned by a typed function to stop the pipeline without an error, i.e. when the data is
+// filtered out. It may be wrapped.
+var ErrStopPipeline = errors.New("stop pipeline")
+
+var (
+	contextType = reflect.TypeOf((*Context)(nil))
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+	fanOutType  = reflect.TypeOf(FanOut{})
+)
+
+// TypedFunction is a strongly typed pipeline function of the form
+//
+//	func(edgexcontext *appcontext.Context, input In) (Out, error)
+//
+// which has been validated by NewTypedFunction. The In and Out types can be any type, i.e. models.Event or []byte.
+type TypedFunction struct {
+	name     string
+	function reflect.Value
+	// InputType is the type of the function's input parameter
+	InputType reflect.Type
+	// OutputType is the type of the function's result
+	OutputType reflect.Type
+}
+
+// NewTypedFunction validates that function is a strongly typed pipeline function. An error is returned if it
+// doesn't have the func(*appcontext.Context, In) (Out, error) signature.
+func NewTypedFunction(function interface{}) (*TypedFunction, error) {
+	if function == nil {
+		return nil, errors.New("typed function can not be nil")
+	}
+
+	value := reflect.ValueOf(function)
+	functionType := value.Type()
+	if functionType.Kind() != reflect.Func {
+		return nil, fmt.Errorf("typed function must be a func, not %s", functionType)
+	}
+
+	name := runtime.FuncForPC(value.Pointer()).Name()
+	if functionType.NumIn() != 2 || functionType.In(0) != contextType || functionType.IsVariadic() ||
+		functionType.NumOut() != 2 || functionType.Out(1) != errorType {
+		return nil, fmt.Errorf(
+			"typed function '%s' must have the signature func(*appcontext.Context, In) (Out, error), not %s",
+			name, functionType)
+	}
+
+	return &TypedFunction{
+		name:       name,
+		function:   value,
+		InputType:  functionType.In(1),
+		OutputType: functionType.Out(0),
+	}, nil
+}
+
+// Accepts returns true if the function can be passed data of the specified type. A pointer to the input type, or
+// the value of a pointer input type, is also accepted. Interface types are only checked when data is received.
+func (typed *TypedFunction) Accepts(dataType reflect.Type) bool {
+	inputType := typed.InputType
+	switch {
+	case dataType == nil:
+		return isNillable(inputType)
+	case dataType.Kind() == reflect.Interface || dataType.AssignableTo(inputType):
+		return true
+	case dataType.Kind() == reflect.Ptr && dataType.Elem().AssignableTo(inputType):
+		return true
+	case inputType.Kind() == reflect.Ptr && dataType.AssignableTo(inputType.Elem()):
+		return true
+	default:
+		return false
+	}
+}
+
+// AppFunction adapts the typed function to an AppFunction. A permanent error is returned if the data received is
+// not accepted by the function. ErrStopPipeline stops the pipeline without an error and any other error stops the
+// pipeline with that error. Otherwise the result is passed to the next function. FunctionName returns the name of
+// the typed function for the adapter.
+func (typed *TypedFunction) AppFunction() AppFunction {
+	adapter := func(edgexcontext *Context, params ...interface{}) (bool, interface{}) {
+		if len(params) < 1 {
+			// We didn't receive a result
+			return false, errors.New("No Data Received")
+		}
+
+		input, err := typed.convert(params[0])
+		if err != nil {
+			return false, NewPermanentError(err)
+		}
+
+		results := typed.function.Call([]reflect.Value{reflect.ValueOf(edgexcontext), input})
+		if err, _ := results[1].Interface().(error); err != nil {
+			if errors.Is(err, ErrStopPipeline) {
+				return false, nil
+			}
+			return false, err
+		}
+
+		return true, results[0].Interface()
+	}
+
+	functions.Store(closureOf(adapter), functionInfo{function: adapter, name: typed.name})
+	return adapter
+}
+
+// convert converts the data to the function's input type, de-referencing or taking the address as needed
+func (typed *TypedFunction) convert(data interface{}) (reflect.Value, error) {
+	inputType := typed.InputType
+	if data == nil {
+		if isNillable(inputType) {
+			return reflect.Zero(inputType), nil
+		}
+		return reflect.Value{}, fmt.Errorf("typed function '%s' expected %s, but received nil", typed.name, inputType)
+	}
+
+	value := reflect.ValueOf(data)
+	switch {
+	case value.Type().AssignableTo(inputType):
+		return value, nil
+	case value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Type().AssignableTo(inputType):
+		return value.Elem(), nil
+	case inputType.Kind() == reflect.Ptr && value.Type().AssignableTo(inputType.Elem()):
+		pointer := reflect.New(inputType.Elem())
+		pointer.Elem().Set(value)
+		return pointer, nil
+	default:
+		return reflect.Value{}, fmt.Errorf("typed function '%s' expected %s, but received %s",
+			typed.name, inputType, value.Type())
+	}
+}
+
+// NewTypedPipeline validates and adapts the typed functions to AppFunctions. The functions are chained, so an error
+// is returned if a function's result is not accepted by the next function. Also, only the last function may return a
+// FanOut since it ends the flow. If inputType is not nil, it must be accepted by the first function.
+func NewTypedPipeline(inputType reflect.Type, functions ...interface{}) ([]AppFunction, error) {
+	if len(functions) == 0 {
+		return nil, errors.New("no typed functions provided")
+	}
+
+	transforms := make([]AppFunction, len(functions))
+	previousType := inputType
+	previousName := "the pipeline input"
+	for index, function := range functions {
+		typed, err := NewTypedFunction(function)
+		if err != nil {
+			return nil, fmt.Errorf("function #%d: %s", index, err.Error())
+		}
+
+		if (index > 0 || inputType != nil) && !typed.Accepts(previousType) {
+			return nil, fmt.Errorf("function #%d '%s' expects %s, but %s is %s",
+				index, typed.name, typed.InputType, previousName, previousType)
+		}
+
+		if typed.OutputType == fanOutType && index < len(functions)-1 {
+			return nil, fmt.Errorf("function #%d '%s' returns a FanOut, so must be the last function", index, typed.name)
+		}
+
+		transforms[index] = typed.AppFunction()
+		previousType = typed.OutputType
+		previousName = fmt.Sprintf("the result of function #%d '%s'", index, typed.name)
+	}
+
+	return transforms, nil
+}
+
+func isNillable(dataType reflect.Type) bool {
+	switch dataType.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func, reflect.Chan:
+		return true
+	default:
+		return false
+	}
+}
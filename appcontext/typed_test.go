@@ -0,0 +1,189 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func deviceName(edgexcontext *Context, event models.Event) (string, error) {
+	if event.Device == "filtered" {
+		return "", fmt.Errorf("filtering: %w", ErrStopPipeline)
+	}
+	if event.Device == "" {
+		return "", errors.New("no device")
+	}
+	return event.Device, nil
+}
+
+func nameLength(edgexcontext *Context, name string) (int, error) {
+	return len(name), nil
+}
+
+func TestNewTypedFunctionInvalidSignatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		function interface{}
+	}{
+		{"Nil", nil},
+		{"Not a func", "function"},
+		{"AppFunction", func(edgexcontext *Context, params ...interface{}) (bool, interface{}) { return true, nil }},
+		{"No context", func(event models.Event) (models.Event, error) { return event, nil }},
+		{"No error", func(edgexcontext *Context, event models.Event) models.Event { return event }},
+		{"Error not last", func(edgexcontext *Context, event models.Event) (error, models.Event) { return nil, event }},
+		{"Too many inputs", func(edgexcontext *Context, event models.Event, other string) (models.Event, error) {
+			return event, nil
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := NewTypedFunction(test.function)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTypedFunctionAppFunction(t *testing.T) {
+	typed, err := NewTypedFunction(deviceName)
+	require.NoError(t, err)
+	function := typed.AppFunction()
+	ctx := &Context{LoggingClient: lc}
+
+	tests := []struct {
+		name             string
+		data             interface{}
+		expectContinue   bool
+		expectedResult   interface{}
+		expectPermanent  bool
+		expectOtherError bool
+	}{
+		{"Value", models.Event{Device: "device1"}, true, "device1", false, false},
+		{"Pointer", &models.Event{Device: "device1"}, true, "device1", false, false},
+		{"Stop", models.Event{Device: "filtered"}, false, nil, false, false},
+		{"Error", models.Event{}, false, nil, false, true},
+		{"Wrong type", "not an event", false, nil, true, false},
+		{"Nil", nil, false, nil, true, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			continuePipeline, result := function(ctx, test.data)
+
+			assert.Equal(t, test.expectContinue, continuePipeline)
+			switch {
+			case test.expectPermanent:
+				err, ok := result.(error)
+				require.True(t, ok, "Expected error result")
+				assert.Equal(t, ErrorKindPermanent, ErrorKindOf(err))
+			case test.expectOtherError:
+				err, ok := result.(error)
+				require.True(t, ok, "Expected error result")
+				assert.Equal(t, ErrorKindUnclassified, ErrorKindOf(err))
+			default:
+				assert.Equal(t, test.expectedResult, result)
+			}
+		})
+	}
+
+	continuePipeline, result := function(ctx)
+	assert.False(t, continuePipeline)
+	assert.Error(t, result.(error), "Expected error when no data received")
+}
+
+func TestTypedFunctionPointerInput(t *testing.T) {
+	typed, err := NewTypedFunction(func(edgexcontext *Context, event *models.Event) (*models.Event, error) {
+		event.Device = "updated"
+		return event, nil
+	})
+	require.NoError(t, err)
+
+	continuePipeline, result := typed.AppFunction()(&Context{LoggingClient: lc}, models.Event{Device: "device1"})
+	require.True(t, continuePipeline)
+	assert.Equal(t, "updated", result.(*models.Event).Device)
+}
+
+func TestTypedFunctionAppFunctionName(t *testing.T) {
+	typedDeviceName, err := NewTypedFunction(deviceName)
+	require.NoError(t, err)
+	typedNameLength, err := NewTypedFunction(nameLength)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasSuffix(FunctionName(typedDeviceName.AppFunction()), ".deviceName"))
+	assert.True(t, strings.HasSuffix(FunctionName(typedNameLength.AppFunction()), ".nameLength"))
+}
+
+func TestNewTypedPipeline(t *testing.T) {
+	eventType := reflect.TypeOf(models.Event{})
+	fanOut := func(edgexcontext *Context, length int) (FanOut, error) {
+		return FanOut{Data: length}, nil
+	}
+	anything := func(edgexcontext *Context, data interface{}) (interface{}, error) {
+		return data, nil
+	}
+
+	tests := []struct {
+		name        string
+		inputType   reflect.Type
+		functions   []interface{}
+		expectError bool
+	}{
+		{"Valid", eventType, []interface{}{deviceName, nameLength}, false},
+		{"Valid pointer input", reflect.TypeOf(&models.Event{}), []interface{}{deviceName}, false},
+		{"Valid no input type", nil, []interface{}{nameLength}, false},
+		{"Valid interface result", eventType, []interface{}{anything, nameLength}, false},
+		{"Valid fan out last", eventType, []interface{}{deviceName, nameLength, fanOut}, false},
+		{"No functions", eventType, nil, true},
+		{"Wrong input type", reflect.TypeOf([]byte{}), []interface{}{deviceName}, true},
+		{"Mismatched chain", eventType, []interface{}{deviceName, deviceName}, true},
+		{"Fan out not last", eventType, []interface{}{deviceName, nameLength, fanOut, anything}, true},
+		{"Invalid function", eventType, []interface{}{deviceName, "not a function"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			transforms, err := NewTypedPipeline(test.inputType, test.functions...)
+			if test.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, len(test.functions), len(transforms))
+		})
+	}
+}
+
+func TestNewTypedPipelineExecution(t *testing.T) {
+	transforms, err := NewTypedPipeline(reflect.TypeOf(models.Event{}), deviceName, nameLength)
+	require.NoError(t, err)
+
+	ctx := &Context{LoggingClient: lc}
+	continuePipeline, result := transforms[0](ctx, models.Event{Device: "device1"})
+	require.True(t, continuePipeline)
+	continuePipeline, result = transforms[1](ctx, result)
+	require.True(t, continuePipeline)
+	assert.Equal(t, len("device1"), result)
+}
@@ -0,0 +1,140 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Keys of the values set by the triggers for the received message
+const (
+	// ValueKeyReceivedTopic is the topic the message was received on, set by topic based triggers
+	ValueKeyReceivedTopic = "receivedtopic"
+	// ValueKeyRemoteAddress is the network address of the client which sent the message, set by the HTTP trigger
+	ValueKeyRemoteAddress = "remoteaddress"
+	// ValueKeyHTTPHeaderPrefix prefixes the lower case names of the request's headers, i.e. 'header.content-type',
+	// set by the HTTP trigger. Multiple values of a header are joined with commas. Headers carrying credentials, i.e.
+	// Authorization and Cookie, are not set.
+	ValueKeyHTTPHeaderPrefix = "header."
+	// ValueKeyFileName is the name of the file the message was read from, set by the file trigger
+	ValueKeyFileName = "filename"
+	// ValueKeyScheduledTime is the time, RFC3339 formatted, the execution was scheduled for, set by the schedule
+	// trigger
+	ValueKeyScheduledTime = "scheduledtime"
+)
+
+// SetValue sets the value for the key, which is available to the following functions of the pipeline for the current
+// message, including when its execution is retried by Store and Forward. The value is stored JSON encoded, so an
+// error is returned if it can't be encoded.
+func (context *Context) SetValue(key string, value interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("unable to encode value for key '%s': %w", key, err)
+	}
+
+	if context.values == nil {
+		context.values = make(map[string]string)
+	}
+	context.values[key] = string(encoded)
+	return nil
+}
+
+// GetValue decodes the value for the key into the target, which must be a pointer. False is returned if there is no
+// value for the key. An error is returned if the value can't be decoded into the target's type.
+func (context *Context) GetValue(key string, target interface{}) (bool, error) {
+	encoded, ok := context.values[key]
+	if !ok {
+		return false, nil
+	}
+
+	if err := json.Unmarshal([]byte(encoded), target); err != nil {
+		return true, fmt.Errorf("unable to decode value for key '%s': %w", key, err)
+	}
+
+	return true, nil
+}
+
+// StringValue returns the value for the key as a string. False is returned if there is no value or it isn't a string.
+func (context *Context) StringValue(key string) (string, bool) {
+	var value string
+	found, err := context.GetValue(key, &value)
+	return value, found && err == nil
+}
+
+// IntValue returns the value for the key as an int64. False is returned if there is no value or it isn't an integer.
+func (context *Context) IntValue(key string) (int64, bool) {
+	var value int64
+	found, err := context.GetValue(key, &value)
+	return value, found && err == nil
+}
+
+// FloatValue returns the value for the key as a float64. False is returned if there is no value or it isn't a number.
+func (context *Context) FloatValue(key string) (float64, bool) {
+	var value float64
+	found, err := context.GetValue(key, &value)
+	return value, found && err == nil
+}
+
+// BoolValue returns the value for the key as a bool. False is returned if there is no value or it isn't a bool.
+func (context *Context) BoolValue(key string) (bool, bool) {
+	var value bool
+	found, err := context.GetValue(key, &value)
+	return value, found && err == nil
+}
+
+// RemoveValue removes the value for the key, if any
+func (context *Context) RemoveValue(key string) {
+	delete(context.values, key)
+}
+
+// ValueKeys returns the keys which have a value, sorted
+func (context *Context) ValueKeys() []string {
+	keys := make([]string, 0, len(context.values))
+	for key := range context.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values returns a copy of the JSON encoded values keyed by their key, which is stored along with the RetryData
+func (context *Context) Values() map[string]string {
+	if len(context.values) == 0 {
+		return nil
+	}
+
+	values := make(map[string]string, len(context.values))
+	for key, value := range context.values {
+		values[key] = value
+	}
+
+	return values
+}
+
+// SetValues replaces the values with a copy of the JSON encoded values, i.e. those stored along with the RetryData
+func (context *Context) SetValues(values map[string]string) {
+	context.values = nil
+	for key, value := range values {
+		if context.values == nil {
+			context.values = make(map[string]string, len(values))
+		}
+		context.values[key] = value
+	}
+}
@@ -0,0 +1,106 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type location struct {
+	Building string
+	Floor    int
+}
+
+func TestValues(t *testing.T) {
+	ctx := Context{}
+	assert.Empty(t, ctx.ValueKeys())
+	assert.Nil(t, ctx.Values())
+
+	require.NoError(t, ctx.SetValue("level", "high"))
+	require.NoError(t, ctx.SetValue("count", 3))
+	require.NoError(t, ctx.SetValue("ratio", 0.5))
+	require.NoError(t, ctx.SetValue("alert", true))
+	require.NoError(t, ctx.SetValue("location", location{Building: "A", Floor: 2}))
+	assert.Error(t, ctx.SetValue("invalid", make(chan int)))
+
+	assert.Equal(t, []string{"alert", "count", "level", "location", "ratio"}, ctx.ValueKeys())
+
+	level, ok := ctx.StringValue("level")
+	assert.True(t, ok)
+	assert.Equal(t, "high", level)
+
+	count, ok := ctx.IntValue("count")
+	assert.True(t, ok)
+	assert.Equal(t, int64(3), count)
+
+	ratio, ok := ctx.FloatValue("ratio")
+	assert.True(t, ok)
+	assert.Equal(t, 0.5, ratio)
+
+	alert, ok := ctx.BoolValue("alert")
+	assert.True(t, ok)
+	assert.True(t, alert)
+
+	var actual location
+	found, err := ctx.GetValue("location", &actual)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, location{Building: "A", Floor: 2}, actual)
+
+	// Wrong type or missing
+	_, ok = ctx.IntValue("level")
+	assert.False(t, ok)
+	_, ok = ctx.IntValue("ratio")
+	assert.False(t, ok)
+	_, ok = ctx.StringValue("missing")
+	assert.False(t, ok)
+	found, err = ctx.GetValue("level", &actual)
+	assert.True(t, found)
+	assert.Error(t, err)
+
+	ctx.RemoveValue("count")
+	_, ok = ctx.IntValue("count")
+	assert.False(t, ok)
+}
+
+func TestSetValues(t *testing.T) {
+	source := Context{}
+	require.NoError(t, source.SetValue("level", "high"))
+	require.NoError(t, source.SetValue("count", 3))
+
+	stored := source.Values()
+	assert.Equal(t, map[string]string{"level": `"high"`, "count": "3"}, stored)
+
+	// Values are copied, so changing either context doesn't affect the other
+	ctx := Context{}
+	ctx.SetValues(stored)
+	require.NoError(t, source.SetValue("level", "low"))
+	require.NoError(t, ctx.SetValue("count", 4))
+
+	level, _ := ctx.StringValue("level")
+	assert.Equal(t, "high", level)
+	count, _ := source.IntValue("count")
+	assert.Equal(t, int64(3), count)
+
+	branch := ctx.Branch()
+	require.NoError(t, branch.SetValue("level", "branch"))
+	level, _ = ctx.StringValue("level")
+	assert.Equal(t, "high", level, "Branch values should be a copy")
+}
@@ -0,0 +1,90 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+)
+
+// functionInfo is what is known of a function returned by WrapFunction or DescribeFunction. The function is kept so
+// its closure isn't collected and the address reused by another function.
+type functionInfo struct {
+	function   AppFunction
+	name       string
+	parameters map[string]string
+}
+
+// functions maps the closure of each function returned by WrapFunction or DescribeFunction to its information
+var functions sync.Map
+
+// WrapFunction records that wrapper wraps function and returns wrapper, so that FunctionName returns the name of
+// the wrapped function rather than that of the wrapper's closure, i.e. for the metrics and spans of the pipeline.
+// The wrapped function's parameters are also those of the wrapper.
+func WrapFunction(wrapper AppFunction, function AppFunction) AppFunction {
+	functions.Store(closureOf(wrapper), functionInfo{
+		function:   wrapper,
+		name:       FunctionName(function),
+		parameters: FunctionParameters(function),
+	})
+	return wrapper
+}
+
+// DescribeFunction records the configuration parameters of the function and returns the function, so that the
+// version of a pipeline defined in code changes when the configuration of its functions changes, as it does for the
+// configurable pipeline, i.e. DescribeFunction(sender.HTTPPost, map[string]string{"url": url}). The function must be
+// a closure or method value, such as returned by a constructor, since describing a plain function describes all of
+// its uses.
+func DescribeFunction(function AppFunction, parameters map[string]string) AppFunction {
+	copied := make(map[string]string, len(parameters))
+	for key, value := range parameters {
+		copied[key] = value
+	}
+
+	functions.Store(closureOf(function), functionInfo{
+		function:   function,
+		name:       FunctionName(function),
+		parameters: copied,
+	})
+	return function
+}
+
+// FunctionName returns the name of the function, or of the function it wraps when it was returned by WrapFunction
+func FunctionName(function AppFunction) string {
+	if info, ok := functions.Load(closureOf(function)); ok {
+		return info.(functionInfo).name
+	}
+
+	return runtime.FuncForPC(reflect.ValueOf(function).Pointer()).Name()
+}
+
+// FunctionParameters returns the configuration parameters recorded by DescribeFunction, nil if none were recorded
+func FunctionParameters(function AppFunction) map[string]string {
+	if info, ok := functions.Load(closureOf(function)); ok {
+		return info.(functionInfo).parameters
+	}
+
+	return nil
+}
+
+// closureOf returns the address of the function's closure, which unlike its code pointer differs for each
+// function returned by the same function literal
+func closureOf(function AppFunction) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&function))
+}
@@ -0,0 +1,59 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appcontext
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func wrappedTransform(edgexcontext *Context, params ...interface{}) (bool, interface{}) {
+	return true, params[0]
+}
+
+func wrap(function AppFunction) AppFunction {
+	return func(edgexcontext *Context, params ...interface{}) (bool, interface{}) {
+		return function(edgexcontext, params...)
+	}
+}
+
+func TestFunctionName(t *testing.T) {
+	wrapper := wrap(wrappedTransform)
+	unwrapped := wrap(wrappedTransform)
+	nested := WrapFunction(wrap(wrapper), WrapFunction(wrapper, wrappedTransform))
+
+	expected := "github.com/student3671/app-functions-sdk-go/appcontext.wrappedTransform"
+	assert.Equal(t, expected, FunctionName(wrappedTransform))
+	assert.Equal(t, expected, FunctionName(wrapper))
+	assert.Equal(t, expected, FunctionName(nested))
+	assert.Equal(t, "github.com/student3671/app-functions-sdk-go/appcontext.wrap.func1", FunctionName(unwrapped),
+		"Name of functions not returned by WrapFunction should be unchanged")
+}
+
+func TestDescribeFunction(t *testing.T) {
+	parameters := map[string]string{"url": "http://host1"}
+	described := DescribeFunction(wrap(wrappedTransform), parameters)
+	parameters["url"] = "http://host2"
+
+	assert.Equal(t, map[string]string{"url": "http://host1"}, FunctionParameters(described),
+		"Parameters should be copied")
+	assert.Equal(t, "github.com/student3671/app-functions-sdk-go/appcontext.wrap.func1", FunctionName(described))
+	assert.Equal(t, FunctionParameters(described), FunctionParameters(WrapFunction(wrap(described), described)),
+		"Wrapper should have the wrapped function's parameters")
+	assert.Nil(t, FunctionParameters(wrap(wrappedTransform)))
+}
@@ -55,6 +55,12 @@ const (
 	CertFile         = "certfile"
 	KeyFile          = "keyfile"
 	CAFile           = "cafile"
+	ContentType      = "contenttype"
+	Rate             = "rate"
+	Burst            = "burst"
+	KeyRate          = "keyrate"
+	KeyBurst         = "keyburst"
+	Mode             = "mode"
 )
 
 // AppFunctionsSDKConfigurable contains the helper functions that return the function pointers for building the configurable function pipeline.
@@ -435,6 +441,36 @@ func (dynamic AppFunctionsSDKConfigurable) SetOutputData() appcontext.AppFunctio
 	return transform.SetOutputData
 }
 
+// SetOutputDataWithContentType sets the output data to that passed in from the previous function along with its
+// content type, i.e. application/xml after TransformToXML. The MessageBus trigger publishes the data with the content
+// type and the HTTP trigger returns it as the response's Content-Type.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) SetOutputDataWithContentType(parameters map[string]string) appcontext.AppFunction {
+	contentType, ok := parameters[ContentType]
+	if !ok || len(strings.TrimSpace(contentType)) == 0 {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + ContentType)
+		return nil
+	}
+
+	transform := transforms.NewOutputDataWithContentType(strings.TrimSpace(contentType))
+	return transform.SetOutputData
+}
+
+// SetOutputTopic sets the topic the MessageBus trigger publishes the output data to, overriding the Binding
+// PublishTopic. The Topic may contain placeholders replaced with the fields of the Event, i.e. 'events/{devicename}'.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) SetOutputTopic(parameters map[string]string) appcontext.AppFunction {
+	topic, ok := parameters[Topic]
+	if !ok || len(strings.TrimSpace(topic)) == 0 {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Topic)
+		return nil
+	}
+
+	dynamic.Sdk.LoggingClient.Debug("Output Topic Parameters", Topic, topic)
+	transform := transforms.NewOutputTopic(strings.TrimSpace(topic))
+	return transform.SetOutputTopic
+}
+
 // BatchByCount ...
 func (dynamic AppFunctionsSDKConfigurable) BatchByCount(parameters map[string]string) appcontext.AppFunction {
 	batchThreshold, ok := parameters[BatchThreshold]
@@ -506,6 +542,81 @@ func (dynamic AppFunctionsSDKConfigurable) JSONLogic(parameters map[string]strin
 	return transform.Evaluate
 }
 
+// RateLimit limits the rate at which data continues through the pipeline using a token bucket. The Rate, in messages
+// per second, and Burst set the global limit, while the KeyRate and KeyBurst set the limit per Key, i.e. 'device'.
+// Either or both limits can be set. The Mode is what is done with data exceeding the limit: delay (default), drop or
+// store for later retry by Store and Forward.
+// This function is a configuration function and returns a function pointer.
+func (dynamic AppFunctionsSDKConfigurable) RateLimit(parameters map[string]string) appcontext.AppFunction {
+	rate, err := parseRateLimitFloat(parameters, Rate)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	burst, err := parseRateLimitInt(parameters, Burst)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	keyRate, err := parseRateLimitFloat(parameters, KeyRate)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	keyBurst, err := parseRateLimitInt(parameters, KeyBurst)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	key := strings.TrimSpace(parameters[Key])
+	if keyRate > 0 && len(key) == 0 {
+		dynamic.Sdk.LoggingClient.Error("Could not find " + Key + " required by " + KeyRate)
+		return nil
+	}
+
+	limiter, err := transforms.NewRateLimiter(rate, burst, keyRate, keyBurst)
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+	transform, err := transforms.NewRateLimit(limiter, key, strings.TrimSpace(parameters[Mode]))
+	if err != nil {
+		dynamic.Sdk.LoggingClient.Error(err.Error())
+		return nil
+	}
+
+	dynamic.Sdk.LoggingClient.Debug("Rate Limit Parameters", Rate, parameters[Rate], Burst, parameters[Burst],
+		Key, key, KeyRate, parameters[KeyRate], KeyBurst, parameters[KeyBurst], Mode, transform.Mode)
+	return transform.Limit
+}
+
+func parseRateLimitFloat(parameters map[string]string, name string) (float64, error) {
+	value := strings.TrimSpace(parameters[name])
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse '%s' to a float for '%s' parameter: %w", value, name, err)
+	}
+	return parsed, nil
+}
+
+func parseRateLimitInt(parameters map[string]string, name string) (int, error) {
+	value := strings.TrimSpace(parameters[name])
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse '%s' to an int for '%s' parameter: %w", value, name, err)
+	}
+	return parsed, nil
+}
+
 // MQTTSecretSend
 func (dynamic AppFunctionsSDKConfigurable) MQTTSecretSend(parameters map[string]string) appcontext.AppFunction {
 	var err error
@@ -185,6 +185,38 @@ func TestConfigurableSetOutputData(t *testing.T) {
 	assert.NotNil(t, trx, "return result from SetOutputData should not be nil")
 }
 
+func TestConfigurableSetOutputDataWithContentType(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	params := make(map[string]string)
+	trx := configurable.SetOutputDataWithContentType(params)
+	assert.Nil(t, trx, "return result from SetOutputDataWithContentType should be nil")
+
+	params[ContentType] = "application/xml"
+	trx = configurable.SetOutputDataWithContentType(params)
+	assert.NotNil(t, trx, "return result from SetOutputDataWithContentType should not be nil")
+}
+
+func TestConfigurableSetOutputTopic(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	params := make(map[string]string)
+	trx := configurable.SetOutputTopic(params)
+	assert.Nil(t, trx, "return result from SetOutputTopic should be nil")
+
+	params[Topic] = "events/{devicename}"
+	trx = configurable.SetOutputTopic(params)
+	assert.NotNil(t, trx, "return result from SetOutputTopic should not be nil")
+}
+
 func TestConfigurableMarkAsPushed(t *testing.T) {
 	configurable := AppFunctionsSDKConfigurable{}
 
@@ -266,3 +298,32 @@ func TestConfigurableMQTTSecretSend(t *testing.T) {
 	trx := configurable.MQTTSecretSend(params)
 	assert.NotNil(t, trx, "return result from MQTTSend should not be nil")
 }
+
+func TestConfigurableRateLimit(t *testing.T) {
+	configurable := AppFunctionsSDKConfigurable{
+		Sdk: &AppFunctionsSDK{
+			LoggingClient: lc,
+		},
+	}
+
+	tests := []struct {
+		Name       string
+		Parameters map[string]string
+		ExpectNil  bool
+	}{
+		{"Global", map[string]string{Rate: "50"}, false},
+		{"Global and per device", map[string]string{Rate: "50", Burst: "10", Key: "device", KeyRate: "5", Mode: "drop"}, false},
+		{"Per key without key", map[string]string{KeyRate: "5"}, true},
+		{"No rate", map[string]string{Mode: "drop"}, true},
+		{"Invalid rate", map[string]string{Rate: "fast"}, true},
+		{"Invalid burst", map[string]string{Rate: "50", Burst: "1.5"}, true},
+		{"Invalid mode", map[string]string{Rate: "50", Mode: "discard"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			trx := configurable.RateLimit(test.Parameters)
+			assert.Equal(t, test.ExpectNil, trx == nil)
+		})
+	}
+}
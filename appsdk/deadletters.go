@@ -0,0 +1,61 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appsdk
+
+import (
+	"errors"
+
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+// DeadLetter is a message, or the data stored for later retry, which could not be processed
+type DeadLetter = runtime.DeadLetter
+
+// ErrDeadLetterNotFound is returned when the dead letter to re-inject or remove doesn't exist
+var ErrDeadLetterNotFound = runtime.ErrDeadLetterNotFound
+
+var errRuntimeNotStarted = errors.New("dead letters are not available until MakeItRun has been called")
+
+// ListDeadLetters returns the dead letters written to the store. Only available when DeadLetter is enabled
+// with the store Target.
+func (sdk *AppFunctionsSDK) ListDeadLetters() ([]DeadLetter, error) {
+	if sdk.runtime == nil {
+		return nil, errRuntimeNotStarted
+	}
+
+	return sdk.runtime.ListDeadLetters(sdk.config, sdk.LoggingClient)
+}
+
+// ReinjectDeadLetter processes the dead letter with the specified ID again and removes it if successful.
+// A received message is processed from the start, while data stored for later retry is retried with the
+// function which failed. The dead letter is kept if processing fails again.
+func (sdk *AppFunctionsSDK) ReinjectDeadLetter(id string) error {
+	if sdk.runtime == nil {
+		return errRuntimeNotStarted
+	}
+
+	return sdk.runtime.ReinjectDeadLetter(id, sdk.config, sdk.edgexClients)
+}
+
+// RemoveDeadLetter removes the dead letter with the specified ID without processing it
+func (sdk *AppFunctionsSDK) RemoveDeadLetter(id string) error {
+	if sdk.runtime == nil {
+		return errRuntimeNotStarted
+	}
+
+	return sdk.runtime.RemoveDeadLetter(id, sdk.config)
+}
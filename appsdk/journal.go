@@ -0,0 +1,221 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appsdk
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/student3671/app-functions-sdk-go/internal/journal"
+)
+
+// JournalSummary is the outcome of replaying the journal of received messages
+type JournalSummary = journal.Summary
+
+// ErrJournalReplayInProgress is returned when a journal replay is started while another is in progress
+var ErrJournalReplayInProgress = journal.ErrReplayInProgress
+
+// replayCommand is the subcommand which replays the journal instead of starting the trigger, i.e.
+// 'app-service replay -speed 10 ./journal'
+const replayCommand = "replay"
+
+// replayOptions are the options of the replay subcommand
+type replayOptions struct {
+	// path is the journal file or directory to replay, the configured Journal Directory when empty
+	path  string
+	speed float64
+}
+
+// ReplayJournal processes the messages of the journal file, or of all the journal files when path is a directory,
+// through the pipeline, returning once all have been processed or the context is done. The time between the messages
+// is divided by speed, so 1 replays at the original speed and 10 ten times faster. The messages are replayed without
+// delay when speed is 0. Any output data is discarded.
+func (sdk *AppFunctionsSDK) ReplayJournal(ctx context.Context, path string, speed float64) (JournalSummary, error) {
+	if sdk.runtime == nil {
+		return JournalSummary{}, errors.New("journal can not be replayed until MakeItRun has been called")
+	}
+
+	entries, err := journal.Read(path)
+	if err != nil {
+		return JournalSummary{}, err
+	}
+
+	if err := sdk.beginJournalReplay(); err != nil {
+		return JournalSummary{}, err
+	}
+	defer sdk.endJournalReplay()
+
+	return sdk.replayEntries(ctx, entries, speed)
+}
+
+// StartJournalReplay starts replaying the journal file in the configured Journal Directory in the background, or
+// all the journal files when file is empty, returning the number of entries to be replayed
+func (sdk *AppFunctionsSDK) StartJournalReplay(file string, speed float64) (int, error) {
+	if sdk.runtime == nil {
+		return 0, errors.New("journal can not be replayed until MakeItRun has been called")
+	}
+
+	path, err := journal.ResolvePath(sdk.config.Journal.Directory, file)
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := journal.Read(path)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := sdk.beginJournalReplay(); err != nil {
+		return 0, err
+	}
+
+	sdk.appWg.Add(1)
+	go func() {
+		defer sdk.appWg.Done()
+		defer sdk.endJournalReplay()
+
+		_, _ = sdk.replayEntries(sdk.appCtx, entries, speed)
+	}()
+
+	return len(entries), nil
+}
+
+func (sdk *AppFunctionsSDK) replayEntries(ctx context.Context, entries []journal.Entry, speed float64) (JournalSummary, error) {
+	sdk.LoggingClient.Info(fmt.Sprintf("Replaying %d journal entries at speed %g", len(entries), speed))
+
+	summary, err := sdk.runtime.ReplayJournal(ctx, entries, speed, sdk.config, sdk.edgexClients)
+	if err != nil {
+		sdk.LoggingClient.Warn(fmt.Sprintf("Journal replay stopped after %d of %d entries, %d failed",
+			summary.Replayed, len(entries), summary.Failed), "error", err.Error())
+		return summary, err
+	}
+
+	sdk.LoggingClient.Info(fmt.Sprintf("Journal replay completed, %d entries replayed, %d failed",
+		summary.Replayed, summary.Failed))
+	return summary, nil
+}
+
+func (sdk *AppFunctionsSDK) beginJournalReplay() error {
+	sdk.journalReplayLock.Lock()
+	defer sdk.journalReplayLock.Unlock()
+
+	if sdk.journalReplaying {
+		return ErrJournalReplayInProgress
+	}
+
+	sdk.journalReplaying = true
+	return nil
+}
+
+func (sdk *AppFunctionsSDK) endJournalReplay() {
+	sdk.journalReplayLock.Lock()
+	defer sdk.journalReplayLock.Unlock()
+
+	sdk.journalReplaying = false
+}
+
+// setupJournal starts capturing the messages received by the trigger to the journal when enabled
+func (sdk *AppFunctionsSDK) setupJournal() error {
+	config := sdk.config.Journal
+	if !config.Enabled {
+		return nil
+	}
+
+	writer, err := journal.NewWriter(config.Directory, int64(config.MaxFileSizeMB)*1024*1024, config.MaxFiles)
+	if err != nil {
+		return err
+	}
+
+	sdk.runtime.SetJournal(writer)
+	sdk.addDeferred(func() {
+		if err := writer.Close(); err != nil {
+			sdk.LoggingClient.Error("Unable to close journal", "error", err.Error())
+		}
+	})
+
+	sdk.LoggingClient.Info("Capturing received messages to journal", "directory", config.Directory)
+	return nil
+}
+
+// parseReplayCommand returns the options of the replay subcommand if it is the first of the arguments remaining
+// after the service's flags, otherwise nil
+func parseReplayCommand(args []string) (*replayOptions, error) {
+	if len(args) == 0 || args[0] != replayCommand {
+		return nil, nil
+	}
+
+	options := &replayOptions{}
+	flagSet := flag.NewFlagSet(replayCommand, flag.ContinueOnError)
+	flagSet.SetOutput(ioutil.Discard)
+	flagSet.Float64Var(&options.speed, "speed", 1, "")
+	if err := flagSet.Parse(args[1:]); err != nil {
+		return nil, fmt.Errorf("invalid %s command: %w", replayCommand, err)
+	}
+
+	if options.speed < 0 {
+		return nil, fmt.Errorf("invalid %s command: speed can not be negative", replayCommand)
+	}
+
+	switch flagSet.NArg() {
+	case 0:
+	case 1:
+		options.path = flagSet.Arg(0)
+	default:
+		return nil, fmt.Errorf("invalid %s command: only one journal file or directory can be replayed", replayCommand)
+	}
+
+	return options, nil
+}
+
+// runReplayCommand replays the journal specified by the replay subcommand and stops the service
+func (sdk *AppFunctionsSDK) runReplayCommand() error {
+	path := sdk.replay.path
+	if len(path) == 0 {
+		path, _ = journal.ResolvePath(sdk.config.Journal.Directory, "")
+	}
+
+	ctx, cancel := context.WithCancel(sdk.appCtx)
+	defer cancel()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case signalReceived := <-signals:
+			sdk.LoggingClient.Info("Stopping journal replay: " + signalReceived.String())
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	_, err := sdk.ReplayJournal(ctx, path, sdk.replay.speed)
+
+	sdk.appCancelCtx()
+	sdk.appWg.Wait()
+	for _, deferredFunc := range sdk.deferredFunctions {
+		deferredFunc()
+	}
+
+	return err
+}
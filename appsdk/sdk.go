@@ -18,6 +18,7 @@ package appsdk
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	nethttp "net/http"
@@ -29,10 +30,12 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
 	"github.com/edgexfoundry/go-mod-registry/registry"
 	"github.com/gorilla/mux"
 
@@ -53,9 +56,15 @@ import (
 	"github.com/student3671/app-functions-sdk-go/internal/security"
 	"github.com/student3671/app-functions-sdk-go/internal/store/db/interfaces"
 	"github.com/student3671/app-functions-sdk-go/internal/trigger"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger/file"
 	"github.com/student3671/app-functions-sdk-go/internal/trigger/http"
 	"github.com/student3671/app-functions-sdk-go/internal/trigger/messagebus"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger/mqtt"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger/schedule"
 	"github.com/student3671/app-functions-sdk-go/internal/webserver"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
 	"github.com/student3671/app-functions-sdk-go/pkg/util"
 )
 
@@ -90,10 +99,20 @@ type AppFunctionsSDK struct {
 	// LoggingClient is the EdgeX logger client used to log messages
 	LoggingClient logger.LoggingClient
 	// TargetType is the expected type of the incoming data. Must be set to a pointer to an instance of the type.
-	// Defaults to &models.Event{} if nil. The income data is unmarshaled (JSON or CBOR) in to the type,
-	// except when &[]byte{} is specified. In this case the []byte data is pass to the first function in the Pipeline.
+	// Defaults to &models.Event{} if nil. The income data is unmarshaled in to the type using the codec registered
+	// for its content type (JSON, CBOR and XML by default, see RegisterCodec), except when &[]byte{} is specified. In this case the []byte data is pass to the first function in the Pipeline.
 	TargetType                interface{}
 	transforms                []appcontext.AppFunction
+	pipelines                 []runtime.FunctionPipeline
+	pipelineTimeouts          map[string]time.Duration
+	pipelineVersions          map[string]string
+	configurableTransforms    []appcontext.AppFunction
+	configurableDescriptors   []runtime.FunctionDescriptor
+	keyExtractor              messagebus.KeyExtractor
+	customTriggerFactories    map[string]TriggerFactory
+	deduplicationKeyExtractor runtime.DeduplicationKeyExtractor
+	codecs                    *codec.Registry
+	tracingExporter           tracing.Exporter
 	skipVersionCheck          bool
 	usingConfigurablePipeline bool
 	httpErrors                chan error
@@ -111,6 +130,9 @@ type AppFunctionsSDK struct {
 	appCancelCtx              context.CancelFunc
 	deferredFunctions         []bootstrap.Deferred
 	serviceKeyOverride        string
+	replay                    *replayOptions
+	journalReplayLock         sync.Mutex
+	journalReplaying          bool
 }
 
 // AddRoute allows you to leverage the existing webserver to add routes.
@@ -118,8 +140,13 @@ func (sdk *AppFunctionsSDK) AddRoute(route string, handler func(nethttp.Response
 	if route == clients.ApiPingRoute ||
 		route == clients.ApiConfigRoute ||
 		route == clients.ApiMetricsRoute ||
+		route == internal.ApiPrometheusMetricsRoute ||
 		route == clients.ApiVersionRoute ||
-		route == internal.ApiTriggerRoute {
+		route == internal.ApiTriggerRoute ||
+		route == internal.ApiDeadLettersRoute ||
+		route == internal.ApiDeadLetterByIdRoute ||
+		route == internal.ApiDeadLetterReinjectRoute ||
+		route == internal.ApiJournalReplayRoute {
 		return errors.New("route is reserved")
 	}
 	return sdk.webserver.AddRoute(route, sdk.addContext(handler), methods...)
@@ -138,9 +165,52 @@ func (sdk *AppFunctionsSDK) MakeItRun() error {
 	}
 
 	sdk.runtime.Initialize(sdk.storeClient, sdk.secretProvider)
-	sdk.runtime.SetTransforms(sdk.transforms)
+	sdk.runtime.SetDeduplicationKeyExtractor(sdk.deduplicationKeyExtractor)
+	sdk.runtime.SetCodecs(sdk.Codecs())
+	if len(sdk.transforms) > 0 {
+		sdk.runtime.SetTransformsWithDescriptors(sdk.transforms, sdk.descriptorsFor(sdk.transforms))
+	}
+
+	for _, pipeline := range sdk.pipelines {
+		if err := sdk.runtime.AddFunctionsPipeline(pipeline); err != nil {
+			return err
+		}
+	}
+
+	for id, timeout := range sdk.pipelineTimeouts {
+		if err := sdk.runtime.SetPipelineTimeout(id, timeout); err != nil {
+			return err
+		}
+	}
+
+	for id, version := range sdk.pipelineVersions {
+		if err := sdk.runtime.SetPipelineVersion(id, version); err != nil {
+			return err
+		}
+	}
+
+	if sdk.config.DeadLetter.Enabled && strings.EqualFold(sdk.config.DeadLetter.Target, runtime.DeadLetterTargetStore) {
+		sdk.webserver.SetupDeadLetterRoutes(sdk)
+	}
+
+	if err := sdk.setupTracing(); err != nil {
+		return err
+	}
+
+	if sdk.replay != nil {
+		return sdk.runReplayCommand()
+	}
+
+	if err := sdk.setupJournal(); err != nil {
+		return err
+	}
+	sdk.webserver.SetupJournalRoutes(sdk)
+
 	// determine input type and create trigger for it
 	t := sdk.setupTrigger(sdk.config, sdk.runtime)
+	if t == nil {
+		return fmt.Errorf("unable to set up the trigger for Binding Type '%s'", sdk.config.Binding.Type)
+	}
 
 	// Initialize the trigger (i.e. start a web server, or connect to message bus)
 	deferred, err := t.Initialize(sdk.appWg, sdk.appCtx)
@@ -159,7 +229,7 @@ func (sdk *AppFunctionsSDK) MakeItRun() error {
 
 	sdk.LoggingClient.Info(sdk.config.Service.StartupMsg)
 
-	signals := make(chan os.Signal)
+	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
 
 	sdk.webserver.StartWebServer(sdk.httpErrors)
@@ -193,6 +263,7 @@ func (sdk *AppFunctionsSDK) MakeItRun() error {
 // LoadConfigurablePipeline ...
 func (sdk *AppFunctionsSDK) LoadConfigurablePipeline() ([]appcontext.AppFunction, error) {
 	var pipeline []appcontext.AppFunction
+	var descriptors []runtime.FunctionDescriptor
 
 	sdk.usingConfigurablePipeline = true
 
@@ -257,10 +328,22 @@ func (sdk *AppFunctionsSDK) LoadConfigurablePipeline() ([]appcontext.AppFunction
 		if !ok {
 			return nil, fmt.Errorf("failed to cast function %s as AppFunction type", functionName)
 		}
+
+		if len(strings.TrimSpace(configuration.Condition)) > 0 {
+			condition, err := transforms.NewCondition(configuration.Condition)
+			if err != nil {
+				return nil, fmt.Errorf("function %s has an invalid condition: %w", functionName, err)
+			}
+			function = condition.Wrap(function)
+		}
 		pipeline = append(pipeline, function)
+		descriptors = append(descriptors, newFunctionDescriptor(functionName, configuration))
 		configurable.Sdk.LoggingClient.Debug(fmt.Sprintf("%s function added to configurable pipeline", functionName))
 	}
 
+	sdk.configurableTransforms = pipeline
+	sdk.configurableDescriptors = descriptors
+
 	return pipeline, nil
 }
 
@@ -274,13 +357,210 @@ func (sdk *AppFunctionsSDK) SetFunctionsPipeline(transforms ...appcontext.AppFun
 	sdk.transforms = transforms
 
 	if sdk.runtime != nil {
-		sdk.runtime.SetTransforms(transforms)
+		sdk.runtime.SetTransformsWithDescriptors(transforms, sdk.descriptorsFor(transforms))
 		sdk.runtime.TargetType = sdk.TargetType
 	}
 
 	return nil
 }
 
+// TypedFunctions validates and adapts strongly typed functions of the form
+// func(*appcontext.Context, In) (Out, error) to AppFunctions for use with SetFunctionsPipeline or any of the
+// AddFunctionsPipelineFor functions. An error is returned if a function doesn't have this signature or doesn't accept
+// the result of the previous function. The first function must accept the TargetType, so TargetType must be set
+// before calling TypedFunctions. Return appcontext.ErrStopPipeline from a function to stop the pipeline without an error.
+func (sdk *AppFunctionsSDK) TypedFunctions(functions ...interface{}) ([]appcontext.AppFunction, error) {
+	inputType := reflect.TypeOf(models.Event{})
+	if sdk.TargetType != nil {
+		if reflect.TypeOf(sdk.TargetType).Kind() != reflect.Ptr {
+			return nil, errors.New("TargetType must be a pointer, not a value of the target type")
+		}
+		inputType = reflect.TypeOf(sdk.TargetType).Elem()
+	}
+
+	return appcontext.NewTypedPipeline(inputType, functions...)
+}
+
+// AddFunctionsPipelineForTopics adds a named functions pipeline which is only executed for messages received on
+// one of the specified topics. The MQTT style '+' and '#' wildcards are supported, using '/' as the level separator.
+// Messages that are not selected by any named pipeline are executed by the pipeline set via SetFunctionsPipeline.
+func (sdk *AppFunctionsSDK) AddFunctionsPipelineForTopics(id string, topics []string, transforms ...appcontext.AppFunction) error {
+	if len(topics) == 0 {
+		return fmt.Errorf("no topics provided for pipeline '%s'", id)
+	}
+
+	return sdk.addFunctionsPipeline(runtime.FunctionPipeline{Id: id, Topics: topics, Transforms: transforms})
+}
+
+// AddFunctionsPipelineForContentTypes adds a named functions pipeline which is only executed for messages
+// with one of the specified content types.
+// Messages that are not selected by any named pipeline are executed by the pipeline set via SetFunctionsPipeline.
+func (sdk *AppFunctionsSDK) AddFunctionsPipelineForContentTypes(id string, contentTypes []string, transforms ...appcontext.AppFunction) error {
+	if len(contentTypes) == 0 {
+		return fmt.Errorf("no content types provided for pipeline '%s'", id)
+	}
+
+	return sdk.addFunctionsPipeline(runtime.FunctionPipeline{Id: id, ContentTypes: contentTypes, Transforms: transforms})
+}
+
+// AddFunctionsPipelineWhen adds a named functions pipeline which is only executed when the predicate returns true.
+// The predicate receives the decoded TargetType, i.e. models.Event when using the default TargetType.
+// Messages that are not selected by any named pipeline are executed by the pipeline set via SetFunctionsPipeline.
+func (sdk *AppFunctionsSDK) AddFunctionsPipelineWhen(id string, predicate func(target interface{}) bool, transforms ...appcontext.AppFunction) error {
+	if predicate == nil {
+		return fmt.Errorf("no predicate provided for pipeline '%s'", id)
+	}
+
+	return sdk.addFunctionsPipeline(runtime.FunctionPipeline{Id: id, Predicate: predicate, Transforms: transforms})
+}
+
+// SetPipelineTimeout sets the maximum duration of each execution of the specified pipeline, overriding the
+// Pipeline Timeout from configuration. Use runtime.DefaultPipelineId for the pipeline set via SetFunctionsPipeline.
+// The functions' request context is cancelled once the timeout expires, which stops the built-in export functions.
+func (sdk *AppFunctionsSDK) SetPipelineTimeout(id string, timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("timeout for pipeline '%s' can not be negative", id)
+	}
+
+	if sdk.runtime != nil {
+		if err := sdk.runtime.SetPipelineTimeout(id, timeout); err != nil {
+			return err
+		}
+	}
+
+	if sdk.pipelineTimeouts == nil {
+		sdk.pipelineTimeouts = make(map[string]time.Duration)
+	}
+	sdk.pipelineTimeouts[id] = timeout
+
+	return nil
+}
+
+// SetPipelineVersion sets the version of the specified pipeline used by Store and Forward, overriding the
+// fingerprint calculated from the pipeline's functions. The fingerprint only includes the configuration of functions
+// defined in code when described with appcontext.DescribeFunction. Data stored for later retry is handled per the StoreAndForward
+// VersionChangePolicy when the version changes, so set an explicit version to keep the stored data across releases
+// which don't affect it. Use runtime.DefaultPipelineId for the pipeline set via SetFunctionsPipeline.
+func (sdk *AppFunctionsSDK) SetPipelineVersion(id string, version string) error {
+	if len(strings.TrimSpace(version)) == 0 {
+		return fmt.Errorf("version for pipeline '%s' can not be empty", id)
+	}
+
+	if sdk.runtime != nil {
+		if err := sdk.runtime.SetPipelineVersion(id, version); err != nil {
+			return err
+		}
+	}
+
+	if sdk.pipelineVersions == nil {
+		sdk.pipelineVersions = make(map[string]string)
+	}
+	sdk.pipelineVersions[id] = version
+
+	return nil
+}
+
+// SetOrderingKeyExtractor enables ordered processing for the MessageBus trigger using the specified function to
+// extract the key of each received message. Messages with the same key are processed sequentially in the order
+// received, while messages with different keys are processed in parallel. Takes precedence over the
+// Binding WorkerPool OrderingKey setting. Must be called before MakeItRun.
+func (sdk *AppFunctionsSDK) SetOrderingKeyExtractor(extractor func(envelope types.MessageEnvelope) string) error {
+	if extractor == nil {
+		return errors.New("ordering key extractor can not be nil")
+	}
+
+	sdk.keyExtractor = extractor
+	return nil
+}
+
+// SetDeduplicationKeyExtractor sets the function used to extract the key identifying duplicate messages, taking
+// precedence over the Deduplication Key setting. The function receives the received message and its decoded
+// TargetType, i.e. models.Event when using the default TargetType. Messages for which an empty key is returned are
+// not de-duplicated. Deduplication must be enabled in configuration. Must be called before MakeItRun.
+func (sdk *AppFunctionsSDK) SetDeduplicationKeyExtractor(extractor func(envelope types.MessageEnvelope, target interface{}) string) error {
+	if extractor == nil {
+		return errors.New("de-duplication key extractor can not be nil")
+	}
+
+	sdk.deduplicationKeyExtractor = extractor
+	return nil
+}
+
+// RegisterCodec registers the codec used to decode received messages of its content type into the TargetType and
+// to encode output data of its content type, i.e. via edgexcontext.CompleteAs. A codec registered for the content
+// type of one of the default JSON, CBOR or XML codecs replaces it. Codecs implementing codec.Sniffer are also used to
+// detect the format of messages received without a content type. Must be called before MakeItRun.
+func (sdk *AppFunctionsSDK) RegisterCodec(payloadCodec codec.Codec) error {
+	return sdk.Codecs().Register(payloadCodec)
+}
+
+// Codecs returns the registry of codecs used to decode the received messages and encode the output data
+func (sdk *AppFunctionsSDK) Codecs() *codec.Registry {
+	if sdk.codecs == nil {
+		sdk.codecs = codec.NewRegistry()
+	}
+	return sdk.codecs
+}
+
+// descriptorsFor returns the descriptors of the configurable pipeline functions if transforms are the functions
+// loaded by LoadConfigurablePipeline, otherwise nil so the function names are used for the pipeline's version.
+func (sdk *AppFunctionsSDK) descriptorsFor(transforms []appcontext.AppFunction) []runtime.FunctionDescriptor {
+	if !sdk.usingConfigurablePipeline || len(transforms) != len(sdk.configurableTransforms) {
+		return nil
+	}
+
+	for index, function := range transforms {
+		if reflect.ValueOf(function).Pointer() != reflect.ValueOf(sdk.configurableTransforms[index]).Pointer() {
+			return nil
+		}
+	}
+
+	return sdk.configurableDescriptors
+}
+
+// newFunctionDescriptor describes the configurable pipeline function by its name and configuration. The parameter
+// names are lower cased since their case is ignored.
+func newFunctionDescriptor(name string, configuration common.PipelineFunction) runtime.FunctionDescriptor {
+	descriptor := runtime.FunctionDescriptor{
+		Name:       name,
+		Parameters: make(map[string]string),
+		Condition:  strings.TrimSpace(configuration.Condition),
+	}
+
+	for key, value := range configuration.Parameters {
+		descriptor.Parameters[strings.ToLower(key)] = value
+	}
+
+	if !reflect.DeepEqual(configuration.Addressable, models.Addressable{}) {
+		addressable, _ := json.Marshal(configuration.Addressable)
+		descriptor.Parameters["addressable"] = string(addressable)
+	}
+
+	return descriptor
+}
+
+func (sdk *AppFunctionsSDK) addFunctionsPipeline(pipeline runtime.FunctionPipeline) error {
+	if len(pipeline.Transforms) == 0 {
+		return fmt.Errorf("no transforms provided to pipeline '%s'", pipeline.Id)
+	}
+
+	for _, existing := range sdk.pipelines {
+		if existing.Id == pipeline.Id {
+			return fmt.Errorf("pipeline with Id '%s' already exists", pipeline.Id)
+		}
+	}
+
+	if sdk.runtime != nil {
+		if err := sdk.runtime.AddFunctionsPipeline(pipeline); err != nil {
+			return err
+		}
+	}
+
+	sdk.pipelines = append(sdk.pipelines, pipeline)
+
+	return nil
+}
+
 // ApplicationSettings returns the values specifed in the custom configuration section.
 func (sdk *AppFunctionsSDK) ApplicationSettings() map[string]string {
 	return sdk.config.ApplicationSettings
@@ -311,7 +591,10 @@ func (sdk *AppFunctionsSDK) Initialize() error {
 		"    -s/--skipVersionCheck           Indicates the service should skip the Core Service's version compatibility check.\n" +
 			"    -sk/--serviceKey                Overrides the service service key used with Registry and/or Configuration Providers.\n" +
 			"                                    If the name provided contains the text `<profile>`, this text will be replaced with\n" +
-			"                                    the name of the profile used."
+			"                                    the name of the profile used.\n" +
+			"    replay [-speed <factor>] [<path>] Replays the journal file or directory, the configured Journal Directory\n" +
+			"                                    by default, through the pipeline and exits instead of starting the trigger.\n" +
+			"                                    The time between messages is divided by the speed factor, 0 for no delay."
 
 	sdkFlags := flags.NewWithUsage(additionalUsage)
 	sdkFlags.FlagSet.BoolVar(&sdk.skipVersionCheck, "skipVersionCheck", false, "")
@@ -321,6 +604,12 @@ func (sdk *AppFunctionsSDK) Initialize() error {
 
 	sdkFlags.Parse(os.Args[1:])
 
+	replay, err := parseReplayCommand(sdkFlags.FlagSet.Args())
+	if err != nil {
+		return err
+	}
+	sdk.replay = replay
+
 	// Temporarily setup logging to STDOUT so the client can be used before bootstrapping is completed
 	sdk.LoggingClient = logger.NewClientStdOut(sdk.ServiceKey, false, "INFO")
 
@@ -427,7 +716,20 @@ func (sdk *AppFunctionsSDK) setupTrigger(configuration *common.ConfigurationStru
 		t = &http.Trigger{Configuration: configuration, Runtime: runtime, Webserver: sdk.webserver, EdgeXClients: sdk.edgexClients}
 	case "MESSAGEBUS":
 		sdk.LoggingClient.Info("MessageBus trigger selected")
-		t = &messagebus.Trigger{Configuration: configuration, Runtime: runtime, EdgeXClients: sdk.edgexClients}
+		t = &messagebus.Trigger{Configuration: configuration, Runtime: runtime, EdgeXClients: sdk.edgexClients,
+			KeyExtractor: sdk.keyExtractor}
+	case "MQTT":
+		sdk.LoggingClient.Info("MQTT trigger selected")
+		t = &mqtt.Trigger{Configuration: configuration, Runtime: runtime, EdgeXClients: sdk.edgexClients,
+			SecretProvider: sdk.secretProvider}
+	case "FILE":
+		sdk.LoggingClient.Info("File trigger selected")
+		t = &file.Trigger{Configuration: configuration, Runtime: runtime, EdgeXClients: sdk.edgexClients}
+	case "SCHEDULE":
+		sdk.LoggingClient.Info("Schedule trigger selected")
+		t = &schedule.Trigger{Configuration: configuration, Runtime: runtime, EdgeXClients: sdk.edgexClients}
+	default:
+		t = sdk.setupCustomTrigger(configuration, runtime)
 	}
 
 	return t
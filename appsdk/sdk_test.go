@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gorilla/mux"
@@ -30,10 +31,14 @@ import (
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 
 	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger/file"
 	triggerHttp "github.com/student3671/app-functions-sdk-go/internal/trigger/http"
 	"github.com/student3671/app-functions-sdk-go/internal/trigger/messagebus"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger/mqtt"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger/schedule"
 	"github.com/student3671/app-functions-sdk-go/internal/webserver"
 )
 
@@ -68,6 +73,23 @@ func TestAddRoute(t *testing.T) {
 
 }
 
+func TestAddRouteReserved(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		webserver: webserver.NewWebServer(&common.ConfigurationStruct{}, nil, lc, mux.NewRouter()),
+	}
+
+	reserved := []string{
+		internal.ApiDeadLettersRoute,
+		internal.ApiDeadLetterByIdRoute,
+		internal.ApiDeadLetterReinjectRoute,
+		internal.ApiJournalReplayRoute,
+	}
+	for _, route := range reserved {
+		err := sdk.AddRoute(route, func(http.ResponseWriter, *http.Request) {}, http.MethodGet)
+		assert.Error(t, err, "Expected route '%s' to be reserved", route)
+	}
+}
+
 func TestSetupHTTPTrigger(t *testing.T) {
 	sdk := AppFunctionsSDK{
 		LoggingClient: lc,
@@ -102,6 +124,57 @@ func TestSetupMessageBusTrigger(t *testing.T) {
 	assert.True(t, result, "Expected Instance of Message Bus Trigger")
 }
 
+func TestSetupMQTTTrigger(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "mqtt",
+			},
+		},
+	}
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms(sdk.transforms)
+	trigger := sdk.setupTrigger(sdk.config, testRuntime)
+	result := IsInstanceOf(trigger, (*mqtt.Trigger)(nil))
+	assert.True(t, result, "Expected Instance of MQTT Trigger")
+}
+
+func TestSetupFileTrigger(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "File",
+			},
+		},
+	}
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms(sdk.transforms)
+	trigger := sdk.setupTrigger(sdk.config, testRuntime)
+	result := IsInstanceOf(trigger, (*file.Trigger)(nil))
+	assert.True(t, result, "Expected Instance of File Trigger")
+}
+
+func TestSetupScheduleTrigger(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "schedule",
+			},
+		},
+	}
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms(sdk.transforms)
+	trigger := sdk.setupTrigger(sdk.config, testRuntime)
+	result := IsInstanceOf(trigger, (*schedule.Trigger)(nil))
+	assert.True(t, result, "Expected Instance of Schedule Trigger")
+}
+
 func TestSetFunctionsPipelineNoTransforms(t *testing.T) {
 	sdk := AppFunctionsSDK{
 		LoggingClient: lc,
@@ -136,6 +209,76 @@ func TestSetFunctionsPipelineOneTransform(t *testing.T) {
 	assert.Equal(t, 1, len(sdk.transforms))
 }
 
+func TestAddFunctionsPipelineForTopics(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		runtime:       &runtime.GolangRuntime{},
+	}
+	function := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+
+	sdk.runtime.Initialize(nil, nil)
+
+	err := sdk.AddFunctionsPipelineForTopics("device1", []string{"edgex/events/device1/#"}, function)
+	require.NoError(t, err)
+	require.NotNil(t, sdk.runtime.GetPipelineById("device1"))
+
+	err = sdk.AddFunctionsPipelineForTopics("device1", []string{"edgex/events/device1/#"}, function)
+	require.Error(t, err, "Expected error for duplicate pipeline Id")
+
+	err = sdk.AddFunctionsPipelineForTopics("device2", nil, function)
+	require.Error(t, err, "Expected error for missing topics")
+
+	err = sdk.AddFunctionsPipelineForTopics("device3", []string{"edgex/events/device3"})
+	require.Error(t, err, "Expected error for missing transforms")
+}
+
+func TestAddFunctionsPipelineWhen(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+	}
+	function := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+	predicate := func(target interface{}) bool {
+		return true
+	}
+
+	// Runtime not created yet, so pipeline is held until MakeItRun
+	err := sdk.AddFunctionsPipelineWhen("predicate", predicate, function)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(sdk.pipelines))
+
+	err = sdk.AddFunctionsPipelineWhen("nilPredicate", nil, function)
+	require.Error(t, err, "Expected error for missing predicate")
+}
+
+func TestTypedFunctions(t *testing.T) {
+	eventFunction := func(edgexcontext *appcontext.Context, event models.Event) ([]byte, error) {
+		return []byte(event.Device), nil
+	}
+	bytesFunction := func(edgexcontext *appcontext.Context, data []byte) (string, error) {
+		return string(data), nil
+	}
+
+	sdk := AppFunctionsSDK{LoggingClient: lc}
+
+	transforms, err := sdk.TypedFunctions(eventFunction, bytesFunction)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(transforms))
+
+	_, err = sdk.TypedFunctions(bytesFunction)
+	assert.Error(t, err, "Expected error since the first function doesn't accept the default TargetType")
+
+	sdk.TargetType = &[]byte{}
+	_, err = sdk.TypedFunctions(bytesFunction)
+	assert.NoError(t, err)
+
+	_, err = sdk.TypedFunctions(bytesFunction, bytesFunction)
+	assert.Error(t, err, "Expected error since the second function doesn't accept the first function's result")
+}
+
 func TestApplicationSettings(t *testing.T) {
 	expectedSettingKey := "ApplicationName"
 	expectedSettingValue := "simple-filter-xml"
@@ -308,6 +451,113 @@ func TestLoadConfigurablePipelineNumFunctions(t *testing.T) {
 	assert.Equal(t, 3, len(appFunctions))
 }
 
+func TestLoadConfigurablePipelineVersion(t *testing.T) {
+	functions := make(map[string]common.PipelineFunction)
+	functions["FilterByDeviceName"] = common.PipelineFunction{
+		Parameters: map[string]string{"FilterValues": "Random-Float-Device"},
+	}
+	functions["SetOutputData"] = common.PipelineFunction{}
+
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		runtime:       &runtime.GolangRuntime{},
+		config: &common.ConfigurationStruct{
+			Writable: common.WritableInfo{
+				Pipeline: common.PipelineInfo{
+					ExecutionOrder: "FilterByDeviceName, SetOutputData",
+					Functions:      functions,
+				},
+			},
+		},
+	}
+	sdk.runtime.Initialize(nil, nil)
+
+	loadVersion := func() string {
+		appFunctions, err := sdk.LoadConfigurablePipeline()
+		require.NoError(t, err)
+		require.NoError(t, sdk.SetFunctionsPipeline(appFunctions...))
+		return sdk.runtime.GetDefaultPipeline().Hash
+	}
+
+	original := loadVersion()
+	assert.Equal(t, original, loadVersion(), "Reloading the same configuration should not change the version")
+
+	functions["FilterByDeviceName"].Parameters["FilterValues"] = "Random-Integer-Device"
+	changed := loadVersion()
+	assert.NotEqual(t, original, changed, "Changing a parameter should change the version")
+
+	functions["SetOutputData"] = common.PipelineFunction{Condition: "size > 0"}
+	conditional := loadVersion()
+	assert.NotEqual(t, changed, conditional, "Adding a condition should change the version")
+	functions["SetOutputData"] = common.PipelineFunction{}
+	assert.Equal(t, changed, loadVersion())
+
+	appFunctions, err := sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	require.NoError(t, sdk.SetFunctionsPipeline(appFunctions[1]))
+	assert.NotEqual(t, changed, sdk.runtime.GetDefaultPipeline().Hash,
+		"Function names should be used when the functions differ from the configurable pipeline")
+}
+
+func TestLoadConfigurablePipelineCondition(t *testing.T) {
+	functions := make(map[string]common.PipelineFunction)
+	functions["CompressWithGZIP"] = common.PipelineFunction{Condition: "size > 1024"}
+
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Writable: common.WritableInfo{
+				Pipeline: common.PipelineInfo{
+					ExecutionOrder: "CompressWithGZIP",
+					Functions:      functions,
+				},
+			},
+		},
+	}
+
+	appFunctions, err := sdk.LoadConfigurablePipeline()
+	require.NoError(t, err)
+	require.Len(t, appFunctions, 1)
+
+	edgexcontext := &appcontext.Context{LoggingClient: lc}
+	small := []byte("small payload")
+	continuePipeline, result := appFunctions[0](edgexcontext, small)
+	assert.True(t, continuePipeline)
+	assert.Equal(t, small, result, "Function should be skipped when the condition is false")
+
+	large := []byte(strings.Repeat("large payload ", 100))
+	continuePipeline, result = appFunctions[0](edgexcontext, large)
+	assert.True(t, continuePipeline)
+	assert.NotEqual(t, large, result, "Function should be executed when the condition is true")
+
+	functions["CompressWithGZIP"] = common.PipelineFunction{Condition: "bogus > 1024"}
+	appFunctions, err = sdk.LoadConfigurablePipeline()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "function CompressWithGZIP has an invalid condition")
+	assert.Nil(t, appFunctions)
+}
+
+func TestSetPipelineVersion(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		runtime:       &runtime.GolangRuntime{},
+	}
+	function := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+
+	sdk.runtime.Initialize(nil, nil)
+	require.NoError(t, sdk.SetFunctionsPipeline(function))
+	original := sdk.runtime.GetDefaultPipeline().Hash
+
+	require.NoError(t, sdk.SetPipelineVersion(runtime.DefaultPipelineId, "1.0"))
+	assert.NotEqual(t, original, sdk.runtime.GetDefaultPipeline().Hash)
+	assert.Equal(t, "1.0", sdk.pipelineVersions[runtime.DefaultPipelineId])
+
+	assert.Error(t, sdk.SetPipelineVersion(runtime.DefaultPipelineId, " "))
+	assert.Error(t, sdk.SetPipelineVersion("missing", "1.0"))
+}
+
 func TestUseTargetTypeOfByteArrayTrue(t *testing.T) {
 	functions := make(map[string]common.PipelineFunction)
 	functions["CompressWithGZIP"] = common.PipelineFunction{}
@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appsdk
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+// Tracing Exporter configuration values
+const (
+	TracingExporterOTLPHTTP = "otlp-http"
+	TracingExporterFile     = "file"
+)
+
+// SetTracingExporter enables tracing with the specified exporter, which takes precedence over the Tracing Exporter
+// configuration. Spans are recorded for the trigger, the pipeline and each pipeline function, along with the HTTP
+// and MQTT exports. Must be called before MakeItRun.
+func (sdk *AppFunctionsSDK) SetTracingExporter(exporter tracing.Exporter) error {
+	if exporter == nil {
+		return errors.New("tracing exporter can not be nil")
+	}
+
+	sdk.tracingExporter = exporter
+	return nil
+}
+
+// setupTracing starts the tracer when an exporter has been set or tracing is enabled in configuration.
+// The tracer is shut down, exporting any remaining spans, once the service exits.
+func (sdk *AppFunctionsSDK) setupTracing() error {
+	exporter := sdk.tracingExporter
+	if exporter == nil {
+		if !sdk.config.Tracing.Enabled {
+			return nil
+		}
+
+		var err error
+		exporter, err = sdk.createTracingExporter()
+		if err != nil {
+			return err
+		}
+	}
+
+	tracer := tracing.NewTracer(exporter, sdk.LoggingClient)
+	tracing.SetTracer(tracer)
+	sdk.LoggingClient.Info("Tracing enabled")
+
+	sdk.addDeferred(func() {
+		tracing.SetTracer(nil)
+		tracer.Shutdown()
+	})
+
+	return nil
+}
+
+func (sdk *AppFunctionsSDK) createTracingExporter() (tracing.Exporter, error) {
+	config := sdk.config.Tracing
+
+	switch strings.ToLower(config.Exporter) {
+	case TracingExporterOTLPHTTP:
+		if len(config.Endpoint) == 0 {
+			return nil, errors.New("tracing Endpoint must be set for the otlp-http Exporter")
+		}
+		return tracing.NewOTLPHTTPExporter(config.Endpoint, sdk.ServiceKey, nil), nil
+
+	case TracingExporterFile:
+		if len(config.FilePath) == 0 {
+			return nil, errors.New("tracing FilePath must be set for the file Exporter")
+		}
+		return tracing.NewFileExporter(config.FilePath)
+
+	default:
+		return nil, fmt.Errorf("tracing Exporter '%s' is not supported", config.Exporter)
+	}
+}
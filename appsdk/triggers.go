@@ -0,0 +1,179 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/google/uuid"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/internal/trigger"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+// Trigger receives messages from a source, i.e. a serial port or CAN bus, and processes them through the functions
+// pipeline(s). Initialize is called by MakeItRun, after which the trigger must stop receiving once the context is
+// done and return from any goroutine added to the wait group. The returned function, if any, is called on exit.
+type Trigger = trigger.Trigger
+
+// MessageError describes why processing a message through the functions pipeline(s) failed
+type MessageError = runtime.MessageError
+
+// TriggerContextBuilder builds the context for a message received by a custom trigger. The context has the service's
+// configuration and clients, and its request context derives from ctx so that the pipeline functions are cancelled
+// when ctx is done. A correlation ID is generated for messages received without one. Set the context's ReceivedTopic
+// to select the pipelines added via AddFunctionsPipelineForTopics.
+type TriggerContextBuilder func(ctx context.Context, envelope types.MessageEnvelope) *appcontext.Context
+
+// TriggerMessageProcessor processes a message received by a custom trigger through the functions pipeline(s) using
+// the context built by the TriggerContextBuilder. The message is captured by the journal and de-duplicated when
+// enabled, data for which a function fails is stored for later retry by Store and Forward, and messages which fail
+// processing are dead lettered. The output data, if any, is available from the context's Outputs once processed. A
+// MessageError with the filtered Kind is returned when a function filtered the data.
+type TriggerMessageProcessor func(edgexcontext *appcontext.Context, envelope types.MessageEnvelope) *MessageError
+
+// TriggerConfig provides a custom trigger with the service's configuration and the functions to process the
+// messages it receives
+type TriggerConfig struct {
+	// Config is the service's configuration. The trigger's settings are typically read from the Binding and
+	// ApplicationSettings.
+	Config *common.ConfigurationStruct
+	// Logger is the service's logging client
+	Logger logger.LoggingClient
+	// ContextBuilder builds the context for each received message
+	ContextBuilder TriggerContextBuilder
+	// MessageProcessor processes each received message through the functions pipeline(s)
+	MessageProcessor TriggerMessageProcessor
+}
+
+// TriggerFactory creates the custom trigger selected by the Binding Type
+type TriggerFactory func(config TriggerConfig) (Trigger, error)
+
+// builtInTriggers are the Binding Types of the triggers provided by the SDK, which can't be replaced
+var builtInTriggers = []string{"HTTP", "MESSAGEBUS", "MQTT", "FILE", "SCHEDULE"}
+
+// RegisterCustomTrigger registers the factory which creates the trigger used when the Binding Type matches name,
+// ignoring case. Custom triggers process the messages they receive via the TriggerConfig's MessageProcessor, so they
+// get the same functions pipeline(s), Store and Forward, journal and dead letter handling as the built-in triggers.
+// The name of a built-in trigger can't be used. Must be called before MakeItRun.
+func (sdk *AppFunctionsSDK) RegisterCustomTrigger(name string, factory TriggerFactory) error {
+	name = strings.ToUpper(strings.TrimSpace(name))
+	if len(name) == 0 {
+		return errors.New("custom trigger name can not be empty")
+	}
+	if factory == nil {
+		return errors.New("custom trigger factory can not be nil")
+	}
+	for _, builtIn := range builtInTriggers {
+		if name == builtIn {
+			return fmt.Errorf("custom trigger name '%s' is used by a built-in trigger", name)
+		}
+	}
+
+	if sdk.customTriggerFactories == nil {
+		sdk.customTriggerFactories = make(map[string]TriggerFactory)
+	}
+	sdk.customTriggerFactories[name] = factory
+	return nil
+}
+
+// setupCustomTrigger creates the custom trigger registered for the Binding Type, returning nil if there is none or
+// the factory fails
+func (sdk *AppFunctionsSDK) setupCustomTrigger(configuration *common.ConfigurationStruct,
+	runtime *runtime.GolangRuntime) trigger.Trigger {
+
+	name := strings.ToUpper(configuration.Binding.Type)
+	factory, ok := sdk.customTriggerFactories[name]
+	if !ok {
+		sdk.LoggingClient.Error(fmt.Sprintf("No trigger registered for Binding Type '%s'", configuration.Binding.Type))
+		return nil
+	}
+
+	sdk.LoggingClient.Info(fmt.Sprintf("Custom trigger '%s' selected", configuration.Binding.Type))
+	t, err := factory(TriggerConfig{
+		Config:           configuration,
+		Logger:           sdk.LoggingClient,
+		ContextBuilder:   sdk.triggerContextBuilder(configuration),
+		MessageProcessor: triggerMessageProcessor(strings.ToLower(name), runtime),
+	})
+	if err != nil {
+		sdk.LoggingClient.Error(fmt.Sprintf("Unable to create custom trigger '%s': %s", configuration.Binding.Type, err.Error()))
+		return nil
+	}
+	return t
+}
+
+// triggerContextBuilder returns the TriggerContextBuilder for custom triggers
+func (sdk *AppFunctionsSDK) triggerContextBuilder(configuration *common.ConfigurationStruct) TriggerContextBuilder {
+	return func(ctx context.Context, envelope types.MessageEnvelope) *appcontext.Context {
+		correlationID := envelope.CorrelationID
+		if len(correlationID) == 0 {
+			correlationID = uuid.New().String()
+		}
+
+		edgexContext := &appcontext.Context{
+			CorrelationID:         correlationID,
+			Configuration:         configuration,
+			LoggingClient:         sdk.edgexClients.LoggingClient,
+			EventClient:           sdk.edgexClients.EventClient,
+			ValueDescriptorClient: sdk.edgexClients.ValueDescriptorClient,
+			CommandClient:         sdk.edgexClients.CommandClient,
+			NotificationsClient:   sdk.edgexClients.NotificationsClient,
+		}
+		edgexContext.SetRequestContext(tracing.ContextWithCorrelationID(ctx, correlationID))
+		return edgexContext
+	}
+}
+
+// triggerMessageProcessor returns the TriggerMessageProcessor for the custom trigger with the specified name
+func triggerMessageProcessor(name string, runtime *runtime.GolangRuntime) TriggerMessageProcessor {
+	return func(edgexcontext *appcontext.Context, envelope types.MessageEnvelope) *MessageError {
+		if len(envelope.CorrelationID) == 0 {
+			envelope.CorrelationID = edgexcontext.CorrelationID
+		}
+		edgexcontext.LoggingClient.Trace(fmt.Sprintf("Received message from %s trigger", name),
+			clients.CorrelationHeader, envelope.CorrelationID)
+		telemetry.RecordMessageReceived(name)
+
+		requestCtx, span := tracing.StartSpan(edgexcontext.RequestContext(), name+" trigger", tracing.SpanKindConsumer)
+		defer span.End()
+		span.SetAttribute(tracing.AttributeCorrelationID, envelope.CorrelationID)
+		span.SetAttribute(tracing.AttributePayloadSize, len(envelope.Payload))
+		edgexcontext.SetRequestContext(requestCtx)
+
+		runtime.CaptureMessage(edgexcontext, envelope)
+		messageError := runtime.ProcessMessage(edgexcontext, envelope)
+		if messageError != nil {
+			// ProcessMessage logs the error, so no need to log it here.
+			if messageError.Kind != appcontext.ErrorKindFiltered {
+				span.SetError(messageError.Err)
+			}
+			runtime.DeadLetterMessage(edgexcontext, envelope, messageError)
+		}
+		return messageError
+	}
+}
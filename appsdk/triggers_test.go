@@ -0,0 +1,155 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package appsdk
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+// serialTrigger is a custom trigger which keeps the TriggerConfig it was created with
+type serialTrigger struct {
+	config TriggerConfig
+}
+
+func (trigger *serialTrigger) Initialize(wg *sync.WaitGroup, ctx context.Context) (bootstrap.Deferred, error) {
+	return nil, nil
+}
+
+func newSerialTrigger(config TriggerConfig) (Trigger, error) {
+	return &serialTrigger{config: config}, nil
+}
+
+func TestRegisterCustomTrigger(t *testing.T) {
+	sdk := AppFunctionsSDK{LoggingClient: lc}
+
+	assert.NoError(t, sdk.RegisterCustomTrigger("serial", newSerialTrigger))
+	assert.Contains(t, sdk.customTriggerFactories, "SERIAL")
+
+	assert.Error(t, sdk.RegisterCustomTrigger(" ", newSerialTrigger), "Expected error for empty name")
+	assert.Error(t, sdk.RegisterCustomTrigger("can", nil), "Expected error for nil factory")
+	assert.Error(t, sdk.RegisterCustomTrigger("MessageBus", newSerialTrigger), "Expected error for built-in name")
+}
+
+func TestSetupCustomTrigger(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "Serial",
+			},
+		},
+	}
+	require.NoError(t, sdk.RegisterCustomTrigger("serial", newSerialTrigger))
+
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	trigger := sdk.setupTrigger(sdk.config, testRuntime)
+	require.True(t, IsInstanceOf(trigger, (*serialTrigger)(nil)), "Expected Instance of custom Trigger")
+
+	config := trigger.(*serialTrigger).config
+	assert.Equal(t, sdk.config, config.Config)
+	assert.NotNil(t, config.Logger)
+	assert.NotNil(t, config.ContextBuilder)
+	assert.NotNil(t, config.MessageProcessor)
+}
+
+func TestSetupCustomTriggerNotRegistered(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "can",
+			},
+		},
+	}
+	require.NoError(t, sdk.RegisterCustomTrigger("serial", newSerialTrigger))
+
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	assert.Nil(t, sdk.setupTrigger(sdk.config, testRuntime))
+
+	// A factory error also results in no trigger
+	require.NoError(t, sdk.RegisterCustomTrigger("can", func(config TriggerConfig) (Trigger, error) {
+		return nil, errors.New("no such device")
+	}))
+	assert.Nil(t, sdk.setupTrigger(sdk.config, testRuntime))
+}
+
+func TestCustomTriggerProcessMessage(t *testing.T) {
+	sdk := AppFunctionsSDK{
+		LoggingClient: lc,
+		edgexClients:  common.EdgeXClients{LoggingClient: lc},
+		config: &common.ConfigurationStruct{
+			Binding: common.BindingInfo{
+				Type: "serial",
+			},
+		},
+	}
+	require.NoError(t, sdk.RegisterCustomTrigger("serial", newSerialTrigger))
+
+	var receivedCorrelationID string
+	var receivedTopic string
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		receivedCorrelationID = edgexcontext.CorrelationID
+		receivedTopic = edgexcontext.ReceivedTopic
+		assert.Equal(t, []byte("reading"), params[0])
+		edgexcontext.Complete([]byte("transformed"))
+		return false, nil
+	}
+
+	testRuntime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms([]appcontext.AppFunction{transform})
+	config := sdk.setupTrigger(sdk.config, testRuntime).(*serialTrigger).config
+
+	envelope := types.MessageEnvelope{Payload: []byte("reading"), ContentType: "application/binary"}
+	edgexcontext := config.ContextBuilder(context.Background(), envelope)
+	require.NotEmpty(t, edgexcontext.CorrelationID, "Expected correlation ID to be generated")
+	assert.Equal(t, sdk.config, edgexcontext.Configuration)
+	edgexcontext.ReceivedTopic = "/dev/ttyUSB0"
+
+	messageError := config.MessageProcessor(edgexcontext, envelope)
+	require.Nil(t, messageError)
+	assert.Equal(t, edgexcontext.CorrelationID, receivedCorrelationID)
+	assert.Equal(t, "/dev/ttyUSB0", receivedTopic)
+
+	outputs := edgexcontext.Outputs()
+	require.Len(t, outputs, 1)
+	assert.Equal(t, "transformed", string(outputs[0].Data))
+
+	// Errors are returned to the trigger
+	testRuntime.SetTransforms([]appcontext.AppFunction{
+		func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+			return false, errors.New("failed")
+		},
+	})
+	messageError = config.MessageProcessor(config.ContextBuilder(context.Background(), envelope), envelope)
+	require.NotNil(t, messageError)
+	assert.EqualError(t, messageError.Err, "failed")
+}
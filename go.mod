@@ -3,5 +3,23 @@ module github.com/student3671/app-functions-sdk-go
 go 1.13
 
 require (
-	github.com/student3671/app-functions-sdk-go v1.2.4-reply
+	bitbucket.org/bertimus9/systemstat v0.0.0-20180207000608-0eeff89b0690
+	github.com/diegoholiveira/jsonlogic v1.0.1-0.20200220175622-ab7989be08b9
+	github.com/eclipse/paho.mqtt.golang v1.2.0
+	github.com/edgexfoundry/go-mod-bootstrap v0.0.57
+	github.com/edgexfoundry/go-mod-core-contracts v0.1.112
+	github.com/edgexfoundry/go-mod-messaging v0.1.30
+	github.com/edgexfoundry/go-mod-registry v0.1.26
+	github.com/edgexfoundry/go-mod-secrets v0.0.26
+	github.com/fxamacker/cbor/v2 v2.3.0
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/gomodule/redigo v2.0.0+incompatible
+	github.com/google/uuid v1.1.2
+	github.com/gorilla/mux v1.8.0
+	github.com/stretchr/objx v0.2.0 // indirect
+	github.com/stretchr/testify v1.6.1
+	github.com/tidwall/pretty v1.0.1 // indirect
+	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c // indirect
+	github.com/xdg/stringprep v1.0.0 // indirect
+	go.mongodb.org/mongo-driver v1.1.1
 )
@@ -18,6 +18,7 @@ package handlers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	bootstrapContainer "github.com/edgexfoundry/go-mod-bootstrap/bootstrap/container"
@@ -27,6 +28,7 @@ import (
 
 	"github.com/student3671/app-functions-sdk-go/internal/bootstrap/container"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
 	"github.com/student3671/app-functions-sdk-go/internal/security"
 	"github.com/student3671/app-functions-sdk-go/internal/store"
 	"github.com/student3671/app-functions-sdk-go/internal/store/db/interfaces"
@@ -41,7 +43,8 @@ func NewDatabase() *Database {
 	return &Database{}
 }
 
-// BootstrapHandler creates the new interfaces.StoreClient use for database access by Store & Forward capability
+// BootstrapHandler creates the new interfaces.StoreClient use for database access by Store & Forward capability, the
+// store de-duplication seen-set and the store dead letter target
 func (_ *Database) BootstrapHandler(
 	ctx context.Context,
 	_ *sync.WaitGroup,
@@ -50,8 +53,7 @@ func (_ *Database) BootstrapHandler(
 
 	config := container.ConfigurationFrom(dic.Get)
 
-	// Only need the database client if Store and Forward is enabled
-	if !config.Writable.StoreAndForward.Enabled {
+	if !storeClientRequired(config) {
 		dic.Update(di.ServiceConstructorMap{
 			container.StoreClientName: func(get di.Get) interface{} {
 				return nil
@@ -78,6 +80,17 @@ func (_ *Database) BootstrapHandler(
 	return true
 }
 
+// storeClientRequired returns true if Store and Forward, the store de-duplication seen-set or the store dead letter
+// target is enabled, which all need the database client
+func storeClientRequired(config *common.ConfigurationStruct) bool {
+	storeSeenSet := config.Deduplication.Enabled &&
+		strings.EqualFold(config.Deduplication.SeenSet, runtime.DeduplicationSeenSetStore)
+	storeDeadLetters := config.DeadLetter.Enabled &&
+		strings.EqualFold(config.DeadLetter.Target, runtime.DeadLetterTargetStore)
+
+	return config.Writable.StoreAndForward.Enabled || storeSeenSet || storeDeadLetters
+}
+
 // InitializeStoreClient initializes the database client for Store and Forward. This is not a receiver function so that
 // it can be called directly when configuration has changed and store and forward has been enabled for the first time
 func InitializeStoreClient(
@@ -0,0 +1,62 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+func TestStoreClientRequired(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Configure func(config *common.ConfigurationStruct)
+		Expected  bool
+	}{
+		{"Nothing enabled", func(config *common.ConfigurationStruct) {}, false},
+		{"Store and Forward", func(config *common.ConfigurationStruct) {
+			config.Writable.StoreAndForward.Enabled = true
+		}, true},
+		{"Store seen-set", func(config *common.ConfigurationStruct) {
+			config.Deduplication.Enabled = true
+			config.Deduplication.SeenSet = runtime.DeduplicationSeenSetStore
+		}, true},
+		{"Store dead letters", func(config *common.ConfigurationStruct) {
+			config.DeadLetter.Enabled = true
+			config.DeadLetter.Target = "Store"
+		}, true},
+		{"Store dead letters disabled", func(config *common.ConfigurationStruct) {
+			config.DeadLetter.Target = runtime.DeadLetterTargetStore
+		}, false},
+		{"Message bus dead letters", func(config *common.ConfigurationStruct) {
+			config.DeadLetter.Enabled = true
+			config.DeadLetter.Target = runtime.DeadLetterTargetMessageBus
+		}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			config := &common.ConfigurationStruct{}
+			test.Configure(config)
+			assert.Equal(t, test.Expected, storeClientRequired(config))
+		})
+	}
+}
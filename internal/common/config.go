@@ -17,6 +17,7 @@
 package common
 
 import (
+	"strings"
 	"time"
 
 	bootstrapConfig "github.com/edgexfoundry/go-mod-bootstrap/config"
@@ -53,6 +54,8 @@ type ConfigurationStruct struct {
 	Service ServiceInfo
 	// MessageBus
 	MessageBus types.MessageBusConfig
+	// MqttBroker
+	MqttBroker MqttBrokerInfo
 	// Binding
 	Binding BindingInfo
 	// ApplicationSettings
@@ -65,6 +68,14 @@ type ConfigurationStruct struct {
 	SecretStore bootstrapConfig.SecretStoreInfo
 	// SecretStoreExclusive
 	SecretStoreExclusive bootstrapConfig.SecretStoreInfo
+	// DeadLetter
+	DeadLetter DeadLetterInfo
+	// Deduplication
+	Deduplication DeduplicationInfo
+	// Tracing
+	Tracing TracingInfo
+	// Journal
+	Journal JournalInfo
 }
 
 // ServiceInfo is used to hold and configure various settings related to the hosting of this service
@@ -88,15 +99,150 @@ type BindingInfo struct {
 	//
 	// example: messagebus
 	// required: true
-	// enum: messagebus,http
-	Type           string
+	// enum: messagebus,http,mqtt,file,schedule
+	Type string
+	// SubscribeTopic is the comma separated list of topics the messagebus and mqtt triggers subscribe to. Topics may
+	// contain wildcards where supported, i.e. the + and # wildcards of MQTT. The topic a message was received on is
+	// set as the Context's ReceivedTopic.
 	SubscribeTopic string
 	PublishTopic   string
+	WorkerPool     WorkerPoolInfo
+	RateLimit      RateLimitInfo
+	FileWatcher    FileWatcherInfo
+	Schedule       ScheduleInfo
+}
+
+// SubscribeTopics returns the comma separated topics of the SubscribeTopic, trimmed and without empty entries
+func (binding BindingInfo) SubscribeTopics() []string {
+	var topics []string
+	for _, topic := range strings.Split(binding.SubscribeTopic, ",") {
+		if topic = strings.TrimSpace(topic); len(topic) > 0 {
+			topics = append(topics, topic)
+		}
+	}
+
+	return topics
+}
+
+// FileWatcherInfo configures the file trigger, which processes the files added to a directory
+type FileWatcherInfo struct {
+	// Directory which is watched for new files. Its subdirectories aren't watched.
+	Directory string
+	// Patterns is a comma separated list of glob patterns matched against the file names, i.e. '*.csv, *.json'.
+	// All files are processed when not set. Hidden files, whose name starts with '.', are never processed.
+	Patterns string
+	// PollInterval is how often the Directory is checked for new files, i.e. 5s, which is the default
+	PollInterval string
+	// MinFileAge is how long a file must be unmodified before being processed, so that files which are still being
+	// written aren't processed, i.e. 2s, which is the default
+	MinFileAge string
+	// ProcessedDirectory is where files are moved once processed, the 'processed' subdirectory of the Directory by
+	// default. Relative paths are relative to the Directory.
+	ProcessedDirectory string
+	// FailedDirectory is where files are moved when processing fails, the 'failed' subdirectory of the Directory by
+	// default. Relative paths are relative to the Directory.
+	FailedDirectory string
+}
+
+// ScheduleInfo configures the schedule trigger, which executes the pipeline on a timer
+type ScheduleInfo struct {
+	// Interval between executions, i.e. 30s. Either the Interval or the Cron expression must be set.
+	Interval string
+	// Cron is an expression with the minute, hour, day of month, month and day of week fields, i.e. '*/15 * * * *',
+	// or a descriptor such as '@hourly'
+	Cron string
+	// TimeZone the Cron expression is evaluated in, i.e. 'America/Chicago'. Defaults to the local time zone.
+	TimeZone string
+	// Payload is the data passed to the pipeline on each execution, empty when not set. Required unless the
+	// TargetType is []byte, since an empty payload can't be decoded into the TargetType.
+	Payload string
+	// ContentType of the Payload. Sniffed from the payload when not set.
+	ContentType string
+	// OverlapPolicy is what is done when the schedule fires while the previous execution is still running. The
+	// firing is missed when skip, which is the default, or executed concurrently when allow.
+	//
+	// enum: skip,allow
+	OverlapPolicy string
+	// MissedRunPolicy is what is done once an execution completes if the schedule was missed while it was running.
+	// The missed firings are skipped until the next scheduled time when skip, which is the default, or executed
+	// once immediately when run-once.
+	//
+	// enum: skip,run-once
+	MissedRunPolicy string
+}
+
+// MqttBrokerInfo configures the connection of the mqtt trigger to an external MQTT broker
+type MqttBrokerInfo struct {
+	// Url of the broker, i.e. tcp://mosquitto:1883 or ssl://mosquitto:8883
+	Url string
+	// ClientId to connect to the broker with. Must be unique for each instance of the service.
+	ClientId string
+	// QoS used for the subscriptions and the published output data
+	QoS byte
+	// Retain sets the retain flag of the published output data
+	Retain bool
+	// AutoReconnect reconnects, and subscribes again, when the connection to the broker is lost
+	AutoReconnect bool
+	// KeepAlive is the interval at which the broker is pinged, i.e. 30s, which is the default
+	KeepAlive string
+	// ConnectTimeout is how long to wait to connect to the broker, i.e. 30s, which is the default
+	ConnectTimeout string
+	// AuthMode is how to connect to the broker. The secrets required by the mode are retrieved from the SecretPath.
+	// See transforms.MQTTSecretConfig for the secrets used by each mode.
+	//
+	// enum: none,usernamepassword,clientcert,cacert
+	AuthMode string
+	// SecretPath is the path of the secrets in the SecretProvider
+	SecretPath string
+	// SkipCertVerify disables the verification of the broker's certificate
+	SkipCertVerify bool
+	// ContentType of the received messages. Sniffed from the payload when not set.
+	ContentType string
+}
+
+// WorkerPoolInfo configures the workers which process the messages received by the messagebus trigger. The mqtt
+// trigger processes the messages in the order received, only using the QueueSize and QueueFullPolicy.
+type WorkerPoolInfo struct {
+	// Workers is the number of messages processed concurrently. Defaults to the number of CPUs when not set.
+	Workers int
+	// QueueSize is the number of received messages which can wait for a worker. Defaults to 100 when not set.
+	QueueSize int
+	// QueueFullPolicy is what is done with a received message when the queue is full. Defaults to block when not set.
+	//
+	// enum: block,drop-oldest,drop-newest
+	QueueFullPolicy string
+	// OrderingKey enables processing messages with the same key in the order received, while messages with
+	// different keys are processed in parallel. Messages are not ordered when not set.
+	//
+	// enum: device,reading
+	OrderingKey string
+	// DrainTimeout is how long the in-flight and queued messages continue to be processed once the service is
+	// shutting down, i.e. 10s, which is the default. The remaining queued messages are discarded and the functions of
+	// the in-flight messages are cancelled once it expires.
+	DrainTimeout string
+}
+
+// RateLimitInfo configures the admission of the messages received by the trigger using a token bucket. The http
+// trigger rejects messages exceeding the limit with 429 Too Many Requests.
+type RateLimitInfo struct {
+	Enabled bool
+	// Rate is the number of messages per second admitted across all keys. Not limited globally when not set.
+	Rate float64
+	// Burst is the number of messages admitted at once above the Rate. Defaults to the Rate when not set.
+	Burst int
+	// Key is what the KeyRate is applied by, i.e. 'remoteaddress' for the client's host, 'header.<name>' for the
+	// value of a request header or 'correlationid'
+	Key string
+	// KeyRate is the number of messages per second admitted per Key. Not limited per key when not set.
+	KeyRate float64
+	// KeyBurst is the number of messages admitted at once above the KeyRate. Defaults to the KeyRate when not set.
+	KeyBurst int
 }
 
 type PipelineInfo struct {
 	ExecutionOrder           string
 	UseTargetTypeOfByteArray bool
+	Timeout                  string
 	Functions                map[string]PipelineFunction
 }
 
@@ -104,12 +250,73 @@ type PipelineFunction struct {
 	// Name	string
 	Parameters  map[string]string
 	Addressable models.Addressable
+	// Condition is evaluated before the function is executed. The function is skipped, passing on its input
+	// unchanged, when the condition is false. The condition is either a JSONLogic rule or a simple expression,
+	// i.e. 'size > 1024'. See transforms.Condition for the values it is evaluated over. Always executed when not set.
+	Condition string
 }
 
 type StoreAndForwardInfo struct {
 	Enabled       bool
 	RetryInterval string
 	MaxRetryCount int
+	// VersionChangePolicy determines what happens to stored data when the version of its pipeline has changed.
+	// Valid values are "dead-letter" (default), "keep-and-retry" and "replay".
+	VersionChangePolicy string
+}
+
+// DeadLetterInfo configures where the messages which could not be processed are written
+type DeadLetterInfo struct {
+	Enabled bool
+	// Target is where dead letters are written. The store target requires the Database to be configured
+	// and is the only target which supports listing and re-injecting dead letters.
+	//
+	// enum: messagebus,store
+	Target string
+	// Topic is the message bus topic dead letters are published to when the Target is messagebus
+	Topic string
+}
+
+// DeduplicationInfo configures the dropping of received messages which have already been processed,
+// i.e. redeliveries by the MessageBus or retries by HTTP clients
+type DeduplicationInfo struct {
+	Enabled bool
+	// Key identifies duplicate messages. A key extractor set by the service takes precedence.
+	//
+	// enum: EventID,EventChecksum,CorrelationID
+	Key string
+	// TTL is how long the key of a processed message is remembered, i.e. 10m, which is the default
+	TTL string
+	// SeenSet is where the keys of the processed messages are kept. The store seen-set requires the Database to be
+	// configured and remembers the keys across restarts.
+	//
+	// enum: memory,store
+	SeenSet string
+}
+
+// TracingInfo configures the export of the spans recorded for the execution of the function pipelines
+type TracingInfo struct {
+	Enabled bool
+	// Exporter is where spans are exported. The file exporter writes a line of JSON per span to FilePath.
+	//
+	// enum: otlp-http,file
+	Exporter string
+	// Endpoint is the OTLP/HTTP traces URL of the collector when the Exporter is otlp-http
+	Endpoint string
+	// FilePath is the file spans are appended to when the Exporter is file
+	FilePath string
+}
+
+// JournalInfo configures the capture of the messages received by the trigger to a local journal, which can be
+// replayed through the pipeline to reproduce the exact input stream
+type JournalInfo struct {
+	Enabled bool
+	// Directory is where the journal files are written, ./journal by default
+	Directory string
+	// MaxFileSizeMB is the size, in megabytes, at which the journal file is rotated, 10 by default
+	MaxFileSizeMB int
+	// MaxFiles is the number of journal files kept, the oldest being removed, 10 by default
+	MaxFiles int
 }
 
 // Credentials encapsulates username-password attributes.
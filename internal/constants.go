@@ -30,6 +30,15 @@ const (
 	ApiV2TriggerRoute = v2.ApiBase + "/trigger"
 	ApiSecretsRoute   = clients.ApiBase + "/secrets"
 	ApiV2SecretsRoute = v2.ApiBase + "/secrets"
+
+	ApiPrometheusMetricsRoute = clients.ApiMetricsRoute + "/prometheus"
+
+	ApiDeadLettersRoute        = clients.ApiBase + "/deadletters"
+	ApiDeadLetterByIdRoute     = ApiDeadLettersRoute + "/{" + DeadLetterIdVar + "}"
+	ApiDeadLetterReinjectRoute = ApiDeadLetterByIdRoute + "/reinject"
+	DeadLetterIdVar            = "id"
+
+	ApiJournalReplayRoute = clients.ApiBase + "/journal/replay"
 )
 
 // SDKVersion indicates the version of the SDK - will be overwritten by build
@@ -0,0 +1,304 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package journal captures the messages received by the triggers to rotating local files, which can be read back
+// and replayed through the pipeline to reproduce the exact input stream.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+)
+
+const (
+	// DefaultDirectory is the directory the journal files are written to when not configured
+	DefaultDirectory = "./journal"
+	// DefaultMaxFileSize is the size, in bytes, at which the journal file is rotated when not configured
+	DefaultMaxFileSize = 10 * 1024 * 1024
+	// DefaultMaxFiles is the number of journal files kept when not configured
+	DefaultMaxFiles = 10
+
+	filePrefix     = "journal-"
+	fileExtension  = ".jsonl"
+	fileTimeLayout = "20060102T150405.000000000Z"
+)
+
+var (
+	// ErrInvalidFileName is returned, wrapped, when the name of a journal file includes a directory
+	ErrInvalidFileName = errors.New("invalid journal file name")
+	// ErrReplayInProgress is returned when a replay is started while another is in progress
+	ErrReplayInProgress = errors.New("a journal replay is already in progress")
+)
+
+// Entry is a message received by a trigger, written to the journal as a line of JSON
+type Entry struct {
+	// Timestamp is when the message was received
+	Timestamp time.Time `json:"timestamp"`
+	// Topic is the topic the message was received on, empty for triggers that are not topic based
+	Topic         string `json:"topic,omitempty"`
+	ContentType   string `json:"contentType,omitempty"`
+	CorrelationID string `json:"correlationId,omitempty"`
+	Checksum      string `json:"checksum,omitempty"`
+	Payload       []byte `json:"payload"`
+}
+
+// NewEntry creates the entry for the message received now on the topic
+func NewEntry(topic string, envelope types.MessageEnvelope) Entry {
+	return Entry{
+		Timestamp:     time.Now().UTC(),
+		Topic:         topic,
+		ContentType:   envelope.ContentType,
+		CorrelationID: envelope.CorrelationID,
+		Checksum:      envelope.Checksum,
+		Payload:       envelope.Payload,
+	}
+}
+
+// Envelope returns the message as received
+func (entry Entry) Envelope() types.MessageEnvelope {
+	return types.MessageEnvelope{
+		Checksum:      entry.Checksum,
+		CorrelationID: entry.CorrelationID,
+		Payload:       entry.Payload,
+		ContentType:   entry.ContentType,
+	}
+}
+
+// Writer appends the entries to the journal files in a directory. A new file is started when the current file
+// would exceed the maximum size, and the oldest files are removed so no more than the maximum number of files are
+// kept. It is safe for concurrent use.
+type Writer struct {
+	directory   string
+	maxFileSize int64
+	maxFiles    int
+	lock        sync.Mutex
+	file        *os.File
+	size        int64
+}
+
+// NewWriter creates a Writer for the directory, creating it if needed. The defaults are used for a maximum file size
+// or number of files less than 1.
+func NewWriter(directory string, maxFileSize int64, maxFiles int) (*Writer, error) {
+	if len(directory) == 0 {
+		directory = DefaultDirectory
+	}
+	if maxFileSize < 1 {
+		maxFileSize = DefaultMaxFileSize
+	}
+	if maxFiles < 1 {
+		maxFiles = DefaultMaxFiles
+	}
+
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create journal directory '%s': %w", directory, err)
+	}
+
+	return &Writer{directory: directory, maxFileSize: maxFileSize, maxFiles: maxFiles}, nil
+}
+
+// Append writes the entry to the end of the journal
+func (writer *Writer) Append(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("unable to encode journal entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.file == nil || (writer.size > 0 && writer.size+int64(len(line)) > writer.maxFileSize) {
+		if err := writer.rotate(); err != nil {
+			return err
+		}
+	}
+
+	written, err := writer.file.Write(line)
+	writer.size += int64(written)
+	if err != nil {
+		return fmt.Errorf("unable to write journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the current journal file
+func (writer *Writer) Close() error {
+	writer.lock.Lock()
+	defer writer.lock.Unlock()
+
+	if writer.file == nil {
+		return nil
+	}
+
+	err := writer.file.Close()
+	writer.file = nil
+	return err
+}
+
+// rotate closes the current file, if any, starts a new file named after the time it was started and removes the
+// oldest files beyond the maximum number of files
+func (writer *Writer) rotate() error {
+	if writer.file != nil {
+		if err := writer.file.Close(); err != nil {
+			return fmt.Errorf("unable to close journal file: %w", err)
+		}
+		writer.file = nil
+	}
+
+	path := filepath.Join(writer.directory, filePrefix+time.Now().UTC().Format(fileTimeLayout)+fileExtension)
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("unable to open journal file '%s': %w", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("unable to open journal file '%s': %w", path, err)
+	}
+
+	writer.file = file
+	writer.size = info.Size()
+
+	files, err := Files(writer.directory)
+	if err != nil {
+		return err
+	}
+
+	for len(files) > writer.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			return fmt.Errorf("unable to remove journal file '%s': %w", files[0], err)
+		}
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// Files returns the paths of the journal files in the directory, oldest first
+func Files(directory string) ([]string, error) {
+	infos, err := ioutil.ReadDir(directory)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list journal directory '%s': %w", directory, err)
+	}
+
+	var files []string
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasPrefix(info.Name(), filePrefix) && strings.HasSuffix(info.Name(), fileExtension) {
+			files = append(files, filepath.Join(directory, info.Name()))
+		}
+	}
+
+	// The names contain the time they were started, so sort in the order they were written
+	sort.Strings(files)
+	return files, nil
+}
+
+// ResolvePath returns the path of the journal file in the directory, or the directory itself when file is empty.
+// An error wrapping ErrInvalidFileName is returned if file isn't just a file name, so that only the journal
+// directory can be read.
+func ResolvePath(directory string, file string) (string, error) {
+	if len(directory) == 0 {
+		directory = DefaultDirectory
+	}
+
+	if len(file) == 0 {
+		return directory, nil
+	}
+
+	if filepath.Base(file) != file || file == "." || file == ".." {
+		return "", fmt.Errorf("'%s': %w", file, ErrInvalidFileName)
+	}
+
+	return filepath.Join(directory, file), nil
+}
+
+// Read returns the entries of the journal file, or of all the journal files when path is a directory, in the
+// order they were written
+func Read(path string) ([]Entry, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read journal: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = Files(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var entries []Entry
+	for _, file := range files {
+		fileEntries, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
+
+	return entries, nil
+}
+
+func readFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	reader := bufio.NewReader(file)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("unable to read journal file '%s': %w", path, err)
+		}
+
+		// A line without a newline at the end of the file was only partially written
+		complete := err == nil
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			var entry Entry
+			if decodeErr := json.Unmarshal(line, &entry); decodeErr != nil {
+				if !complete {
+					break
+				}
+				return nil, fmt.Errorf("invalid entry at line %d of journal file '%s': %w", lineNumber, path, decodeErr)
+			}
+			entries = append(entries, entry)
+		}
+
+		if !complete {
+			break
+		}
+	}
+
+	return entries, nil
+}
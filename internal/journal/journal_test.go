@@ -0,0 +1,171 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package journal
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterAndRead(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	writer, err := NewWriter(directory, 0, 0)
+	require.NoError(t, err)
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: "123-abc",
+		ContentType:   "application/json",
+		Payload:       []byte(`{"device":"Random-Float-Device"}`),
+	}
+	entry := NewEntry("edgex/events", envelope)
+	require.NoError(t, writer.Append(entry))
+	require.NoError(t, writer.Append(NewEntry("", types.MessageEnvelope{Payload: []byte{0xa0}})))
+	require.NoError(t, writer.Close())
+
+	entries, err := Read(directory)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "edgex/events", entries[0].Topic)
+	assert.Equal(t, envelope, entries[0].Envelope())
+	assert.True(t, entry.Timestamp.Equal(entries[0].Timestamp))
+	assert.Equal(t, []byte{0xa0}, entries[1].Payload)
+
+	files, err := Files(directory)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	fileEntries, err := Read(files[0])
+	require.NoError(t, err)
+	assert.Equal(t, entries, fileEntries)
+}
+
+func TestWriterRotation(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	// Each file only has room for a single entry
+	writer, err := NewWriter(directory, 10, 3)
+	require.NoError(t, err)
+	defer writer.Close()
+
+	for index := 0; index < 5; index++ {
+		envelope := types.MessageEnvelope{CorrelationID: string(rune('a' + index)), Payload: []byte("{}")}
+		require.NoError(t, writer.Append(NewEntry("", envelope)))
+		// The files are named after the time they are started
+		time.Sleep(time.Millisecond)
+	}
+
+	files, err := Files(directory)
+	require.NoError(t, err)
+	assert.Len(t, files, 3, "Oldest files should be removed")
+
+	entries, err := Read(directory)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "c", entries[0].CorrelationID)
+	assert.Equal(t, "e", entries[2].CorrelationID)
+}
+
+func TestReadPartialEntry(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	path := filepath.Join(directory, "journal-partial.jsonl")
+	valid := `{"timestamp":"2020-10-18T10:00:00Z","correlationId":"123","payload":"e30="}` + "\n"
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(valid+`{"timestamp":"2020-10-18T10:00:01Z","pay`), 0644))
+	entries, err := Read(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "Partially written last entry should be ignored")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"timestamp":`+"\n"+valid), 0644))
+	_, err = Read(path)
+	assert.Error(t, err)
+
+	_, err = Read(filepath.Join(directory, "missing.jsonl"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestResolvePath(t *testing.T) {
+	path, err := ResolvePath("/var/journal", "")
+	require.NoError(t, err)
+	assert.Equal(t, "/var/journal", path)
+
+	path, err = ResolvePath("", "journal-20201018T100000.000000000Z.jsonl")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(DefaultDirectory, "journal-20201018T100000.000000000Z.jsonl"), path)
+
+	for _, file := range []string{"../secrets.json", "/etc/passwd", ".."} {
+		_, err = ResolvePath("/var/journal", file)
+		assert.True(t, errors.Is(err, ErrInvalidFileName), file)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	start := time.Now()
+	entries := []Entry{
+		{Timestamp: start, CorrelationID: "1"},
+		{Timestamp: start.Add(200 * time.Millisecond), CorrelationID: "2"},
+		{Timestamp: start.Add(400 * time.Millisecond), CorrelationID: "3"},
+	}
+
+	var replayed []string
+	process := func(entry Entry) error {
+		replayed = append(replayed, entry.CorrelationID)
+		if entry.CorrelationID == "2" {
+			return errors.New("failed")
+		}
+		return nil
+	}
+
+	began := time.Now()
+	summary, err := Replay(context.Background(), entries, 4, process)
+	require.NoError(t, err)
+	assert.Equal(t, Summary{Replayed: 3, Failed: 1}, summary)
+	assert.Equal(t, []string{"1", "2", "3"}, replayed)
+	elapsed := time.Since(began)
+	assert.True(t, elapsed >= 100*time.Millisecond, "Entries should be spaced at four times the original speed")
+	assert.True(t, elapsed < 400*time.Millisecond, "Entries should be spaced at four times the original speed")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	replayed = nil
+	summary, err = Replay(ctx, entries, 1, process)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 1, summary.Replayed)
+
+	replayed = nil
+	began = time.Now()
+	_, err = Replay(context.Background(), entries, 0, process)
+	require.NoError(t, err)
+	assert.Len(t, replayed, 3)
+	assert.True(t, time.Since(began) < 100*time.Millisecond, "Entries should be replayed without delay")
+}
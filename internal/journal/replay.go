@@ -0,0 +1,73 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package journal
+
+import (
+	"context"
+	"time"
+)
+
+// Summary is the outcome of a replay
+type Summary struct {
+	// Replayed is the number of entries replayed
+	Replayed int `json:"replayed"`
+	// Failed is the number of the replayed entries which failed to be processed
+	Failed int `json:"failed"`
+}
+
+// Replay passes the entries to process in order. The entries are spaced by the time between when they were
+// received divided by speed, so 1 replays at the original speed and 10 ten times faster. The entries are replayed
+// without delay when speed is 0 or less. The replay stops, returning the context's error, when the context is done.
+func Replay(ctx context.Context, entries []Entry, speed float64, process func(entry Entry) error) (Summary, error) {
+	var summary Summary
+
+	for index, entry := range entries {
+		if index > 0 && speed > 0 {
+			delay := time.Duration(float64(entry.Timestamp.Sub(entries[index-1].Timestamp)) / speed)
+			if err := sleep(ctx, delay); err != nil {
+				return summary, err
+			}
+		}
+
+		if err := ctx.Err(); err != nil {
+			return summary, err
+		}
+
+		summary.Replayed++
+		if err := process(entry); err != nil {
+			summary.Failed++
+		}
+	}
+
+	return summary, nil
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
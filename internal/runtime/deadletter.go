@@ -0,0 +1,396 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/messaging"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/store/contracts"
+)
+
+const (
+	// DeadLetterTargetMessageBus publishes dead letters to the configured DeadLetter Topic
+	DeadLetterTargetMessageBus = "messagebus"
+	// DeadLetterTargetStore writes dead letters to the database used by Store and Forward
+	DeadLetterTargetStore = "store"
+
+	// DeadLetterReasonProcessingFailed is used when a received message failed processing
+	DeadLetterReasonProcessingFailed = "processing-failed"
+	// DeadLetterReasonMaxRetriesExceeded is used when stored data failed to be retried MaxRetryCount times
+	DeadLetterReasonMaxRetriesExceeded = "max-retries-exceeded"
+	// DeadLetterReasonRetryFailed is used when retrying stored data failed with a permanent error
+	DeadLetterReasonRetryFailed = "retry-failed"
+	// DeadLetterReasonPipelineChanged is used when stored data can no longer be retried since the pipeline changed
+	DeadLetterReasonPipelineChanged = "pipeline-changed"
+
+	// Dead letters are stored under their own app service key so they aren't retried by Store and Forward
+	deadLetterKeySuffix = "-dead-letters"
+	deadLetterVersion   = "dead-letter"
+)
+
+// ErrDeadLetterNotFound is returned when the dead letter to re-inject or remove doesn't exist
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// DeadLetter is a message, or the data stored for later retry, which could not be processed
+type DeadLetter struct {
+	// ID identifies the dead letter in the store, empty when published to the message bus
+	ID string `json:"id,omitempty"`
+	// Reason is why the data was dead lettered
+	Reason string `json:"reason"`
+	// Error is the error which occurred processing the data
+	Error string `json:"error"`
+	// Stored indicates Payload is the data stored for later retry at FunctionIndex, rather than the received message
+	Stored bool `json:"stored"`
+	// CorrelationID of the received message
+	CorrelationID string `json:"correlationId,omitempty"`
+	// ReceivedTopic is the topic the message was received on, if any
+	ReceivedTopic string `json:"receivedTopic,omitempty"`
+	// ContentType of the received message
+	ContentType string `json:"contentType,omitempty"`
+	// Payload is the received message or the data stored for later retry
+	Payload []byte `json:"payload"`
+	// PipelineId identifies the pipeline which failed, empty when failed before a pipeline was selected
+	PipelineId string `json:"pipelineId,omitempty"`
+	// FunctionIndex is the position of the function which failed, -1 when failed before the pipeline was executed
+	FunctionIndex int `json:"functionIndex"`
+	// BranchPath identifies the fan-out branch of the function which failed, if any
+	BranchPath []contracts.BranchPosition `json:"branchPath,omitempty"`
+	// RetryCount is the number of times Store and Forward retried the data
+	RetryCount int `json:"retryCount"`
+	// EventID is used to mark the EdgeX event as pushed
+	EventID string `json:"eventId,omitempty"`
+	// EventChecksum is used to mark the CBOR encoded EdgeX event as pushed
+	EventChecksum string `json:"eventChecksum,omitempty"`
+	// ContextValues are the values set on the Context, restored when the dead letter is re-injected
+	ContextValues map[string]string `json:"contextValues,omitempty"`
+	// Created is the time, in milliseconds, the dead letter was created
+	Created int64 `json:"created"`
+}
+
+type deadLetterInfo struct {
+	runtime       *GolangRuntime
+	publisher     messaging.MessageClient
+	publisherLock sync.Mutex
+}
+
+// SetDeadLetterPublisher sets the message bus client used to publish dead letters, so that the client connected
+// by the trigger is reused. A client is created from the MessageBus configuration when not set.
+func (gr *GolangRuntime) SetDeadLetterPublisher(publisher messaging.MessageClient) {
+	gr.deadLetters.publisherLock.Lock()
+	defer gr.deadLetters.publisherLock.Unlock()
+
+	gr.deadLetters.publisher = publisher
+}
+
+// DeadLetterMessage writes the received message which failed processing as a dead letter when dead lettering
+// is enabled. Messages which were filtered or stored for later retry are not dead lettered.
+func (gr *GolangRuntime) DeadLetterMessage(edgexcontext *appcontext.Context, envelope types.MessageEnvelope,
+	messageError *MessageError) {
+
+	config := edgexcontext.Configuration
+	if config == nil || !config.DeadLetter.Enabled ||
+		messageError.Kind == appcontext.ErrorKindFiltered || messageError.StoredForRetry {
+		return
+	}
+
+	letter := DeadLetter{
+		Reason:        DeadLetterReasonProcessingFailed,
+		Error:         messageError.Err.Error(),
+		CorrelationID: envelope.CorrelationID,
+		ReceivedTopic: edgexcontext.ReceivedTopic,
+		ContentType:   envelope.ContentType,
+		Payload:       envelope.Payload,
+		PipelineId:    messageError.PipelineId,
+		FunctionIndex: messageError.FunctionIndex,
+		ContextValues: edgexcontext.Values(),
+	}
+
+	gr.deadLetters.write(letter, config, edgexcontext.LoggingClient)
+}
+
+// deadLetterStoredItem writes the data stored for later retry as a dead letter when dead lettering is enabled
+func (dl *deadLetterInfo) deadLetterStoredItem(item contracts.StoredObject, reason string, errorMessage string,
+	config *common.ConfigurationStruct, lc logger.LoggingClient) {
+
+	if !config.DeadLetter.Enabled {
+		return
+	}
+
+	letter := DeadLetter{
+		Reason:        reason,
+		Error:         errorMessage,
+		Stored:        true,
+		CorrelationID: item.CorrelationID,
+		Payload:       item.Payload,
+		PipelineId:    item.PipelineId,
+		FunctionIndex: item.PipelinePosition,
+		BranchPath:    item.BranchPath,
+		RetryCount:    item.RetryCount,
+		EventID:       item.EventID,
+		EventChecksum: item.EventChecksum,
+		ContextValues: item.ContextValues,
+	}
+
+	dl.write(letter, config, lc)
+}
+
+// write publishes or stores the dead letter based on the DeadLetter Target. Failures are logged since there
+// is nothing more which can be done with the data.
+func (dl *deadLetterInfo) write(letter DeadLetter, config *common.ConfigurationStruct, lc logger.LoggingClient) {
+	letter.Created = time.Now().UnixNano() / int64(time.Millisecond)
+
+	payload, err := json.Marshal(letter)
+	if err != nil {
+		lc.Error("Failed to marshal dead letter", "error", err.Error(),
+			clients.CorrelationHeader, letter.CorrelationID)
+		return
+	}
+
+	switch strings.ToLower(config.DeadLetter.Target) {
+	case DeadLetterTargetMessageBus:
+		err = dl.publish(letter, payload, config)
+
+	case DeadLetterTargetStore:
+		err = dl.store(letter, payload)
+
+	default:
+		err = fmt.Errorf("DeadLetter Target '%s' is invalid, must be %s or %s",
+			config.DeadLetter.Target, DeadLetterTargetMessageBus, DeadLetterTargetStore)
+	}
+
+	if err != nil {
+		lc.Error("Failed to write dead letter", "error", err.Error(),
+			clients.CorrelationHeader, letter.CorrelationID)
+		return
+	}
+
+	lc.Debug("Dead letter written", "reason", letter.Reason, clients.CorrelationHeader, letter.CorrelationID)
+}
+
+func (dl *deadLetterInfo) publish(letter DeadLetter, payload []byte, config *common.ConfigurationStruct) error {
+	if len(config.DeadLetter.Topic) == 0 {
+		return errors.New("DeadLetter Topic not configured")
+	}
+
+	dl.publisherLock.Lock()
+	defer dl.publisherLock.Unlock()
+
+	if dl.publisher == nil {
+		publisher, err := messaging.NewMessageClient(config.MessageBus)
+		if err != nil {
+			return err
+		}
+
+		if err = publisher.Connect(); err != nil {
+			return err
+		}
+
+		dl.publisher = publisher
+	}
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: letter.CorrelationID,
+		Payload:       payload,
+		ContentType:   clients.ContentTypeJSON,
+	}
+
+	return dl.publisher.Publish(envelope, config.DeadLetter.Topic)
+}
+
+func (dl *deadLetterInfo) store(letter DeadLetter, payload []byte) error {
+	storeClient := dl.runtime.storeForward.storeClient
+	if storeClient == nil {
+		return errors.New("database not configured")
+	}
+
+	item := contracts.NewStoredObject(dl.runtime.ServiceKey+deadLetterKeySuffix, payload, letter.FunctionIndex, deadLetterVersion)
+	item.PipelineId = letter.PipelineId
+	item.CorrelationID = letter.CorrelationID
+	item.EventID = letter.EventID
+	item.EventChecksum = letter.EventChecksum
+
+	_, err := storeClient.Store(item)
+	return err
+}
+
+// ListDeadLetters returns the dead letters written to the store. Dead letters which can't be decoded are logged
+// and skipped, so they don't prevent the others being listed.
+func (gr *GolangRuntime) ListDeadLetters(config *common.ConfigurationStruct, lc logger.LoggingClient) ([]DeadLetter, error) {
+	items, err := gr.deadLetters.retrieve(config)
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter, 0, len(items))
+	for _, item := range items {
+		letter, err := toDeadLetter(item)
+		if err != nil {
+			lc.Warn("Ignoring invalid dead letter", "objectID", item.ID, "error", err.Error())
+			continue
+		}
+
+		letters = append(letters, letter)
+	}
+
+	return letters, nil
+}
+
+// RemoveDeadLetter removes the dead letter with the specified ID from the store
+func (gr *GolangRuntime) RemoveDeadLetter(id string, config *common.ConfigurationStruct) error {
+	item, _, err := gr.deadLetters.find(id, config)
+	if err != nil {
+		return err
+	}
+
+	return gr.storeForward.storeClient.RemoveFromStore(item)
+}
+
+// ReinjectDeadLetter processes the dead letter with the specified ID again and removes it from the store if successful.
+// A received message is processed from the start by the pipeline selected for it, while data stored for later retry
+// is retried with the function which failed. Any output data is discarded since there is no trigger to return it to.
+func (gr *GolangRuntime) ReinjectDeadLetter(id string, config *common.ConfigurationStruct, edgeXClients common.EdgeXClients) error {
+	item, letter, err := gr.deadLetters.find(id, config)
+	if err != nil {
+		return err
+	}
+
+	if letter.Stored {
+		err = gr.deadLetters.retryStored(letter, config, edgeXClients)
+	} else {
+		err = gr.deadLetters.reprocess(letter, config, edgeXClients)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return gr.storeForward.storeClient.RemoveFromStore(item)
+}
+
+func (dl *deadLetterInfo) retryStored(letter DeadLetter, config *common.ConfigurationStruct, edgeXClients common.EdgeXClients) error {
+	pipelineId := letter.PipelineId
+	if len(pipelineId) == 0 {
+		pipelineId = DefaultPipelineId
+	}
+
+	pipeline := dl.runtime.GetPipelineById(pipelineId)
+	if pipeline == nil {
+		return fmt.Errorf("pipeline '%s' no longer exists", pipelineId)
+	}
+
+	item := contracts.StoredObject{
+		Payload:          letter.Payload,
+		PipelineId:       pipelineId,
+		PipelinePosition: letter.FunctionIndex,
+		BranchPath:       letter.BranchPath,
+		CorrelationID:    letter.CorrelationID,
+		EventID:          letter.EventID,
+		EventChecksum:    letter.EventChecksum,
+		ContextValues:    letter.ContextValues,
+	}
+
+	storeForward := &dl.runtime.storeForward
+	edgexContext := storeForward.createRetryContext(item, config, edgeXClients)
+	transforms, err := storeForward.resolveBranch(edgexContext, pipeline, item)
+	if err != nil {
+		return err
+	}
+
+	if messageError := storeForward.retryExportFunction(item, edgexContext, pipeline, transforms); messageError != nil {
+		return messageError.Err
+	}
+
+	return nil
+}
+
+func (dl *deadLetterInfo) reprocess(letter DeadLetter, config *common.ConfigurationStruct, edgeXClients common.EdgeXClients) error {
+	edgexContext := &appcontext.Context{
+		CorrelationID:         letter.CorrelationID,
+		ReceivedTopic:         letter.ReceivedTopic,
+		Configuration:         config,
+		LoggingClient:         edgeXClients.LoggingClient,
+		EventClient:           edgeXClients.EventClient,
+		ValueDescriptorClient: edgeXClients.ValueDescriptorClient,
+		CommandClient:         edgeXClients.CommandClient,
+		NotificationsClient:   edgeXClients.NotificationsClient,
+	}
+	// The functions see the values the message was processed with, i.e. the received topic set by the trigger
+	edgexContext.SetValues(letter.ContextValues)
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: letter.CorrelationID,
+		ContentType:   letter.ContentType,
+		Payload:       letter.Payload,
+	}
+
+	// Stored for later retry means it is now handled by Store and Forward
+	if messageError := dl.runtime.ProcessMessage(edgexContext, envelope); messageError != nil && !messageError.StoredForRetry {
+		return messageError.Err
+	}
+
+	return nil
+}
+
+func (dl *deadLetterInfo) retrieve(config *common.ConfigurationStruct) ([]contracts.StoredObject, error) {
+	if !strings.EqualFold(config.DeadLetter.Target, DeadLetterTargetStore) {
+		return nil, fmt.Errorf("dead letters are only available when the DeadLetter Target is %s", DeadLetterTargetStore)
+	}
+
+	storeClient := dl.runtime.storeForward.storeClient
+	if storeClient == nil {
+		return nil, errors.New("database not configured")
+	}
+
+	return storeClient.RetrieveFromStore(dl.runtime.ServiceKey + deadLetterKeySuffix)
+}
+
+func (dl *deadLetterInfo) find(id string, config *common.ConfigurationStruct) (contracts.StoredObject, DeadLetter, error) {
+	items, err := dl.retrieve(config)
+	if err != nil {
+		return contracts.StoredObject{}, DeadLetter{}, err
+	}
+
+	for _, item := range items {
+		if item.ID == id {
+			letter, err := toDeadLetter(item)
+			return item, letter, err
+		}
+	}
+
+	return contracts.StoredObject{}, DeadLetter{}, ErrDeadLetterNotFound
+}
+
+func toDeadLetter(item contracts.StoredObject) (DeadLetter, error) {
+	var letter DeadLetter
+	if err := json.Unmarshal(item.Payload, &letter); err != nil {
+		return DeadLetter{}, fmt.Errorf("unable to unmarshal dead letter '%s': %s", item.ID, err.Error())
+	}
+
+	letter.ID = item.ID
+	return letter, nil
+}
@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/store/contracts"
+)
+
+func deadLetterConfig() common.ConfigurationStruct {
+	return common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel:        "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{MaxRetryCount: 1},
+		},
+		DeadLetter: common.DeadLetterInfo{
+			Enabled: true,
+			Target:  DeadLetterTargetStore,
+		},
+	}
+}
+
+func TestDeadLetterMessage(t *testing.T) {
+	config := deadLetterConfig()
+	exportErr := errors.New("export failed")
+
+	tests := []struct {
+		name         string
+		messageError *MessageError
+		expectLetter bool
+	}{
+		{"Failed", &MessageError{Err: exportErr, PipelineId: DefaultPipelineId, FunctionIndex: 1}, true},
+		{"Filtered", &MessageError{Err: exportErr, Kind: appcontext.ErrorKindFiltered}, false},
+		{"Stored for retry", &MessageError{Err: exportErr, StoredForRetry: true}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			runtime := GolangRuntime{ServiceKey: serviceKey}
+			runtime.Initialize(creatMockStoreClient(), nil)
+
+			ctx := &appcontext.Context{
+				Configuration: &config,
+				LoggingClient: lc,
+				ReceivedTopic: "events",
+			}
+			require.NoError(t, ctx.SetValue(appcontext.ValueKeyReceivedTopic, "events"))
+			envelope := types.MessageEnvelope{
+				CorrelationID: "123",
+				ContentType:   clients.ContentTypeJSON,
+				Payload:       []byte(`{"device":"device1"}`),
+			}
+
+			runtime.DeadLetterMessage(ctx, envelope, test.messageError)
+
+			letters, err := runtime.ListDeadLetters(&config, lc)
+			require.NoError(t, err)
+			assert.Empty(t, mockRetrieveObjects(serviceKey), "Dead letters must not be retried by Store and Forward")
+			if !test.expectLetter {
+				assert.Empty(t, letters)
+				return
+			}
+
+			require.Equal(t, 1, len(letters))
+			letter := letters[0]
+			assert.NotEmpty(t, letter.ID)
+			assert.Equal(t, DeadLetterReasonProcessingFailed, letter.Reason)
+			assert.Equal(t, exportErr.Error(), letter.Error)
+			assert.False(t, letter.Stored)
+			assert.Equal(t, envelope.CorrelationID, letter.CorrelationID)
+			assert.Equal(t, envelope.ContentType, letter.ContentType)
+			assert.Equal(t, envelope.Payload, letter.Payload)
+			assert.Equal(t, "events", letter.ReceivedTopic)
+			assert.Equal(t, DefaultPipelineId, letter.PipelineId)
+			assert.Equal(t, 1, letter.FunctionIndex)
+			assert.Equal(t, ctx.Values(), letter.ContextValues)
+		})
+	}
+}
+
+func TestDeadLetterMaxRetriesAndReinject(t *testing.T) {
+	config := deadLetterConfig()
+
+	shouldFail := true
+	var exportedTopic string
+	export := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		exportedTopic, _ = edgexcontext.StringValue(appcontext.ValueKeyReceivedTopic)
+		if shouldFail {
+			return false, errors.New("export failed")
+		}
+		return false, nil
+	}
+
+	runtime := GolangRuntime{ServiceKey: serviceKey}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{export})
+
+	item := contracts.NewStoredObject(serviceKey, []byte("payload"), 0, runtime.GetDefaultPipeline().Hash)
+	item.CorrelationID = "123"
+	item.ContextValues = map[string]string{appcontext.ValueKeyReceivedTopic: `"events"`}
+
+	edgeXClients := common.EdgeXClients{LoggingClient: lc}
+	removes, _ := runtime.storeForward.processRetryItems([]contracts.StoredObject{item}, &config, edgeXClients)
+	require.Equal(t, 1, len(removes), "Expected item to be removed once max retries exceeded")
+
+	letters, err := runtime.ListDeadLetters(&config, lc)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(letters))
+	assert.Equal(t, DeadLetterReasonMaxRetriesExceeded, letters[0].Reason)
+	assert.True(t, letters[0].Stored)
+	assert.Equal(t, 1, letters[0].RetryCount)
+	assert.Equal(t, []byte("payload"), letters[0].Payload)
+	assert.Equal(t, item.ContextValues, letters[0].ContextValues)
+
+	err = runtime.ReinjectDeadLetter(letters[0].ID, &config, edgeXClients)
+	require.Error(t, err, "Expected re-inject to fail while export still failing")
+	letters, err = runtime.ListDeadLetters(&config, lc)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(letters), "Expected dead letter kept when re-inject fails")
+
+	shouldFail = false
+	err = runtime.ReinjectDeadLetter(letters[0].ID, &config, edgeXClients)
+	require.NoError(t, err)
+	assert.Equal(t, "events", exportedTopic, "Expected context values restored when re-injected")
+	letters, err = runtime.ListDeadLetters(&config, lc)
+	require.NoError(t, err)
+	assert.Empty(t, letters, "Expected dead letter removed once re-injected")
+}
+
+func TestDeadLetterNotFound(t *testing.T) {
+	config := deadLetterConfig()
+
+	runtime := GolangRuntime{ServiceKey: serviceKey}
+	runtime.Initialize(creatMockStoreClient(), nil)
+
+	err := runtime.RemoveDeadLetter("unknown", &config)
+	assert.True(t, errors.Is(err, ErrDeadLetterNotFound))
+
+	err = runtime.ReinjectDeadLetter("unknown", &config, common.EdgeXClients{LoggingClient: lc})
+	assert.True(t, errors.Is(err, ErrDeadLetterNotFound))
+
+	config.DeadLetter.Target = DeadLetterTargetMessageBus
+	_, err = runtime.ListDeadLetters(&config, lc)
+	assert.Error(t, err, "Expected error listing dead letters published to the message bus")
+}
+
+func TestListDeadLettersSkipsInvalid(t *testing.T) {
+	config := deadLetterConfig()
+
+	runtime := GolangRuntime{ServiceKey: serviceKey}
+	runtime.Initialize(creatMockStoreClient(), nil)
+
+	invalid := contracts.NewStoredObject(serviceKey+deadLetterKeySuffix, []byte("{bad"), 0, deadLetterVersion)
+	_, err := runtime.storeForward.storeClient.Store(invalid)
+	require.NoError(t, err)
+
+	ctx := &appcontext.Context{Configuration: &config, LoggingClient: lc}
+	envelope := types.MessageEnvelope{CorrelationID: "123", Payload: []byte("payload")}
+	runtime.DeadLetterMessage(ctx, envelope, &MessageError{Err: errors.New("failed")})
+
+	letters, err := runtime.ListDeadLetters(&config, lc)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(letters), "Expected the invalid dead letter to be skipped")
+	assert.Equal(t, "123", letters[0].CorrelationID)
+}
@@ -0,0 +1,370 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/store/contracts"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+)
+
+const (
+	// DeduplicationKeyEventID identifies duplicates by the ID of the received Event. This is the default key. Events
+	// without an ID, such as the CBOR Events published by Core Data which are encoded before the ID is assigned,
+	// aren't de-duplicated, so DeduplicationKeyEventChecksum is to be used for them.
+	DeduplicationKeyEventID = "eventid"
+	// DeduplicationKeyEventChecksum identifies duplicates by the checksum of the received CBOR Event
+	DeduplicationKeyEventChecksum = "eventchecksum"
+	// DeduplicationKeyCorrelationID identifies duplicates by the correlation ID of the received message
+	DeduplicationKeyCorrelationID = "correlationid"
+	// deduplicationKeyExtractor is the key name recorded in the metrics when the key extractor is used
+	deduplicationKeyExtractor = "extractor"
+
+	// DeduplicationSeenSetMemory keeps the keys of the processed messages in memory. This is the default seen-set.
+	DeduplicationSeenSetMemory = "memory"
+	// DeduplicationSeenSetStore also writes the keys of the processed messages to the database used by
+	// Store and Forward so they are remembered across restarts
+	DeduplicationSeenSetStore = "store"
+
+	defaultDeduplicationTTL = 10 * time.Minute
+
+	// Keys are stored under their own app service key so they aren't retried by Store and Forward
+	deduplicationKeySuffix = "-seen-set"
+	deduplicationVersion   = "seen-set"
+)
+
+// DeduplicationKeyExtractor returns the key identifying duplicates of the received message, given the message and
+// its decoded target. Messages for which an empty key is returned are not de-duplicated.
+type DeduplicationKeyExtractor func(envelope types.MessageEnvelope, target interface{}) string
+
+// seenEntry is a key which has been seen within the TTL window
+type seenEntry struct {
+	expires time.Time
+	// stored is the key written to the store seen-set, its ID is empty if not stored
+	stored contracts.StoredObject
+}
+
+// seenRecord is the payload of a key written to the store seen-set
+type seenRecord struct {
+	Key string `json:"key"`
+	// Expires is the time, in milliseconds, the key is forgotten
+	Expires int64 `json:"expires"`
+}
+
+type deduplicationInfo struct {
+	runtime   *GolangRuntime
+	extractor DeduplicationKeyExtractor
+	lock      sync.Mutex
+	seen      map[string]*seenEntry
+	ttl       time.Duration
+	// ttlSetting is the TTL setting ttl was parsed from, so it is re-parsed when the configuration changes
+	ttlSetting string
+	loaded     bool
+	lastSweep  time.Time
+}
+
+// SetDeduplicationKeyExtractor sets the function used to extract the key identifying duplicate messages, which
+// takes precedence over the Deduplication Key setting. De-duplication must still be enabled in configuration.
+func (gr *GolangRuntime) SetDeduplicationKeyExtractor(extractor DeduplicationKeyExtractor) {
+	gr.deduplication.lock.Lock()
+	defer gr.deduplication.lock.Unlock()
+
+	gr.deduplication.extractor = extractor
+}
+
+// claim returns true if the message is a duplicate of one seen within the TTL window, in which case it is to be
+// dropped. Otherwise the message's key is returned, which has been marked as seen and must be passed to complete
+// once the message has been processed. An empty key is returned when the message isn't de-duplicated.
+// Note that the key is marked as seen while the message is processed so that concurrent redeliveries are dropped.
+func (dd *deduplicationInfo) claim(edgexcontext *appcontext.Context, envelope types.MessageEnvelope,
+	target interface{}) (string, bool) {
+
+	config := edgexcontext.Configuration
+	if config == nil || !config.Deduplication.Enabled {
+		return "", false
+	}
+
+	dd.lock.Lock()
+	extractor := dd.extractor
+	dd.lock.Unlock()
+
+	keyName, key := extractDeduplicationKey(extractor, config, edgexcontext, envelope, target)
+	if len(key) == 0 {
+		return "", false
+	}
+
+	now := time.Now()
+	dd.lock.Lock()
+	dd.initialize(config, edgexcontext.LoggingClient, now)
+	expired := dd.sweep(now)
+
+	entry, duplicate := dd.seen[key]
+	if !duplicate || !now.Before(entry.expires) {
+		duplicate = false
+		if entry != nil && len(entry.stored.ID) > 0 {
+			expired = append(expired, entry.stored)
+		}
+		dd.seen[key] = &seenEntry{expires: now.Add(dd.ttl)}
+	}
+	dd.lock.Unlock()
+
+	dd.removeStored(expired, edgexcontext.LoggingClient)
+
+	if duplicate {
+		telemetry.RecordMessageDuplicate(keyName)
+		edgexcontext.LoggingClient.Debug("Duplicate message dropped",
+			"key", keyName,
+			clients.CorrelationHeader, envelope.CorrelationID)
+		return "", true
+	}
+
+	return key, false
+}
+
+// complete keeps the key as seen if the message was processed, which includes the message being filtered or stored
+// for later retry. Otherwise the key is forgotten so a redelivery of the message is processed.
+func (dd *deduplicationInfo) complete(edgexcontext *appcontext.Context, key string, messageError *MessageError) {
+	if len(key) == 0 {
+		return
+	}
+
+	config := edgexcontext.Configuration
+	processed := messageError == nil || messageError.StoredForRetry || messageError.Kind == appcontext.ErrorKindFiltered
+
+	dd.lock.Lock()
+	entry, ok := dd.seen[key]
+	if !ok {
+		dd.lock.Unlock()
+		return
+	}
+
+	if !processed {
+		delete(dd.seen, key)
+		dd.lock.Unlock()
+		return
+	}
+	expires := entry.expires
+	dd.lock.Unlock()
+
+	if !strings.EqualFold(config.Deduplication.SeenSet, DeduplicationSeenSetStore) {
+		return
+	}
+
+	item, err := dd.store(key, expires)
+	if err != nil {
+		edgexcontext.LoggingClient.Error("Failed to write key to the de-duplication seen-set",
+			"error", err.Error(),
+			clients.CorrelationHeader, edgexcontext.CorrelationID)
+		return
+	}
+
+	// The entry is forgotten if it expired, or was replaced by a redelivery, while the key was being stored
+	dd.lock.Lock()
+	current := dd.seen[key] == entry
+	if current {
+		entry.stored = item
+	}
+	dd.lock.Unlock()
+
+	if !current {
+		dd.removeStored([]contracts.StoredObject{item}, edgexcontext.LoggingClient)
+	}
+}
+
+// extractDeduplicationKey returns the name and value of the key identifying duplicates of the message
+func extractDeduplicationKey(extractor DeduplicationKeyExtractor, config *common.ConfigurationStruct,
+	edgexcontext *appcontext.Context, envelope types.MessageEnvelope, target interface{}) (string, string) {
+
+	if extractor != nil {
+		return deduplicationKeyExtractor, callKeyExtractor(extractor, envelope, target)
+	}
+
+	switch strings.ToLower(config.Deduplication.Key) {
+	case DeduplicationKeyEventChecksum:
+		return DeduplicationKeyEventChecksum, edgexcontext.EventChecksum
+	case DeduplicationKeyCorrelationID:
+		return DeduplicationKeyCorrelationID, envelope.CorrelationID
+	default:
+		return DeduplicationKeyEventID, deduplicationEventID(edgexcontext, envelope, target)
+	}
+}
+
+// deduplicationEventID returns the ID of the received Event. The ID isn't set on the context for CBOR Events, which
+// are marked as pushed by their checksum, nor when the TargetType isn't an Event, so is then taken from the decoded
+// Event or, failing that, by decoding the payload as an Event.
+func deduplicationEventID(edgexcontext *appcontext.Context, envelope types.MessageEnvelope,
+	target interface{}) string {
+
+	if len(edgexcontext.EventID) > 0 {
+		return edgexcontext.EventID
+	}
+
+	if event, ok := target.(models.Event); ok {
+		return event.ID
+	}
+
+	payloadCodec, ok := edgexcontext.Codecs().Lookup(envelope.ContentType)
+	if !ok {
+		return ""
+	}
+
+	var event models.Event
+	if err := payloadCodec.Unmarshal(envelope.Payload, &event); err != nil {
+		return ""
+	}
+	return event.ID
+}
+
+// callKeyExtractor calls the key extractor, treating a panic as the message not having a key
+func callKeyExtractor(extractor DeduplicationKeyExtractor, envelope types.MessageEnvelope,
+	target interface{}) (key string) {
+	defer func() {
+		if recover() != nil {
+			key = ""
+		}
+	}()
+
+	return extractor(envelope, target)
+}
+
+// initialize parses the TTL whenever its setting changes and loads the store seen-set the first time a message is
+// de-duplicated. Must be called with the lock held.
+func (dd *deduplicationInfo) initialize(config *common.ConfigurationStruct, lc logger.LoggingClient, now time.Time) {
+	if dd.seen == nil {
+		dd.seen = make(map[string]*seenEntry)
+		dd.lastSweep = now
+	}
+
+	if dd.ttl == 0 || dd.ttlSetting != config.Deduplication.TTL {
+		dd.ttlSetting = config.Deduplication.TTL
+		dd.ttl = defaultDeduplicationTTL
+		if len(config.Deduplication.TTL) > 0 {
+			ttl, err := time.ParseDuration(config.Deduplication.TTL)
+			if err != nil || ttl <= 0 {
+				lc.Warn(fmt.Sprintf("Deduplication TTL '%s' is invalid, defaulting to %s",
+					config.Deduplication.TTL, defaultDeduplicationTTL.String()))
+			} else {
+				dd.ttl = ttl
+			}
+		}
+	}
+
+	if dd.loaded || !strings.EqualFold(config.Deduplication.SeenSet, DeduplicationSeenSetStore) {
+		return
+	}
+	dd.loaded = true
+
+	storeClient := dd.runtime.storeForward.storeClient
+	if storeClient == nil {
+		lc.Error("Unable to load the de-duplication seen-set", "error", "database not configured")
+		return
+	}
+
+	items, err := storeClient.RetrieveFromStore(dd.runtime.ServiceKey + deduplicationKeySuffix)
+	if err != nil {
+		lc.Error("Unable to load the de-duplication seen-set", "error", err.Error())
+		return
+	}
+
+	for _, item := range items {
+		var record seenRecord
+		if err := json.Unmarshal(item.Payload, &record); err != nil {
+			lc.Warn("Ignoring invalid key in the de-duplication seen-set", "objectID", item.ID)
+			continue
+		}
+
+		// Expired keys are removed by the next sweep
+		dd.seen[record.Key] = &seenEntry{
+			expires: time.Unix(0, record.Expires*int64(time.Millisecond)),
+			stored:  item,
+		}
+	}
+
+	lc.Debug(fmt.Sprintf("Loaded %d keys from the de-duplication seen-set", len(items)))
+}
+
+// sweep forgets the expired keys, at most once per TTL, and returns those to be removed from the store.
+// Must be called with the lock held.
+func (dd *deduplicationInfo) sweep(now time.Time) []contracts.StoredObject {
+	if now.Sub(dd.lastSweep) < dd.ttl {
+		return nil
+	}
+	dd.lastSweep = now
+
+	var expired []contracts.StoredObject
+	for key, entry := range dd.seen {
+		if now.Before(entry.expires) {
+			continue
+		}
+
+		if len(entry.stored.ID) > 0 {
+			expired = append(expired, entry.stored)
+		}
+		delete(dd.seen, key)
+	}
+
+	return expired
+}
+
+func (dd *deduplicationInfo) store(key string, expires time.Time) (contracts.StoredObject, error) {
+	storeClient := dd.runtime.storeForward.storeClient
+	if storeClient == nil {
+		return contracts.StoredObject{}, errors.New("database not configured")
+	}
+
+	payload, err := json.Marshal(seenRecord{Key: key, Expires: expires.UnixNano() / int64(time.Millisecond)})
+	if err != nil {
+		return contracts.StoredObject{}, err
+	}
+
+	item := contracts.NewStoredObject(dd.runtime.ServiceKey+deduplicationKeySuffix, payload, 0, deduplicationVersion)
+	item.ID, err = storeClient.Store(item)
+	return item, err
+}
+
+// removeStored removes the expired keys from the store seen-set. Failures are logged since they are only retried
+// once the service is restarted.
+func (dd *deduplicationInfo) removeStored(items []contracts.StoredObject, lc logger.LoggingClient) {
+	if len(items) == 0 {
+		return
+	}
+
+	storeClient := dd.runtime.storeForward.storeClient
+	if storeClient == nil {
+		return
+	}
+
+	for _, item := range items {
+		if err := storeClient.RemoveFromStore(item); err != nil {
+			lc.Error("Unable to remove expired key from the de-duplication seen-set",
+				"error", err.Error(),
+				"objectID", item.ID)
+		}
+	}
+}
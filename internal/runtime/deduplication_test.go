@@ -0,0 +1,261 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+)
+
+func newDeduplicationEnvelope(t *testing.T, eventID string, device string, correlationID string) types.MessageEnvelope {
+	payload, err := json.Marshal(models.Event{ID: eventID, Device: device})
+	require.NoError(t, err)
+
+	return types.MessageEnvelope{
+		CorrelationID: correlationID,
+		Payload:       payload,
+		ContentType:   clients.ContentTypeJSON,
+	}
+}
+
+func newDeduplicationCBOREnvelope(t *testing.T, eventID string, correlationID string) types.MessageEnvelope {
+	payload, err := cbor.Marshal(models.Event{ID: eventID, Device: "device-1"})
+	require.NoError(t, err)
+
+	return types.MessageEnvelope{
+		CorrelationID: correlationID,
+		Payload:       payload,
+		ContentType:   clients.ContentTypeCBOR,
+	}
+}
+
+func newDeduplicationConfig(key string, ttl string, seenSet string) *common.ConfigurationStruct {
+	config := &common.ConfigurationStruct{}
+	config.Writable.StoreAndForward.MaxRetryCount = 10
+	config.Deduplication = common.DeduplicationInfo{Enabled: true, Key: key, TTL: ttl, SeenSet: seenSet}
+	return config
+}
+
+func TestProcessMessageDeduplication(t *testing.T) {
+	tests := []struct {
+		Name              string
+		Enabled           bool
+		Key               string
+		Envelopes         []types.MessageEnvelope
+		ExpectedProcessed int
+	}{
+		{"Disabled", false, "", []types.MessageEnvelope{
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1"),
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1"),
+		}, 2},
+		{"EventID", true, "EventID", []types.MessageEnvelope{
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1"),
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-2"),
+			newDeduplicationEnvelope(t, "event-2", "device-1", "correlation-1"),
+		}, 2},
+		{"CBOR EventID", true, "EventID", []types.MessageEnvelope{
+			newDeduplicationCBOREnvelope(t, "event-1", "correlation-1"),
+			newDeduplicationCBOREnvelope(t, "event-1", "correlation-2"),
+			newDeduplicationCBOREnvelope(t, "event-2", "correlation-3"),
+		}, 2},
+		{"Default Key", true, "", []types.MessageEnvelope{
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1"),
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-2"),
+		}, 1},
+		{"CorrelationID", true, "CorrelationID", []types.MessageEnvelope{
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1"),
+			newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-2"),
+			newDeduplicationEnvelope(t, "event-2", "device-1", "correlation-1"),
+		}, 2},
+		{"No Key", true, "EventID", []types.MessageEnvelope{
+			newDeduplicationEnvelope(t, "", "device-1", "correlation-1"),
+			newDeduplicationEnvelope(t, "", "device-1", "correlation-1"),
+		}, 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			processed := 0
+			transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				processed++
+				return false, nil
+			}
+
+			config := newDeduplicationConfig(test.Key, "", "")
+			config.Deduplication.Enabled = test.Enabled
+
+			runtime := GolangRuntime{}
+			runtime.Initialize(nil, nil)
+			runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+			for _, envelope := range test.Envelopes {
+				context := &appcontext.Context{LoggingClient: lc, Configuration: config}
+				assert.Nil(t, runtime.ProcessMessage(context, envelope), "Duplicates should not be reported as errors")
+			}
+
+			assert.Equal(t, test.ExpectedProcessed, processed)
+		})
+	}
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, telemetry.DefaultRegistry.WritePrometheus(buffer))
+	assert.Contains(t, buffer.String(), `app_messages_duplicate_total{key="eventid"}`)
+	assert.Contains(t, buffer.String(), `app_messages_duplicate_total{key="correlationid"}`)
+}
+
+func TestProcessMessageDeduplicationFailure(t *testing.T) {
+	processed := 0
+	fail := true
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		if fail {
+			return false, errors.New("failed")
+		}
+		return false, nil
+	}
+	filter := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, appcontext.NewFilteredError("filtered")
+	}
+
+	config := newDeduplicationConfig("", "", "")
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+	envelope := newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1")
+
+	require.NotNil(t, runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope))
+	fail = false
+	require.Nil(t, runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope))
+	require.Nil(t, runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope))
+	assert.Equal(t, 2, processed, "Message which failed should be processed again, but not once successful")
+
+	processed = 0
+	runtime.SetTransforms([]appcontext.AppFunction{filter})
+	envelope = newDeduplicationEnvelope(t, "event-2", "device-1", "correlation-1")
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	assert.Equal(t, 1, processed, "Filtered message should not be processed again")
+}
+
+func TestProcessMessageDeduplicationTTL(t *testing.T) {
+	processed := 0
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	}
+
+	config := newDeduplicationConfig("", "20ms", "")
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+	envelope := newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1")
+
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	require.Equal(t, 1, processed)
+
+	time.Sleep(30 * time.Millisecond)
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	assert.Equal(t, 2, processed, "Message should be processed again once the TTL has expired")
+
+	config.Deduplication.TTL = "10m"
+	time.Sleep(30 * time.Millisecond)
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	require.Equal(t, 3, processed)
+
+	time.Sleep(30 * time.Millisecond)
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	assert.Equal(t, 3, processed, "Updated TTL should be used for keys seen afterwards")
+}
+
+func TestProcessMessageDeduplicationKeyExtractor(t *testing.T) {
+	processed := 0
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	}
+	byDevice := func(envelope types.MessageEnvelope, target interface{}) string {
+		event, _ := target.(models.Event)
+		if event.Device == "panic" {
+			panic("extractor failed")
+		}
+		return event.Device
+	}
+
+	config := newDeduplicationConfig("EventID", "", "")
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+	runtime.SetDeduplicationKeyExtractor(byDevice)
+
+	envelopes := []types.MessageEnvelope{
+		newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1"),
+		newDeduplicationEnvelope(t, "event-2", "device-1", "correlation-2"),
+		newDeduplicationEnvelope(t, "event-3", "device-2", "correlation-3"),
+		newDeduplicationEnvelope(t, "event-4", "panic", "correlation-4"),
+		newDeduplicationEnvelope(t, "event-5", "panic", "correlation-5"),
+	}
+	for _, envelope := range envelopes {
+		runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	}
+
+	assert.Equal(t, 4, processed, "Key extractor should take precedence and messages without a key are processed")
+}
+
+func TestProcessMessageDeduplicationStoreSeenSet(t *testing.T) {
+	processed := 0
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	}
+
+	config := newDeduplicationConfig("", "", DeduplicationSeenSetStore)
+	storeClient := creatMockStoreClient()
+	envelope := newDeduplicationEnvelope(t, "event-1", "device-1", "correlation-1")
+
+	runtime := GolangRuntime{ServiceKey: serviceKey}
+	runtime.Initialize(storeClient, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+	runtime.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	require.Equal(t, 1, processed)
+
+	stored := mockRetrieveObjects(serviceKey + deduplicationKeySuffix)
+	require.Len(t, stored, 1)
+	assert.Empty(t, mockRetrieveObjects(serviceKey), "Keys should not be retried by Store and Forward")
+
+	// The seen-set is loaded from the store after a restart
+	restarted := GolangRuntime{ServiceKey: serviceKey}
+	restarted.Initialize(storeClient, nil)
+	restarted.SetTransforms([]appcontext.AppFunction{transform})
+	restarted.ProcessMessage(&appcontext.Context{LoggingClient: lc, Configuration: config}, envelope)
+	assert.Equal(t, 1, processed, "Duplicate should be dropped after a restart")
+}
@@ -0,0 +1,91 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/journal"
+)
+
+type journalInfo struct {
+	lock   sync.RWMutex
+	writer *journal.Writer
+}
+
+// SetJournal sets the journal the triggers capture the received messages to. Capture is disabled when nil.
+func (gr *GolangRuntime) SetJournal(writer *journal.Writer) {
+	gr.journal.lock.Lock()
+	defer gr.journal.lock.Unlock()
+
+	gr.journal.writer = writer
+}
+
+// CaptureMessage appends the message received by the trigger to the journal, if set. Failures are logged so that
+// the message is still processed.
+func (gr *GolangRuntime) CaptureMessage(edgexcontext *appcontext.Context, envelope types.MessageEnvelope) {
+	gr.journal.lock.RLock()
+	writer := gr.journal.writer
+	gr.journal.lock.RUnlock()
+
+	if writer == nil {
+		return
+	}
+
+	if err := writer.Append(journal.NewEntry(edgexcontext.ReceivedTopic, envelope)); err != nil {
+		edgexcontext.LoggingClient.Error("Failed to capture received message to the journal",
+			"error", err.Error(),
+			clients.CorrelationHeader, envelope.CorrelationID)
+	}
+}
+
+// ReplayJournal processes the messages of the journal entries from the start of the pipeline selected for each,
+// spaced by the time between when they were received divided by speed. See journal.Replay for the speed. Replayed
+// messages are not dropped as duplicates and any output data is discarded since there is no trigger to return it to.
+func (gr *GolangRuntime) ReplayJournal(ctx context.Context, entries []journal.Entry, speed float64,
+	config *common.ConfigurationStruct, edgeXClients common.EdgeXClients) (journal.Summary, error) {
+
+	return journal.Replay(ctx, entries, speed, func(entry journal.Entry) error {
+		edgexContext := &appcontext.Context{
+			CorrelationID:         entry.CorrelationID,
+			ReceivedTopic:         entry.Topic,
+			Configuration:         config,
+			LoggingClient:         edgeXClients.LoggingClient,
+			EventClient:           edgeXClients.EventClient,
+			ValueDescriptorClient: edgeXClients.ValueDescriptorClient,
+			CommandClient:         edgeXClients.CommandClient,
+			NotificationsClient:   edgeXClients.NotificationsClient,
+		}
+		if len(entry.Topic) > 0 {
+			_ = edgexContext.SetValue(appcontext.ValueKeyReceivedTopic, entry.Topic)
+		}
+		edgexContext.SetRequestContext(ctx)
+
+		if messageError := gr.processMessage(edgexContext, entry.Envelope(), false); messageError != nil &&
+			messageError.Kind != appcontext.ErrorKindFiltered {
+			return messageError.Err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,89 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/journal"
+)
+
+func TestCaptureAndReplayJournal(t *testing.T) {
+	directory, err := ioutil.TempDir("", "journal")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	writer, err := journal.NewWriter(directory, journal.DefaultMaxFileSize, journal.DefaultMaxFiles)
+	require.NoError(t, err)
+
+	var topics []string
+	fail := false
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		topic, _ := edgexcontext.StringValue(appcontext.ValueKeyReceivedTopic)
+		topics = append(topics, topic)
+		if fail {
+			return false, errors.New("failed")
+		}
+		return false, nil
+	}
+
+	config := newDeduplicationConfig("", "", "")
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+	// Nothing is captured until the journal is set
+	envelope := newDeduplicationEnvelope(t, "event-0", "device-1", "correlation-0")
+	runtime.CaptureMessage(&appcontext.Context{LoggingClient: lc, ReceivedTopic: "events/device-1"}, envelope)
+
+	runtime.SetJournal(writer)
+	for _, eventID := range []string{"event-1", "event-2"} {
+		edgexcontext := &appcontext.Context{LoggingClient: lc, Configuration: config, ReceivedTopic: "events/device-1"}
+		envelope := newDeduplicationEnvelope(t, eventID, "device-1", "correlation-"+eventID)
+		runtime.CaptureMessage(edgexcontext, envelope)
+		require.Nil(t, runtime.ProcessMessage(edgexcontext, envelope))
+	}
+	require.NoError(t, writer.Close())
+
+	entries, err := journal.Read(directory)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "events/device-1", entries[0].Topic)
+	assert.Equal(t, "correlation-event-1", entries[0].CorrelationID)
+
+	// Replayed messages aren't dropped as duplicates of those already processed
+	topics = nil
+	clients := common.EdgeXClients{LoggingClient: lc}
+	summary, err := runtime.ReplayJournal(context.Background(), entries, 0, config, clients)
+	require.NoError(t, err)
+	assert.Equal(t, journal.Summary{Replayed: 2}, summary)
+	assert.Equal(t, []string{"events/device-1", "events/device-1"}, topics)
+
+	fail = true
+	summary, err = runtime.ReplayJournal(context.Background(), entries, 0, config, clients)
+	require.NoError(t, err)
+	assert.Equal(t, journal.Summary{Replayed: 2, Failed: 2}, summary)
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"strings"
+	"time"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+)
+
+const (
+	// DefaultPipelineId is the Id of the pipeline set via SetTransforms. It is used for any message
+	// that isn't selected by one of the named pipelines.
+	DefaultPipelineId = "default-pipeline"
+
+	topicSeparator      = "/"
+	topicSingleWildcard = "+"
+	topicMultiWildcard  = "#"
+)
+
+// FunctionPipeline is a named set of functions along with the criteria used to select it for a received message.
+// All criteria which are specified must match for the pipeline to be selected.
+type FunctionPipeline struct {
+	// Id uniquely identifies the pipeline
+	Id string
+	// Transforms are the functions executed, in order, for messages routed to this pipeline
+	Transforms []appcontext.AppFunction
+	// Topics selects the pipeline by the topic the message was received on. The MQTT style '+' (single level)
+	// and '#' (multi level) wildcards are supported, using '/' as the level separator.
+	Topics []string
+	// ContentTypes selects the pipeline by the content type of the received message. Content types are compared
+	// without their parameters and case, i.e. 'application/json' matches 'Application/JSON; charset=utf-8'.
+	ContentTypes []string
+	// Predicate selects the pipeline when it returns true for the decoded target
+	Predicate func(target interface{}) bool
+	// Timeout limits the duration of each execution of the pipeline. The Pipeline Timeout from configuration is used when not set.
+	Timeout time.Duration
+	// Version, when set, is used as the pipeline's version rather than the fingerprint of its functions, so that
+	// data stored for later retry is kept across code changes which don't affect it.
+	Version string
+	// Descriptors identify each of the Transforms and their configuration, i.e. for the configurable pipeline, so
+	// that configuration changes change the pipeline's version. The function names are used when not set.
+	Descriptors []FunctionDescriptor
+	// Hash is the version of the pipeline's functions used by Store and Forward
+	Hash string
+	// legacyHash is the version calculated by earlier releases, which is accepted for data they stored
+	legacyHash string
+}
+
+// hasCriteria returns true if at least one of the selection criteria has been specified
+func (pipeline *FunctionPipeline) hasCriteria() bool {
+	return len(pipeline.Topics) > 0 || len(pipeline.ContentTypes) > 0 || pipeline.Predicate != nil
+}
+
+// matchesEnvelope returns true if the topic and content type criteria match the received message.
+// The predicate is evaluated separately since it requires the decoded target.
+func (pipeline *FunctionPipeline) matchesEnvelope(topic string, contentType string) bool {
+	if len(pipeline.Topics) > 0 {
+		found := false
+		for _, pattern := range pipeline.Topics {
+			if topicMatches(pattern, topic) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(pipeline.ContentTypes) > 0 {
+		found := false
+		normalized := codec.Normalize(contentType)
+		for _, expected := range pipeline.ContentTypes {
+			if codec.Normalize(expected) == normalized {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// topicMatches determines if the topic matches the pattern, which may contain the '+' and '#' wildcards
+func topicMatches(pattern string, topic string) bool {
+	if pattern == topic || pattern == topicMultiWildcard {
+		return true
+	}
+
+	patternLevels := strings.Split(pattern, topicSeparator)
+	topicLevels := strings.Split(topic, topicSeparator)
+
+	for index, level := range patternLevels {
+		if level == topicMultiWildcard {
+			// '#' must be the last level and matches the remaining levels, including none
+			return index == len(patternLevels)-1
+		}
+
+		if index >= len(topicLevels) {
+			return false
+		}
+
+		if level != topicSingleWildcard && level != topicLevels[index] {
+			return false
+		}
+	}
+
+	return len(patternLevels) == len(topicLevels)
+}
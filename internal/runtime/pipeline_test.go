@@ -0,0 +1,159 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+)
+
+func TestTopicMatches(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Pattern  string
+		Topic    string
+		Expected bool
+	}{
+		{"Exact match", "edgex/events/device1", "edgex/events/device1", true},
+		{"Exact no match", "edgex/events/device1", "edgex/events/device2", false},
+		{"Multi level wildcard only", "#", "edgex/events/device1", true},
+		{"Multi level wildcard", "edgex/events/#", "edgex/events/device1/reading", true},
+		{"Multi level wildcard parent", "edgex/events/#", "edgex/events", true},
+		{"Multi level wildcard no match", "edgex/events/#", "edgex/other/device1", false},
+		{"Single level wildcard", "edgex/+/device1", "edgex/events/device1", true},
+		{"Single level wildcard too deep", "edgex/+", "edgex/events/device1", false},
+		{"Single level wildcard too short", "edgex/+/device1", "edgex/events", false},
+		{"Pattern longer than topic", "edgex/events/device1", "edgex/events", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, topicMatches(test.Pattern, test.Topic))
+		})
+	}
+}
+
+func TestAddFunctionsPipeline(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+
+	tests := []struct {
+		Name          string
+		Pipeline      FunctionPipeline
+		ErrorExpected bool
+	}{
+		{"Valid", FunctionPipeline{Id: "valid", Topics: []string{"events"}, Transforms: []appcontext.AppFunction{transform}}, false},
+		{"Duplicate", FunctionPipeline{Id: "valid", Topics: []string{"events"}, Transforms: []appcontext.AppFunction{transform}}, true},
+		{"Missing Id", FunctionPipeline{Topics: []string{"events"}, Transforms: []appcontext.AppFunction{transform}}, true},
+		{"Reserved Id", FunctionPipeline{Id: DefaultPipelineId, Topics: []string{"events"}, Transforms: []appcontext.AppFunction{transform}}, true},
+		{"No Transforms", FunctionPipeline{Id: "noTransforms", Topics: []string{"events"}}, true},
+		{"No Criteria", FunctionPipeline{Id: "noCriteria", Transforms: []appcontext.AppFunction{transform}}, true},
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := runtime.AddFunctionsPipeline(test.Pipeline)
+			if test.ErrorExpected {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			actual := runtime.GetPipelineById(test.Pipeline.Id)
+			require.NotNil(t, actual)
+			assert.NotEmpty(t, actual.Hash)
+		})
+	}
+}
+
+func TestSelectPipeline(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+	isDevice2 := func(target interface{}) bool {
+		event, ok := target.(models.Event)
+		return ok && event.Device == "device2"
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+	require.NoError(t, runtime.AddFunctionsPipeline(FunctionPipeline{
+		Id:         "byTopic",
+		Topics:     []string{"edgex/events/device1/#"},
+		Transforms: []appcontext.AppFunction{transform},
+	}))
+	require.NoError(t, runtime.AddFunctionsPipeline(FunctionPipeline{
+		Id:           "byContentType",
+		ContentTypes: []string{clients.ContentTypeCBOR + "; charset=binary"},
+		Transforms:   []appcontext.AppFunction{transform},
+	}))
+	require.NoError(t, runtime.AddFunctionsPipeline(FunctionPipeline{
+		Id:         "byPredicate",
+		Predicate:  isDevice2,
+		Transforms: []appcontext.AppFunction{transform},
+	}))
+
+	tests := []struct {
+		Name        string
+		Topic       string
+		ContentType string
+		Target      interface{}
+		ExpectedId  string
+	}{
+		{"Topic", "edgex/events/device1/reading", clients.ContentTypeJSON, models.Event{}, "byTopic"},
+		{"Content Type", "edgex/events/device3", clients.ContentTypeCBOR, models.Event{}, "byContentType"},
+		{"Content Type Parameters", "edgex/events/device3", "Application/CBOR; charset=utf-8", models.Event{},
+			"byContentType"},
+		{"Predicate", "edgex/events/device2", clients.ContentTypeJSON, models.Event{Device: "device2"}, "byPredicate"},
+		{"Default", "edgex/events/device3", clients.ContentTypeJSON, models.Event{Device: "device3"}, DefaultPipelineId},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual := runtime.selectPipeline(test.Topic, test.ContentType, test.Target)
+			require.NotNil(t, actual)
+			assert.Equal(t, test.ExpectedId, actual.Id)
+		})
+	}
+}
+
+func TestSelectPipelineNoDefault(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	require.NoError(t, runtime.AddFunctionsPipeline(FunctionPipeline{
+		Id:         "byTopic",
+		Topics:     []string{"edgex/events/device1"},
+		Transforms: []appcontext.AppFunction{transform},
+	}))
+
+	assert.Nil(t, runtime.selectPipeline("edgex/events/device2", clients.ContentTypeJSON, models.Event{}))
+}
@@ -18,14 +18,15 @@ package runtime
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
+	"runtime/debug"
 	"strconv"
+	"strings"
 	"sync"
-
-	"github.com/fxamacker/cbor/v2"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
@@ -33,7 +34,18 @@ import (
 	"github.com/student3671/app-functions-sdk-go/appcontext"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/student3671/app-functions-sdk-go/internal/security"
+	"github.com/student3671/app-functions-sdk-go/internal/store/contracts"
 	"github.com/student3671/app-functions-sdk-go/internal/store/db/interfaces"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+)
+
+// Outcomes of a function execution recorded in the metrics and trace spans. Failed executions record the error kind,
+// or panicErrorKind when the function panicked.
+const (
+	outcomeSuccess  = "success"
+	outcomeFiltered = "filtered"
+	panicErrorKind  = "panic"
 )
 
 const unmarshalErrorMessage = "Unable to unmarshal message payload as %s"
@@ -42,21 +54,86 @@ const unmarshalErrorMessage = "Unable to unmarshal message payload as %s"
 type GolangRuntime struct {
 	TargetType     interface{}
 	ServiceKey     string
-	transforms     []appcontext.AppFunction
+	pipelines      []*FunctionPipeline
 	isBusyCopying  sync.Mutex
 	storeForward   storeForwardInfo
+	deadLetters    deadLetterInfo
+	deduplication  deduplicationInfo
 	secretProvider security.SecretProvider
+	codecs         *codec.Registry
+	codecsLock     sync.Mutex
+	journal        journalInfo
+	fanOuts        fanOutBranches
 }
 
 type MessageError struct {
 	Err       error
 	ErrorCode int
+	// Kind is the classification of the error returned by the pipeline function, if any
+	Kind appcontext.ErrorKind
+	// PipelineId identifies the pipeline which failed, empty when the message failed before a pipeline was selected
+	PipelineId string
+	// FunctionIndex is the position of the function which failed, noFunctionIndex when the message failed before
+	// a pipeline was executed
+	FunctionIndex int
+	// StoredForRetry indicates the data was stored by Store and Forward for later retry
+	StoredForRetry bool
 }
 
+// noFunctionIndex is used as the MessageError's FunctionIndex when the message failed before a pipeline was executed
+const noFunctionIndex = -1
+
 // ProcessMessage sends the contents of the message thru the functions pipeline
 func (gr *GolangRuntime) ProcessMessage(edgexcontext *appcontext.Context, envelope types.MessageEnvelope) *MessageError {
+	return gr.processMessage(edgexcontext, envelope, true)
+}
+
+// processMessage sends the contents of the message thru the functions pipeline, dropping duplicates of messages
+// already processed when deduplicate is true
+func (gr *GolangRuntime) processMessage(edgexcontext *appcontext.Context, envelope types.MessageEnvelope,
+	deduplicate bool) *MessageError {
+
+	if len(codec.Normalize(envelope.ContentType)) == 0 {
+		envelope.ContentType = gr.sniffContentType(edgexcontext, envelope)
+	}
+
+	target, contentType, messageError := gr.decodeTarget(edgexcontext, envelope)
+	if messageError != nil {
+		return messageError
+	}
 
-	edgexcontext.LoggingClient.Debug("Processing message: " + strconv.Itoa(len(gr.transforms)) + " Transforms")
+	// Duplicates are dropped before a pipeline is selected, so are treated as successfully processed by the trigger
+	var key string
+	if deduplicate {
+		var duplicate bool
+		if key, duplicate = gr.deduplication.claim(edgexcontext, envelope, target); duplicate {
+			return nil
+		}
+	}
+
+	pipeline := gr.selectPipeline(edgexcontext.ReceivedTopic, envelope.ContentType, target)
+	if pipeline == nil {
+		edgexcontext.LoggingClient.Debug("No pipeline selected for message, message not processed",
+			"topic", edgexcontext.ReceivedTopic,
+			clients.ContentType, envelope.ContentType,
+			clients.CorrelationHeader, envelope.CorrelationID)
+		gr.deduplication.complete(edgexcontext, key, nil)
+		return nil
+	}
+
+	edgexcontext.LoggingClient.Debug(
+		fmt.Sprintf("Processing message with pipeline '%s': %d Transforms", pipeline.Id, len(pipeline.Transforms)))
+
+	messageError = gr.ExecutePipeline(target, contentType, edgexcontext, pipeline, 0, false)
+	gr.deduplication.complete(edgexcontext, key, messageError)
+
+	return messageError
+}
+
+// decodeTarget decodes the message's payload into a new instance of the TargetType, returning it along with the
+// content type to pass to the first function.
+func (gr *GolangRuntime) decodeTarget(edgexcontext *appcontext.Context,
+	envelope types.MessageEnvelope) (interface{}, string, *MessageError) {
 
 	if gr.TargetType == nil {
 		gr.TargetType = &models.Event{}
@@ -65,7 +142,7 @@ func (gr *GolangRuntime) ProcessMessage(edgexcontext *appcontext.Context, envelo
 	if reflect.TypeOf(gr.TargetType).Kind() != reflect.Ptr {
 		err := fmt.Errorf("TargetType must be a pointer, not a value of the target type.")
 		edgexcontext.LoggingClient.Error(err.Error())
-		return &MessageError{Err: err, ErrorCode: http.StatusInternalServerError}
+		return nil, "", &MessageError{Err: err, ErrorCode: http.StatusInternalServerError, FunctionIndex: noFunctionIndex}
 	}
 
 	// Must make a copy of the type so that data isn't retained between calls.
@@ -74,126 +151,620 @@ func (gr *GolangRuntime) ProcessMessage(edgexcontext *appcontext.Context, envelo
 	// Only set when the data is binary so function receiving it knows how to deal with it.
 	var contentType string
 
+	codecs := gr.Codecs()
+	edgexcontext.SetCodecs(codecs)
+
 	switch target.(type) {
 	case *[]byte:
 		target = &envelope.Payload
 		contentType = envelope.ContentType
 
 	default:
-		switch envelope.ContentType {
-		case clients.ContentTypeJSON:
-
-			if err := json.Unmarshal([]byte(envelope.Payload), target); err != nil {
-				message := fmt.Sprintf(unmarshalErrorMessage, "JSON")
-				edgexcontext.LoggingClient.Error(
-					message, "error", err.Error(),
-					clients.CorrelationHeader, envelope.CorrelationID)
-				err = fmt.Errorf("%s : %s", message, err.Error())
-				return &MessageError{Err: err, ErrorCode: http.StatusBadRequest}
-			}
-
-			event, ok := target.(*models.Event)
-			if ok {
-				// Needed for Marking event as handled
-				edgexcontext.EventID = event.ID
-			}
-
-		case clients.ContentTypeCBOR:
-			err := cbor.Unmarshal([]byte(envelope.Payload), target)
-			if err != nil {
-				message := fmt.Sprintf(unmarshalErrorMessage, "CBOR")
-				edgexcontext.LoggingClient.Error(
-					message, "error", err.Error(),
-					clients.CorrelationHeader, envelope.CorrelationID)
-				err = fmt.Errorf("%s : %s", message, err.Error())
-				return &MessageError{Err: err, ErrorCode: http.StatusBadRequest}
-			}
-
-			// Needed for Marking event as handled
-			edgexcontext.EventChecksum = envelope.Checksum
-
-		default:
+		payloadCodec, ok := codecs.Lookup(envelope.ContentType)
+		if !ok {
 			message := "content type for input data not supported"
 			edgexcontext.LoggingClient.Error(message,
 				clients.ContentType, envelope.ContentType,
 				clients.CorrelationHeader, envelope.CorrelationID)
 			err := fmt.Errorf("'%s' %s", envelope.ContentType, message)
-			return &MessageError{Err: err, ErrorCode: http.StatusBadRequest}
+			return nil, "", &MessageError{Err: err, ErrorCode: http.StatusBadRequest, FunctionIndex: noFunctionIndex}
+		}
+
+		if err := payloadCodec.Unmarshal(envelope.Payload, target); err != nil {
+			message := fmt.Sprintf(unmarshalErrorMessage, payloadCodec.Name())
+			edgexcontext.LoggingClient.Error(
+				message, "error", err.Error(),
+				clients.CorrelationHeader, envelope.CorrelationID)
+			err = fmt.Errorf("%s : %s", message, err.Error())
+			return nil, "", &MessageError{Err: err, ErrorCode: http.StatusBadRequest, FunctionIndex: noFunctionIndex}
+		}
+
+		// Needed for Marking event as handled
+		if payloadCodec.ContentType() == codec.ContentTypeCBOR {
+			edgexcontext.EventChecksum = envelope.Checksum
+		} else if event, ok := target.(*models.Event); ok {
+			edgexcontext.EventID = event.ID
 		}
 	}
 
 	edgexcontext.CorrelationID = envelope.CorrelationID
+	edgexcontext.SetReceivedMessage(envelope.Payload, envelope.ContentType)
 
 	// All functions expect an object, not a pointer to an object, so must use reflection to
 	// dereference to pointer to the object
-	target = reflect.ValueOf(target).Elem().Interface()
+	return reflect.ValueOf(target).Elem().Interface(), contentType, nil
+}
 
-	// Make copy of transform functions to avoid disruption of pipeline when updating the pipeline from registry
-	gr.isBusyCopying.Lock()
-	transforms := make([]appcontext.AppFunction, len(gr.transforms))
-	copy(transforms, gr.transforms)
-	gr.isBusyCopying.Unlock()
+// sniffContentType returns the content type of the codec recognising the payload of a message received without a
+// content type. An empty string is returned if none of the codecs recognise it.
+func (gr *GolangRuntime) sniffContentType(edgexcontext *appcontext.Context, envelope types.MessageEnvelope) string {
+	payloadCodec, ok := gr.Codecs().Sniff(envelope.Payload)
+	if !ok {
+		return ""
+	}
 
-	return gr.ExecutePipeline(target, contentType, edgexcontext, transforms, 0, false)
+	edgexcontext.LoggingClient.Debug("Message received without content type, payload sniffed",
+		clients.ContentType, payloadCodec.ContentType(),
+		clients.CorrelationHeader, envelope.CorrelationID)
+	return payloadCodec.ContentType()
+}
+
+// Codecs returns the registry of codecs used to decode the received messages and encode the output data
+func (gr *GolangRuntime) Codecs() *codec.Registry {
+	gr.codecsLock.Lock()
+	defer gr.codecsLock.Unlock()
 
+	if gr.codecs == nil {
+		gr.codecs = codec.NewRegistry()
+	}
+	return gr.codecs
+}
+
+// SetCodecs sets the registry of codecs used to decode the received messages and encode the output data
+func (gr *GolangRuntime) SetCodecs(codecs *codec.Registry) {
+	gr.codecsLock.Lock()
+	defer gr.codecsLock.Unlock()
+
+	gr.codecs = codecs
 }
 
 // Initialize sets the internal reference to the StoreClient for use when Store and Forward is enabled
 func (gr *GolangRuntime) Initialize(storeClient interfaces.StoreClient, secretProvider security.SecretProvider) {
 	gr.storeForward.storeClient = storeClient
 	gr.storeForward.runtime = gr
+	gr.deadLetters.runtime = gr
+	gr.deduplication.runtime = gr
 	gr.secretProvider = secretProvider
 }
 
-// SetTransforms is thread safe to set transforms
+// SetTransforms is thread safe to set transforms of the default pipeline
 func (gr *GolangRuntime) SetTransforms(transforms []appcontext.AppFunction) {
+	gr.SetTransformsWithDescriptors(transforms, nil)
+}
+
+// SetTransformsWithDescriptors is thread safe to set transforms of the default pipeline along with the descriptors
+// used to calculate its version. The function names are used when descriptors is nil.
+func (gr *GolangRuntime) SetTransformsWithDescriptors(transforms []appcontext.AppFunction,
+	descriptors []FunctionDescriptor) {
 	gr.isBusyCopying.Lock()
-	gr.transforms = transforms
-	gr.storeForward.pipelineHash = gr.storeForward.calculatePipelineHash() // Only need to calculate hash when the pipeline changes.
+	defer gr.isBusyCopying.Unlock()
+
+	for _, pipeline := range gr.pipelines {
+		if pipeline.Id == DefaultPipelineId {
+			pipeline.Transforms = transforms
+			pipeline.Descriptors = descriptors
+			pipeline.updateHash() // Only need to calculate hash when the pipeline changes.
+			return
+		}
+	}
+
+	pipeline := &FunctionPipeline{
+		Id:          DefaultPipelineId,
+		Transforms:  transforms,
+		Descriptors: descriptors,
+	}
+	pipeline.updateHash()
+	gr.pipelines = append(gr.pipelines, pipeline)
+}
+
+// AddFunctionsPipeline is thread safe to add a named pipeline which is selected by the pipeline's topics,
+// content types and/or predicate. Named pipelines are evaluated in the order they are added and the first match is used.
+// The default pipeline is only used when none of the named pipelines match.
+func (gr *GolangRuntime) AddFunctionsPipeline(pipeline FunctionPipeline) error {
+	if len(strings.TrimSpace(pipeline.Id)) == 0 {
+		return errors.New("pipeline Id can not be empty")
+	}
+
+	if pipeline.Id == DefaultPipelineId {
+		return fmt.Errorf("pipeline Id '%s' is reserved for the default pipeline", DefaultPipelineId)
+	}
+
+	if len(pipeline.Transforms) == 0 {
+		return fmt.Errorf("no transforms provided for pipeline '%s'", pipeline.Id)
+	}
+
+	if !pipeline.hasCriteria() {
+		return fmt.Errorf("pipeline '%s' must specify at least one topic, content type or predicate", pipeline.Id)
+	}
+
+	gr.isBusyCopying.Lock()
+	defer gr.isBusyCopying.Unlock()
+
+	for _, existing := range gr.pipelines {
+		if existing.Id == pipeline.Id {
+			return fmt.Errorf("pipeline with Id '%s' already exists", pipeline.Id)
+		}
+	}
+
+	pipeline.updateHash()
+	gr.pipelines = append(gr.pipelines, &pipeline)
+
+	return nil
+}
+
+// SetPipelineTimeout is thread safe to set the timeout of the pipeline with the specified Id.
+// A timeout of zero uses the Pipeline Timeout from configuration.
+func (gr *GolangRuntime) SetPipelineTimeout(id string, timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("timeout for pipeline '%s' can not be negative", id)
+	}
+
+	gr.isBusyCopying.Lock()
+	defer gr.isBusyCopying.Unlock()
+
+	for _, pipeline := range gr.pipelines {
+		if pipeline.Id == id {
+			pipeline.Timeout = timeout
+			return nil
+		}
+	}
+
+	return fmt.Errorf("pipeline with Id '%s' not found", id)
+}
+
+// SetPipelineVersion is thread safe to set the explicit version of the pipeline with the specified Id, which is used
+// by Store and Forward rather than the fingerprint of its functions. An empty version reverts to the fingerprint.
+func (gr *GolangRuntime) SetPipelineVersion(id string, version string) error {
+	gr.isBusyCopying.Lock()
+	defer gr.isBusyCopying.Unlock()
+
+	for _, pipeline := range gr.pipelines {
+		if pipeline.Id == id {
+			pipeline.Version = version
+			pipeline.updateHash()
+			return nil
+		}
+	}
+
+	return fmt.Errorf("pipeline with Id '%s' not found", id)
+}
+
+// GetPipelineById returns a copy of the pipeline with the specified Id or nil if it doesn't exist.
+func (gr *GolangRuntime) GetPipelineById(id string) *FunctionPipeline {
+	gr.isBusyCopying.Lock()
+	defer gr.isBusyCopying.Unlock()
+
+	for _, pipeline := range gr.pipelines {
+		if pipeline.Id == id {
+			return copyPipeline(pipeline)
+		}
+	}
+
+	return nil
+}
+
+// GetDefaultPipeline returns a copy of the default pipeline or nil if transforms have not been set.
+func (gr *GolangRuntime) GetDefaultPipeline() *FunctionPipeline {
+	return gr.GetPipelineById(DefaultPipelineId)
+}
+
+// selectPipeline returns a copy of the first named pipeline whose criteria match the received message,
+// falling back to the default pipeline. Copies avoid disruption of the pipeline when it is updated from the registry.
+func (gr *GolangRuntime) selectPipeline(topic string, contentType string, target interface{}) *FunctionPipeline {
+	gr.isBusyCopying.Lock()
+	pipelines := make([]*FunctionPipeline, len(gr.pipelines))
+	for index, pipeline := range gr.pipelines {
+		pipelines[index] = copyPipeline(pipeline)
+	}
 	gr.isBusyCopying.Unlock()
+
+	var defaultPipeline *FunctionPipeline
+	for _, pipeline := range pipelines {
+		if pipeline.Id == DefaultPipelineId {
+			defaultPipeline = pipeline
+			continue
+		}
+
+		if !pipeline.matchesEnvelope(topic, contentType) {
+			continue
+		}
+
+		// Predicate is evaluated outside the lock since it is user code
+		if pipeline.Predicate != nil && !evaluatePredicate(pipeline.Predicate, target) {
+			continue
+		}
+
+		return pipeline
+	}
+
+	return defaultPipeline
+}
+
+// evaluatePredicate calls the pipeline's predicate, treating a panic as not matching
+func evaluatePredicate(predicate func(target interface{}) bool, target interface{}) (matched bool) {
+	defer func() {
+		if recover() != nil {
+			matched = false
+		}
+	}()
+
+	return predicate(target)
+}
+
+func copyPipeline(pipeline *FunctionPipeline) *FunctionPipeline {
+	pipelineCopy := *pipeline
+	pipelineCopy.Transforms = make([]appcontext.AppFunction, len(pipeline.Transforms))
+	copy(pipelineCopy.Transforms, pipeline.Transforms)
+	return &pipelineCopy
 }
 
+// ExecutePipeline executes the functions of the specified pipeline starting at startPosition
 func (gr *GolangRuntime) ExecutePipeline(target interface{}, contentType string, edgexcontext *appcontext.Context,
-	transforms []appcontext.AppFunction, startPosition int, isRetry bool) *MessageError {
+	pipeline *FunctionPipeline, startPosition int, isRetry bool) *MessageError {
+
+	edgexcontext.SecretProvider = gr.secretProvider
+
+	requestCtx, cancel := gr.createRequestContext(edgexcontext, pipeline)
+	defer cancel()
+
+	requestCtx, span := startPipelineSpan(requestCtx, edgexcontext, "pipeline "+pipeline.Id, pipeline)
+	edgexcontext.SetRequestContext(requestCtx)
+
+	messageError := gr.executeFunctions(
+		target, contentType, edgexcontext, pipeline, pipeline.Transforms, nil, startPosition, isRetry)
+	endPipelineSpan(span, messageError)
+
+	return messageError
+}
+
+// createRequestContext derives the request context for a pipeline execution from the context set by the trigger.
+// The pipeline's timeout is used if set, otherwise the Pipeline Timeout from configuration, if any.
+func (gr *GolangRuntime) createRequestContext(edgexcontext *appcontext.Context,
+	pipeline *FunctionPipeline) (context.Context, context.CancelFunc) {
+
+	parent := edgexcontext.RequestContext()
+
+	timeout := pipeline.Timeout
+	if timeout == 0 && edgexcontext.Configuration != nil && edgexcontext.Configuration.Writable.Pipeline.Timeout != "" {
+		var err error
+		timeout, err = time.ParseDuration(edgexcontext.Configuration.Writable.Pipeline.Timeout)
+		if err != nil {
+			edgexcontext.LoggingClient.Warn(
+				fmt.Sprintf("Pipeline Timeout of '%s' is invalid, no timeout used", edgexcontext.Configuration.Writable.Pipeline.Timeout),
+				"error", err.Error())
+			timeout = 0
+		}
+	}
+
+	if timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+
+	return context.WithCancel(parent)
+}
+
+// executeFunctions executes the flow of functions, which is either the pipeline's main flow or one of its
+// fan-out branches as identified by branchPath.
+func (gr *GolangRuntime) executeFunctions(target interface{}, contentType string, edgexcontext *appcontext.Context,
+	pipeline *FunctionPipeline, transforms []appcontext.AppFunction, branchPath []contracts.BranchPosition,
+	startPosition int, isRetry bool) *MessageError {
 
 	var result interface{}
 	var continuePipeline = true
-
-	edgexcontext.SecretProvider = gr.secretProvider
+	requestCtx := edgexcontext.RequestContext()
 
 	for functionIndex, trxFunc := range transforms {
 		if functionIndex < startPosition {
 			continue
 		}
 
+		if err := requestCtx.Err(); err != nil {
+			return gr.requestContextDone(err, edgexcontext, pipeline, branchPath, functionIndex)
+		}
+
 		edgexcontext.RetryData = nil
 
+		metricLabels := functionMetricLabels(pipeline, branchPath, functionIndex, trxFunc)
+		params := []interface{}{result}
 		if result == nil {
-			continuePipeline, result = trxFunc(edgexcontext, target, contentType)
-		} else {
-			continuePipeline, result = trxFunc(edgexcontext, result)
+			params = []interface{}{target, contentType}
+		}
+
+		functionCtx, span := startFunctionSpan(requestCtx, metricLabels, params[0])
+		edgexcontext.SetRequestContext(functionCtx)
+		started := time.Now()
+
+		var panicErr error
+		continuePipeline, result, panicErr = invokeFunction(trxFunc, edgexcontext, params...)
+
+		telemetry.RecordFunctionInvocation(metricLabels, time.Since(started))
+		edgexcontext.SetRequestContext(requestCtx)
+
+		if panicErr != nil {
+			telemetry.RecordFunctionError(metricLabels, panicErrorKind)
+			endFunctionSpan(span, panicErrorKind, panicErr)
+			edgexcontext.LoggingClient.Error(
+				fmt.Sprintf("Pipeline '%s' function #%d panicked", pipeline.Id, functionIndex),
+				"error", panicErr.Error(),
+				"branch", formatBranchPath(branchPath),
+				clients.CorrelationHeader, edgexcontext.CorrelationID)
+			return &MessageError{Err: panicErr, ErrorCode: http.StatusInternalServerError,
+				PipelineId: pipeline.Id, FunctionIndex: functionIndex}
 		}
 
 		if continuePipeline != true {
 			if result != nil {
 				if err, ok := result.(error); ok {
-					edgexcontext.LoggingClient.Error(
-						fmt.Sprintf("Pipeline function #%d resulted in error", functionIndex),
-						"error", err.Error(), clients.CorrelationHeader, edgexcontext.CorrelationID)
-					if edgexcontext.RetryData != nil && !isRetry {
-						gr.storeForward.storeForLaterRetry(edgexcontext.RetryData, edgexcontext, functionIndex)
+					if kind := appcontext.ErrorKindOf(err); kind == appcontext.ErrorKindFiltered {
+						telemetry.RecordFunctionFiltered(metricLabels)
+						endFunctionSpan(span, outcomeFiltered, nil)
+					} else {
+						telemetry.RecordFunctionError(metricLabels, kind.String())
+						endFunctionSpan(span, kind.String(), err)
 					}
-
-					return &MessageError{Err: err, ErrorCode: http.StatusUnprocessableEntity}
+					return gr.functionFailed(err, edgexcontext, pipeline, branchPath, functionIndex, isRetry)
 				}
 			}
+
+			// Stopping before the last function without an error means the data was filtered out
+			if functionIndex < len(transforms)-1 {
+				telemetry.RecordFunctionFiltered(metricLabels)
+				endFunctionSpan(span, outcomeFiltered, nil)
+			} else {
+				endFunctionSpan(span, outcomeSuccess, nil)
+			}
 			break
 		}
+
+		endFunctionSpan(span, outcomeSuccess, nil)
+
+		if fanOut, ok := result.(appcontext.FanOut); ok {
+			if functionIndex < len(transforms)-1 {
+				edgexcontext.LoggingClient.Warn(
+					fmt.Sprintf("Pipeline '%s' function #%d fanned out, remaining functions in flow will not be executed",
+						pipeline.Id, functionIndex),
+					clients.CorrelationHeader, edgexcontext.CorrelationID)
+			}
+
+			return gr.executeBranches(fanOut, edgexcontext, pipeline, branchPath, functionIndex, isRetry)
+		}
 	}
 
 	return nil
 }
 
+// functionFailed handles the error returned by the function at functionIndex based on its classification.
+// Only retryable and unclassified errors are stored for later retry, and filtered errors are not logged as failures.
+func (gr *GolangRuntime) functionFailed(err error, edgexcontext *appcontext.Context, pipeline *FunctionPipeline,
+	branchPath []contracts.BranchPosition, functionIndex int, isRetry bool) *MessageError {
+
+	kind := appcontext.ErrorKindOf(err)
+	messageError := &MessageError{Err: err, ErrorCode: http.StatusUnprocessableEntity, Kind: kind,
+		PipelineId: pipeline.Id, FunctionIndex: functionIndex}
+
+	if kind == appcontext.ErrorKindFiltered {
+		edgexcontext.LoggingClient.Debug(
+			fmt.Sprintf("Pipeline '%s' function #%d filtered the data", pipeline.Id, functionIndex),
+			"reason", err.Error(),
+			"branch", formatBranchPath(branchPath),
+			clients.CorrelationHeader, edgexcontext.CorrelationID)
+		return messageError
+	}
+
+	edgexcontext.LoggingClient.Error(
+		fmt.Sprintf("Pipeline '%s' function #%d resulted in error", pipeline.Id, functionIndex),
+		"error", err.Error(),
+		"kind", kind.String(),
+		"branch", formatBranchPath(branchPath),
+		clients.CorrelationHeader, edgexcontext.CorrelationID)
+
+	if edgexcontext.RetryData != nil && !isRetry && kind != appcontext.ErrorKindPermanent {
+		messageError.StoredForRetry = gr.storeForward.storeForLaterRetry(
+			edgexcontext.RetryData, edgexcontext, pipeline, branchPath, functionIndex)
+	}
+
+	return messageError
+}
+
+// invokeFunction calls the pipeline function, recovering from any panic so that it doesn't take down the service.
+// The panic is returned as an error which includes the stack trace.
+func invokeFunction(function appcontext.AppFunction, edgexcontext *appcontext.Context,
+	params ...interface{}) (continuePipeline bool, result interface{}, panicErr error) {
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			continuePipeline = false
+			result = nil
+			panicErr = fmt.Errorf("recovered from panic: %v\n%s", recovered, debug.Stack())
+		}
+	}()
+
+	continuePipeline, result = function(edgexcontext, params...)
+	return continuePipeline, result, nil
+}
+
+// requestContextDone stops the flow when the request context is done before the function at functionIndex is executed
+func (gr *GolangRuntime) requestContextDone(err error, edgexcontext *appcontext.Context, pipeline *FunctionPipeline,
+	branchPath []contracts.BranchPosition, functionIndex int) *MessageError {
+
+	errorCode := http.StatusServiceUnavailable
+	if errors.Is(err, context.DeadlineExceeded) {
+		errorCode = http.StatusGatewayTimeout
+	}
+
+	err = fmt.Errorf("pipeline '%s' stopped before function #%d: %s", pipeline.Id, functionIndex, err.Error())
+	edgexcontext.LoggingClient.Error(err.Error(),
+		"branch", formatBranchPath(branchPath),
+		clients.CorrelationHeader, edgexcontext.CorrelationID)
+
+	return &MessageError{Err: err, ErrorCode: errorCode, PipelineId: pipeline.Id, FunctionIndex: functionIndex}
+}
+
+// executeBranches executes each of the fan-out's branches in parallel with a copy of the context, so that
+// each branch has its own RetryData. The first OutputData set by a branch, in branch order, is set on the context
+// and the outputs added by the branches are added to the context.
+func (gr *GolangRuntime) executeBranches(fanOut appcontext.FanOut, edgexcontext *appcontext.Context,
+	pipeline *FunctionPipeline, branchPath []contracts.BranchPosition, functionIndex int, isRetry bool) *MessageError {
+
+	gr.fanOuts.record(pipeline, branchPath, functionIndex, fanOut.Branches)
+
+	branchCount := len(fanOut.Branches)
+	branchContexts := make([]*appcontext.Context, branchCount)
+	branchErrors := make([]*MessageError, branchCount)
+	branchWg := sync.WaitGroup{}
+
+	for branchIndex, branch := range fanOut.Branches {
+		branchContexts[branchIndex] = edgexcontext.Branch()
+
+		// Must make a copy of the path since each branch appends to it.
+		path := make([]contracts.BranchPosition, len(branchPath), len(branchPath)+1)
+		copy(path, branchPath)
+		path = append(path, contracts.BranchPosition{FunctionIndex: functionIndex, Branch: branchIndex})
+
+		branchWg.Add(1)
+		go func(branchIndex int, branch []appcontext.AppFunction, path []contracts.BranchPosition) {
+			defer branchWg.Done()
+			branchErrors[branchIndex] = gr.executeFunctions(
+				fanOut.Data, "", branchContexts[branchIndex], pipeline, branch, path, 0, isRetry)
+		}(branchIndex, branch, path)
+	}
+
+	branchWg.Wait()
+
+	for _, branchContext := range branchContexts {
+		edgexcontext.MergeBranch(branchContext)
+	}
+
+	var failed []string
+	var errorCode int
+	allStored := true
+	for branchIndex, branchError := range branchErrors {
+		// A branch filtering the data is not a failure of the fan-out
+		if branchError == nil || branchError.Kind == appcontext.ErrorKindFiltered {
+			continue
+		}
+
+		if errorCode == 0 {
+			errorCode = branchError.ErrorCode
+		}
+
+		allStored = allStored && branchError.StoredForRetry
+		failed = append(failed, fmt.Sprintf("branch #%d: %s", branchIndex, branchError.Err.Error()))
+	}
+
+	if len(failed) > 0 {
+		err := fmt.Errorf("%d of %d branches failed: %s", len(failed), branchCount, strings.Join(failed, "; "))
+		return &MessageError{Err: err, ErrorCode: errorCode, PipelineId: pipeline.Id, FunctionIndex: functionIndex,
+			StoredForRetry: allStored}
+	}
+
+	return nil
+}
+
+// fanOutBranches records the branches returned by each fan-out function, so that the branch a stored item is to be
+// retried with can be resolved without invoking the fan-out function again with data it didn't receive. The
+// branches are recorded per pipeline version, since the fan-out functions change with the pipeline's functions.
+type fanOutBranches struct {
+	lock sync.Mutex
+	// hashes are the versions of the pipelines the branches were recorded for, keyed by pipeline Id
+	hashes   map[string]string
+	branches map[fanOutPosition][][]appcontext.AppFunction
+}
+
+// fanOutPosition identifies a fan-out function by the Id of its pipeline and its position within the pipeline
+type fanOutPosition struct {
+	pipelineId string
+	position   string
+}
+
+func newFanOutPosition(pipeline *FunctionPipeline, branchPath []contracts.BranchPosition, functionIndex int) fanOutPosition {
+	return fanOutPosition{
+		pipelineId: pipeline.Id,
+		position:   formatBranchPath(branchPath) + "/" + strconv.Itoa(functionIndex),
+	}
+}
+
+// record records the branches returned by the fan-out function at functionIndex of the flow identified by branchPath.
+// The branches recorded for an earlier version of the pipeline are forgotten.
+func (fanOuts *fanOutBranches) record(pipeline *FunctionPipeline, branchPath []contracts.BranchPosition,
+	functionIndex int, branches [][]appcontext.AppFunction) {
+
+	fanOuts.lock.Lock()
+	defer fanOuts.lock.Unlock()
+
+	if fanOuts.branches == nil {
+		fanOuts.hashes = make(map[string]string)
+		fanOuts.branches = make(map[fanOutPosition][][]appcontext.AppFunction)
+	}
+
+	if hash, ok := fanOuts.hashes[pipeline.Id]; ok && hash != pipeline.Hash {
+		for position := range fanOuts.branches {
+			if position.pipelineId == pipeline.Id {
+				delete(fanOuts.branches, position)
+			}
+		}
+	}
+
+	fanOuts.hashes[pipeline.Id] = pipeline.Hash
+	fanOuts.branches[newFanOutPosition(pipeline, branchPath, functionIndex)] = branches
+}
+
+// lookup returns the recorded branches of the fan-out function, false if it hasn't fanned out since the service
+// started or the pipeline changed
+func (fanOuts *fanOutBranches) lookup(pipeline *FunctionPipeline, branchPath []contracts.BranchPosition,
+	functionIndex int) ([][]appcontext.AppFunction, bool) {
+
+	fanOuts.lock.Lock()
+	defer fanOuts.lock.Unlock()
+
+	if fanOuts.hashes[pipeline.Id] != pipeline.Hash {
+		return nil, false
+	}
+
+	branches, ok := fanOuts.branches[newFanOutPosition(pipeline, branchPath, functionIndex)]
+	return branches, ok
+}
+
+// functionMetricLabels identifies the function at functionIndex of the flow identified by branchPath in the metrics
+func functionMetricLabels(pipeline *FunctionPipeline, branchPath []contracts.BranchPosition, functionIndex int,
+	function appcontext.AppFunction) telemetry.FunctionLabels {
+
+	position := strconv.Itoa(functionIndex)
+	if len(branchPath) > 0 {
+		position = formatBranchPath(branchPath) + "/" + position
+	}
+
+	return telemetry.FunctionLabels{
+		PipelineId: pipeline.Id,
+		Position:   position,
+		Function:   functionName(function),
+	}
+}
+
+// formatBranchPath formats the branch path for logging as <function index>.<branch index> pairs
+func formatBranchPath(branchPath []contracts.BranchPosition) string {
+	if len(branchPath) == 0 {
+		return "main"
+	}
+
+	positions := make([]string, len(branchPath))
+	for index, position := range branchPath {
+		positions[index] = fmt.Sprintf("%d.%d", position.FunctionIndex, position.Branch)
+	}
+
+	return strings.Join(positions, "/")
+}
+
 func (gr *GolangRuntime) StartStoreAndForward(
 	appWg *sync.WaitGroup,
 	appCtx context.Context,
@@ -16,10 +16,14 @@
 package runtime
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/edgexfoundry/go-mod-bootstrap/config"
 	"github.com/fxamacker/cbor/v2"
@@ -32,6 +36,9 @@ import (
 	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
 	"github.com/student3671/app-functions-sdk-go/appcontext"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/store/contracts"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
 	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
 )
 
@@ -400,6 +407,106 @@ func TestProcessMessageTargetType(t *testing.T) {
 	}
 }
 
+// readingCodec is a custom codec encoding the reading as 'device=value'
+type readingCodec struct{}
+
+func (readingCodec) Name() string        { return "Reading" }
+func (readingCodec) ContentType() string { return "text/x-reading" }
+
+func (readingCodec) Marshal(data interface{}) ([]byte, error) {
+	reading := data.(models.Reading)
+	return []byte(reading.Device + "=" + reading.Value), nil
+}
+
+func (readingCodec) Unmarshal(payload []byte, target interface{}) error {
+	parts := bytes.SplitN(payload, []byte("="), 2)
+	if len(parts) != 2 {
+		return errors.New("missing '='")
+	}
+
+	reading := target.(*models.Reading)
+	reading.Device = string(parts[0])
+	reading.Value = string(parts[1])
+	return nil
+}
+
+func TestProcessMessageCodecs(t *testing.T) {
+	eventIn := models.Event{ID: "1234", Device: devID1}
+	eventJson, _ := json.Marshal(eventIn)
+	eventCborBytes, err := cbor.Marshal(eventIn)
+	require.NoError(t, err)
+
+	readingCodecs := codec.NewRegistry()
+	require.NoError(t, readingCodecs.Register(readingCodec{}))
+
+	tests := []struct {
+		Name                string
+		TargetType          interface{}
+		Codecs              *codec.Registry
+		Payload             []byte
+		ContentType         string
+		ExpectedTarget      interface{}
+		ExpectedContentType string
+		ExpectedEventID     string
+		ExpectedErrorCode   int
+	}{
+		{"JSON with parameters", nil, nil, eventJson, "application/json; charset=utf-8", eventIn, "", "1234", 0},
+		{"JSON suffix", nil, nil, eventJson, "application/vnd.edgex.event+json", eventIn, "", "1234", 0},
+		{"Sniffed JSON", nil, nil, eventJson, "", eventIn, "", "1234", 0},
+		{"Sniffed CBOR", nil, nil, eventCborBytes, "", eventIn, "", "", 0},
+		{"Sniffed Byte Slice", &[]byte{}, nil, eventJson, "", eventJson, clients.ContentTypeJSON, "", 0},
+		{"Registered Codec", &models.Reading{}, readingCodecs, []byte("thermostat=21"), "text/x-reading",
+			models.Reading{Device: "thermostat", Value: "21"}, "", "", 0},
+		{"Codec Not Registered", &models.Reading{}, nil, []byte("thermostat=21"), "text/x-reading", nil, "", "",
+			http.StatusBadRequest},
+		{"Unrecognised Payload", nil, nil, []byte("thermostat=21"), "", nil, "", "", http.StatusBadRequest},
+		{"Invalid Payload", &models.Reading{}, readingCodecs, []byte("thermostat"), "text/x-reading", nil, "", "",
+			http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var actualTarget interface{}
+			var actualContentType string
+			transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				actualTarget = params[0]
+				if len(params) > 1 {
+					actualContentType, _ = params[1].(string)
+				}
+				return false, nil
+			}
+
+			context := &appcontext.Context{LoggingClient: lc}
+			runtime := GolangRuntime{TargetType: test.TargetType}
+			runtime.Initialize(nil, nil)
+			if test.Codecs != nil {
+				runtime.SetCodecs(test.Codecs)
+			}
+			runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+			envelope := types.MessageEnvelope{CorrelationID: "123-234-345-456", Payload: test.Payload, ContentType: test.ContentType}
+			messageError := runtime.ProcessMessage(context, envelope)
+			if test.ExpectedErrorCode != 0 {
+				require.NotNil(t, messageError)
+				assert.Equal(t, test.ExpectedErrorCode, messageError.ErrorCode)
+				assert.Nil(t, actualTarget, "pipeline should not have been executed")
+				return
+			}
+
+			require.Nil(t, messageError)
+			if expectedEvent, ok := test.ExpectedTarget.(models.Event); ok {
+				require.IsType(t, models.Event{}, actualTarget)
+				assert.Equal(t, expectedEvent.Device, actualTarget.(models.Event).Device)
+			} else {
+				assert.Equal(t, test.ExpectedTarget, actualTarget)
+			}
+			assert.Equal(t, test.ExpectedContentType, actualContentType)
+			assert.Equal(t, test.ExpectedEventID, context.EventID)
+			assert.Equal(t, runtime.Codecs(), context.Codecs(), "Context should use the runtime's codecs")
+		})
+	}
+}
+
 func TestExecutePipelinePersist(t *testing.T) {
 	expectedItemCount := 1
 	config := common.ConfigurationStruct{
@@ -431,7 +538,7 @@ func TestExecutePipelinePersist(t *testing.T) {
 	payload := []byte("My Payload")
 
 	// Target of this test
-	actual := runtime.ExecutePipeline(payload, "", &ctx, runtime.transforms, 0, false)
+	actual := runtime.ExecutePipeline(payload, "", &ctx, runtime.GetDefaultPipeline(), 0, false)
 
 	require.NotNil(t, actual)
 	require.Error(t, actual.Err, "Error expected from export function")
@@ -441,4 +548,302 @@ func TestExecutePipelinePersist(t *testing.T) {
 	assert.Equal(t, ctx.CorrelationID, storedObjects[0].CorrelationID, "CorrelationID not as expected")
 	assert.Equal(t, ctx.EventID, storedObjects[0].EventID, "EventID not as expected")
 	assert.Equal(t, ctx.EventChecksum, storedObjects[0].EventChecksum, "EventChecksum not as expected")
+	assert.Equal(t, DefaultPipelineId, storedObjects[0].PipelineId, "PipelineId not as expected")
+}
+
+func TestExecutePipelineFanOut(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel: "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{
+				Enabled:       true,
+				MaxRetryCount: 10},
+		},
+	}
+
+	ctx := appcontext.Context{
+		Configuration: &config,
+		LoggingClient: lc,
+		CorrelationID: "CorrelationID",
+	}
+
+	payload := []byte("My Payload")
+	branch1WasCalled := false
+
+	transformPassthru := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, params[0]
+	}
+	branch1 := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		branch1WasCalled = true
+		assert.Equal(t, payload, params[0], "Branch did not receive fan-out data")
+		edgexcontext.Complete([]byte("branch1"))
+		return false, nil
+	}
+	failure := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		edgexcontext.SetRetryData(params[0].([]byte))
+		return false, errors.New("export failed")
+	}
+
+	runtime := GolangRuntime{ServiceKey: serviceKey}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{
+		transformPassthru,
+		appcontext.NewFanOut(
+			[]appcontext.AppFunction{branch1},
+			[]appcontext.AppFunction{transformPassthru, failure},
+		),
+	})
+
+	// Target of this test
+	actual := runtime.ExecutePipeline(payload, "", &ctx, runtime.GetDefaultPipeline(), 0, false)
+
+	require.NotNil(t, actual)
+	require.Error(t, actual.Err, "Error expected from failed branch")
+	assert.Contains(t, actual.Err.Error(), "1 of 2 branches failed")
+	assert.True(t, branch1WasCalled, "branch1 should have been called")
+	assert.Equal(t, []byte("branch1"), ctx.OutputData, "OutputData from branch not set")
+
+	storedObjects := mockRetrieveObjects(serviceKey)
+	require.Equal(t, 1, len(storedObjects), "unexpected item count")
+	assert.Equal(t, 1, storedObjects[0].PipelinePosition, "PipelinePosition not as expected")
+	assert.Equal(t,
+		[]contracts.BranchPosition{{FunctionIndex: 1, Branch: 1}},
+		storedObjects[0].BranchPath,
+		"BranchPath not as expected")
+}
+
+func TestFanOutBranchesPipelineVersion(t *testing.T) {
+	branches := [][]appcontext.AppFunction{{}, {}}
+	branchPath := []contracts.BranchPosition{{FunctionIndex: 0, Branch: 1}}
+
+	fanOuts := fanOutBranches{}
+	pipeline := &FunctionPipeline{Id: "pipeline", Hash: "v1"}
+	fanOuts.record(pipeline, nil, 0, branches)
+	fanOuts.record(pipeline, branchPath, 1, branches)
+
+	// A copy of the pipeline, as selected for a message, resolves the recorded branches
+	copied := &FunctionPipeline{Id: "pipeline", Hash: "v1"}
+	_, ok := fanOuts.lookup(copied, nil, 0)
+	assert.True(t, ok)
+
+	// A new version of the pipeline doesn't resolve the branches recorded for the previous version
+	changed := &FunctionPipeline{Id: "pipeline", Hash: "v2"}
+	_, ok = fanOuts.lookup(changed, nil, 0)
+	assert.False(t, ok)
+
+	fanOuts.record(changed, nil, 0, branches)
+	assert.Len(t, fanOuts.branches, 1, "branches of the previous version should be evicted")
+	_, ok = fanOuts.lookup(changed, branchPath, 1)
+	assert.False(t, ok)
+	_, ok = fanOuts.lookup(changed, nil, 0)
+	assert.True(t, ok)
+}
+
+func TestExecutePipelineTimeout(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel: "DEBUG",
+			Pipeline: common.PipelineInfo{
+				Timeout: "10ms",
+			},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		pipelineTimeout time.Duration
+		expectedTimeout time.Duration
+	}{
+		{"Configured timeout", 0, 10 * time.Millisecond},
+		{"Pipeline timeout", 20 * time.Millisecond, 20 * time.Millisecond},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := appcontext.Context{
+				Configuration: &config,
+				LoggingClient: lc,
+			}
+
+			secondWasCalled := false
+			var deadline time.Time
+			waitForTimeout := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				deadline, _ = edgexcontext.RequestContext().Deadline()
+				<-edgexcontext.RequestContext().Done()
+				return true, params[0]
+			}
+			second := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				secondWasCalled = true
+				return false, nil
+			}
+
+			runtime := GolangRuntime{}
+			runtime.SetTransforms([]appcontext.AppFunction{waitForTimeout, second})
+			require.NoError(t, runtime.SetPipelineTimeout(DefaultPipelineId, test.pipelineTimeout))
+
+			started := time.Now()
+			msgErr := runtime.ExecutePipeline([]byte("data"), "", &ctx, runtime.GetDefaultPipeline(), 0, false)
+			require.NotNil(t, msgErr)
+			assert.Equal(t, http.StatusGatewayTimeout, msgErr.ErrorCode)
+			assert.False(t, secondWasCalled, "Function after timeout should not be called")
+			assert.WithinDuration(t, started.Add(test.expectedTimeout), deadline, 5*time.Millisecond)
+		})
+	}
+}
+
+func TestExecutePipelineCancelled(t *testing.T) {
+	ctx := appcontext.Context{
+		LoggingClient: lc,
+	}
+
+	parent, cancel := context.WithCancel(context.Background())
+	ctx.SetRequestContext(parent)
+
+	cancelFunc := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		cancel()
+		return true, params[0]
+	}
+	secondWasCalled := false
+	second := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		secondWasCalled = true
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.SetTransforms([]appcontext.AppFunction{cancelFunc, second})
+
+	msgErr := runtime.ExecutePipeline([]byte("data"), "", &ctx, runtime.GetDefaultPipeline(), 0, false)
+	require.NotNil(t, msgErr)
+	assert.Equal(t, http.StatusServiceUnavailable, msgErr.ErrorCode)
+	assert.False(t, secondWasCalled, "Function after cancellation should not be called")
+}
+
+func TestSetPipelineTimeout(t *testing.T) {
+	runtime := GolangRuntime{}
+	runtime.SetTransforms([]appcontext.AppFunction{transforms.NewOutputData().SetOutputData})
+
+	require.NoError(t, runtime.SetPipelineTimeout(DefaultPipelineId, time.Second))
+	assert.Equal(t, time.Second, runtime.GetDefaultPipeline().Timeout)
+
+	assert.Error(t, runtime.SetPipelineTimeout(DefaultPipelineId, -time.Second))
+	assert.Error(t, runtime.SetPipelineTimeout("unknown", time.Second))
+}
+
+func TestExecutePipelineErrorKinds(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel: "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{
+				Enabled:       true,
+				MaxRetryCount: 10},
+		},
+	}
+
+	cause := errors.New("export failed")
+
+	tests := []struct {
+		name         string
+		err          error
+		expectedKind appcontext.ErrorKind
+		expectStored bool
+	}{
+		{"Unclassified", cause, appcontext.ErrorKindUnclassified, true},
+		{"Retryable", appcontext.NewRetryableError(cause), appcontext.ErrorKindRetryable, true},
+		{"Permanent", appcontext.NewPermanentError(cause), appcontext.ErrorKindPermanent, false},
+		{"Filtered", appcontext.NewFilteredError("not interested"), appcontext.ErrorKindFiltered, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := appcontext.Context{
+				Configuration: &config,
+				LoggingClient: lc,
+				CorrelationID: "CorrelationID",
+			}
+
+			failure := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				edgexcontext.SetRetryData(params[0].([]byte))
+				return false, test.err
+			}
+
+			runtime := GolangRuntime{ServiceKey: serviceKey}
+			runtime.Initialize(creatMockStoreClient(), nil)
+			runtime.SetTransforms([]appcontext.AppFunction{failure})
+
+			actual := runtime.ExecutePipeline([]byte("My Payload"), "", &ctx, runtime.GetDefaultPipeline(), 0, false)
+
+			require.NotNil(t, actual)
+			assert.Equal(t, test.expectedKind, actual.Kind)
+			assert.Equal(t, http.StatusUnprocessableEntity, actual.ErrorCode)
+			assert.Equal(t, test.expectStored, len(mockRetrieveObjects(serviceKey)) == 1)
+		})
+	}
+}
+
+func TestExecutePipelinePanic(t *testing.T) {
+	ctx := appcontext.Context{
+		LoggingClient: lc,
+		CorrelationID: "CorrelationID",
+	}
+
+	secondWasCalled := false
+	panicFunc := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		var event *models.Event
+		return true, event.Device
+	}
+	second := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		secondWasCalled = true
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.SetTransforms([]appcontext.AppFunction{panicFunc, second})
+
+	actual := runtime.ExecutePipeline([]byte("My Payload"), "", &ctx, runtime.GetDefaultPipeline(), 0, false)
+
+	require.NotNil(t, actual)
+	assert.Equal(t, http.StatusInternalServerError, actual.ErrorCode)
+	assert.Contains(t, actual.Err.Error(), "recovered from panic")
+	assert.False(t, secondWasCalled, "Function after panic should not be called")
+}
+
+func TestExecutePipelineMetrics(t *testing.T) {
+	ctx := appcontext.Context{
+		LoggingClient: lc,
+		CorrelationID: "CorrelationID",
+	}
+
+	passThrough := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, params[0]
+	}
+	filter := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, nil
+	}
+	notCalled := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	err := runtime.AddFunctionsPipeline(FunctionPipeline{
+		Id:         "metrics-pipeline",
+		Topics:     []string{"metrics"},
+		Transforms: []appcontext.AppFunction{passThrough, filter, notCalled},
+	})
+	require.NoError(t, err)
+
+	actual := runtime.ExecutePipeline([]byte("My Payload"), "", &ctx, runtime.GetPipelineById("metrics-pipeline"), 0, false)
+	require.Nil(t, actual)
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, telemetry.DefaultRegistry.WritePrometheus(buffer))
+	metrics := buffer.String()
+
+	labels := func(position int, function appcontext.AppFunction) string {
+		return fmt.Sprintf(`{pipeline="metrics-pipeline",position="%d",function="%s"}`, position, functionName(function))
+	}
+	assert.Contains(t, metrics, "app_pipeline_function_invocations_total"+labels(0, passThrough)+" 1\n")
+	assert.Contains(t, metrics, "app_pipeline_function_invocations_total"+labels(1, filter)+" 1\n")
+	assert.Contains(t, metrics, "app_pipeline_function_filtered_total"+labels(1, filter)+" 1\n")
+	assert.NotContains(t, metrics, "app_pipeline_function_filtered_total"+labels(0, passThrough))
+	assert.NotContains(t, metrics, labels(2, notCalled))
 }
@@ -19,16 +19,16 @@ package runtime
 import (
 	"context"
 	"fmt"
-	"reflect"
-	"runtime"
 	"sync"
 	"time"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
 	"github.com/student3671/app-functions-sdk-go/appcontext"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/student3671/app-functions-sdk-go/internal/store/contracts"
 	"github.com/student3671/app-functions-sdk-go/internal/store/db/interfaces"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
 )
 
 const (
@@ -36,9 +36,9 @@ const (
 )
 
 type storeForwardInfo struct {
-	runtime      *GolangRuntime
-	storeClient  interfaces.StoreClient
-	pipelineHash string
+	runtime     *GolangRuntime
+	storeClient interfaces.StoreClient
+	appCtx      context.Context
 }
 
 func (sf *storeForwardInfo) startStoreAndForwardRetryLoop(
@@ -50,6 +50,8 @@ func (sf *storeForwardInfo) startStoreAndForwardRetryLoop(
 	config *common.ConfigurationStruct,
 	edgeXClients common.EdgeXClients) {
 
+	sf.appCtx = appCtx
+
 	appWg.Add(1)
 	enabledWg.Add(1)
 
@@ -103,12 +105,21 @@ func (sf *storeForwardInfo) startStoreAndForwardRetryLoop(
 
 func (sf *storeForwardInfo) storeForLaterRetry(payload []byte,
 	edgexcontext *appcontext.Context,
-	pipelinePosition int) {
+	pipeline *FunctionPipeline,
+	branchPath []contracts.BranchPosition,
+	pipelinePosition int) bool {
 
-	item := contracts.NewStoredObject(sf.runtime.ServiceKey, payload, pipelinePosition, sf.pipelineHash)
+	item := contracts.NewStoredObject(sf.runtime.ServiceKey, payload, pipelinePosition, pipeline.Hash)
+	item.PipelineId = pipeline.Id
+	item.BranchPath = branchPath
 	item.CorrelationID = edgexcontext.CorrelationID
 	item.EventID = edgexcontext.EventID
 	item.EventChecksum = edgexcontext.EventChecksum
+	item.ContextValues = edgexcontext.Values()
+
+	if versionChangePolicy(edgexcontext.Configuration, edgexcontext.LoggingClient) == VersionChangePolicyReplay {
+		item.ReceivedPayload, item.ReceivedContentType = edgexcontext.ReceivedMessage()
+	}
 
 	edgexcontext.LoggingClient.Trace("Storing data for later retry",
 		clients.CorrelationHeader, edgexcontext.CorrelationID)
@@ -117,14 +128,17 @@ func (sf *storeForwardInfo) storeForLaterRetry(payload []byte,
 		edgexcontext.LoggingClient.Error(
 			"Failed to store item for later retry", "error", "StoreAndForward not enabled",
 			clients.CorrelationHeader, item.CorrelationID)
-		return
+		return false
 	}
 
 	if _, err := sf.storeClient.Store(item); err != nil {
 		edgexcontext.LoggingClient.Error("Failed to store item for later retry",
 			"error", err,
 			clients.CorrelationHeader, item.CorrelationID)
+		return false
 	}
+
+	return true
 }
 
 func (sf *storeForwardInfo) retryStoredData(serviceKey string,
@@ -138,9 +152,11 @@ func (sf *storeForwardInfo) retryStoredData(serviceKey string,
 	}
 
 	edgeXClients.LoggingClient.Debug(fmt.Sprintf(" %d stored data items found for retrying", len(items)))
+	telemetry.SetStoreForwardQueueDepth(len(items))
 
 	if len(items) > 0 {
 		itemsToRemove, itemsToUpdate := sf.processRetryItems(items, config, edgeXClients)
+		telemetry.SetStoreForwardQueueDepth(len(items) - len(itemsToRemove))
 
 		edgeXClients.LoggingClient.Debug(
 			fmt.Sprintf(" %d stored data items will be removed post retry", len(itemsToRemove)))
@@ -176,52 +192,200 @@ func (sf *storeForwardInfo) processRetryItems(items []contracts.StoredObject,
 	var itemsToUpdate []contracts.StoredObject
 
 	for _, item := range items {
-		if item.Version == sf.calculatePipelineHash() {
-			if !sf.retryExportFunction(item, config, edgeXClients) {
-				item.RetryCount++
-				if config.Writable.StoreAndForward.MaxRetryCount == 0 ||
-					item.RetryCount < config.Writable.StoreAndForward.MaxRetryCount {
-					edgeXClients.LoggingClient.Trace("Export retry failed. Incrementing retry count",
-						"retries",
-						item.RetryCount,
-						clients.CorrelationHeader,
-						item.CorrelationID)
+		pipelineId := item.PipelineId
+		if len(pipelineId) == 0 {
+			// Items stored prior to named pipelines belong to the default pipeline
+			pipelineId = DefaultPipelineId
+		}
+
+		deadLetters := &sf.runtime.deadLetters
+		pipeline := sf.runtime.GetPipelineById(pipelineId)
+		if pipeline == nil {
+			message := fmt.Sprintf("Stored data item's Function Pipeline '%s' no longer exists", pipelineId)
+			edgeXClients.LoggingClient.Error(
+				message+". Removing item from DB",
+				clients.CorrelationHeader,
+				item.CorrelationID)
+			deadLetters.deadLetterStoredItem(item, DeadLetterReasonPipelineChanged, message, config, edgeXClients.LoggingClient)
+			telemetry.RecordRetryOutcome(telemetry.RetryOutcomeRemoved)
+		} else if !pipeline.matchesVersion(item.Version) {
+			message := "Stored data item's Function Pipeline Version doesn't match current Function Pipeline Version"
+			switch versionChangePolicy(config, edgeXClients.LoggingClient) {
+			case VersionChangePolicyKeepAndRetry:
+				edgeXClients.LoggingClient.Debug(
+					message+". Retrying item with current Function Pipeline",
+					clients.CorrelationHeader,
+					item.CorrelationID)
+				item.Version = pipeline.Hash
+				if sf.retryStoredItem(&item, pipeline, config, edgeXClients) {
 					itemsToUpdate = append(itemsToUpdate, item)
 					continue
 				}
 
-				edgeXClients.LoggingClient.Trace(
-					"Max retries exceeded. Removing item from DB", "retries",
-					item.RetryCount,
+			case VersionChangePolicyReplay:
+				edgeXClients.LoggingClient.Debug(
+					message+". Replaying received message with current Function Pipeline",
 					clients.CorrelationHeader,
 					item.CorrelationID)
-				// Note that item will be removed for DB below.
-			} else {
-				edgeXClients.LoggingClient.Trace(
-					"Export retry successful. Removing item from DB",
+				sf.replayStoredItem(item, pipeline, config, edgeXClients)
+
+			default:
+				edgeXClients.LoggingClient.Error(
+					message+". Removing item from DB",
 					clients.CorrelationHeader,
 					item.CorrelationID)
+				deadLetters.deadLetterStoredItem(item, DeadLetterReasonPipelineChanged, message, config, edgeXClients.LoggingClient)
+				telemetry.RecordRetryOutcome(telemetry.RetryOutcomeRemoved)
 			}
-		} else {
-			edgeXClients.LoggingClient.Error(
-				"Stored data item's Function Pipeline Version doesn't match current Function Pipeline Version. Removing item from DB",
-				clients.CorrelationHeader,
-				item.CorrelationID)
+		} else if sf.retryStoredItem(&item, pipeline, config, edgeXClients) {
+			itemsToUpdate = append(itemsToUpdate, item)
+			continue
 		}
 
 		// Item will be remove from store if:
-		//    - successfully retried
+		//    - successfully retried or replayed
 		//    - max retries exceeded
-		//    - version no longer matches current Pipeline
+		//    - retry failed with a permanent error or the data was filtered
+		//    - pipeline no longer exists
+		//    - version no longer matches current Pipeline, unless kept by the VersionChangePolicy
+		//    - branch no longer exists in current Pipeline
 		// Item will not be removed if retry failed and more retries available (hit 'continue' above)
+		// Item is dead lettered, when enabled, unless successfully retried or the data was filtered
 		itemsToRemove = append(itemsToRemove, item)
 	}
 
 	return itemsToRemove, itemsToUpdate
 }
 
-func (sf *storeForwardInfo) retryExportFunction(item contracts.StoredObject, config *common.ConfigurationStruct,
-	edgeXClients common.EdgeXClients) bool {
+// retryStoredItem retries the stored item at its stored position. True is returned if the retry failed and more
+// retries are available, in which case the item's RetryCount has been incremented. Otherwise the item is to be removed.
+func (sf *storeForwardInfo) retryStoredItem(item *contracts.StoredObject, pipeline *FunctionPipeline,
+	config *common.ConfigurationStruct, edgeXClients common.EdgeXClients) bool {
+
+	deadLetters := &sf.runtime.deadLetters
+	edgexContext := sf.createRetryContext(*item, config, edgeXClients)
+	transforms, err := sf.resolveBranch(edgexContext, pipeline, *item)
+	if err != nil {
+		edgeXClients.LoggingClient.Error(
+			"Stored data item's position doesn't exist in current Function Pipeline. Removing item from DB",
+			"error", err,
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		deadLetters.deadLetterStoredItem(*item, DeadLetterReasonPipelineChanged, err.Error(), config, edgeXClients.LoggingClient)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeRemoved)
+	} else if retryErr := sf.retryExportFunction(*item, edgexContext, pipeline, transforms); retryErr != nil &&
+		retryErr.Kind != appcontext.ErrorKindPermanent && retryErr.Kind != appcontext.ErrorKindFiltered {
+		item.RetryCount++
+		if config.Writable.StoreAndForward.MaxRetryCount == 0 ||
+			item.RetryCount < config.Writable.StoreAndForward.MaxRetryCount {
+			edgeXClients.LoggingClient.Trace("Export retry failed. Incrementing retry count",
+				"retries",
+				item.RetryCount,
+				clients.CorrelationHeader,
+				item.CorrelationID)
+			telemetry.RecordRetryOutcome(telemetry.RetryOutcomeFailed)
+			return true
+		}
+
+		edgeXClients.LoggingClient.Trace(
+			"Max retries exceeded. Removing item from DB", "retries",
+			item.RetryCount,
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		deadLetters.deadLetterStoredItem(*item, DeadLetterReasonMaxRetriesExceeded, retryErr.Err.Error(),
+			config, edgeXClients.LoggingClient)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeMaxRetriesExceeded)
+	} else if retryErr != nil {
+		edgeXClients.LoggingClient.Trace(
+			"Export retry stopped with non-retryable error. Removing item from DB",
+			"kind", retryErr.Kind.String(),
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		if retryErr.Kind == appcontext.ErrorKindPermanent {
+			deadLetters.deadLetterStoredItem(*item, DeadLetterReasonRetryFailed, retryErr.Err.Error(),
+				config, edgeXClients.LoggingClient)
+		}
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeRemoved)
+	} else {
+		edgeXClients.LoggingClient.Trace(
+			"Export retry successful. Removing item from DB",
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeSucceeded)
+	}
+
+	return false
+}
+
+// replayStoredItem executes the current pipeline from the start with the message received by the trigger, which is
+// stored along with the item when the VersionChangePolicy is replay. The item is always removed afterwards since the
+// replay stores new data for later retry if it fails again. Items stored without the received message are dead lettered.
+func (sf *storeForwardInfo) replayStoredItem(item contracts.StoredObject, pipeline *FunctionPipeline,
+	config *common.ConfigurationStruct, edgeXClients common.EdgeXClients) {
+
+	if len(item.ReceivedPayload) == 0 {
+		message := "Stored data item's Function Pipeline Version has changed and the received message wasn't stored for replay"
+		edgeXClients.LoggingClient.Error(
+			message+". Removing item from DB",
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		sf.runtime.deadLetters.deadLetterStoredItem(item, DeadLetterReasonPipelineChanged, message, config,
+			edgeXClients.LoggingClient)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeRemoved)
+		return
+	}
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: item.CorrelationID,
+		Payload:       item.ReceivedPayload,
+		ContentType:   item.ReceivedContentType,
+	}
+
+	edgexContext := sf.createRetryContext(item, config, edgeXClients)
+	messageError := sf.replayMessage(edgexContext, envelope, pipeline)
+	switch {
+	case messageError == nil:
+		edgeXClients.LoggingClient.Trace(
+			"Replay successful. Removing item from DB",
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeSucceeded)
+
+	case messageError.StoredForRetry:
+		edgeXClients.LoggingClient.Trace(
+			"Replay failed and has been stored for later retry. Removing previous item from DB",
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeFailed)
+
+	default:
+		edgeXClients.LoggingClient.Trace(
+			"Replay failed. Removing item from DB",
+			"kind", messageError.Kind.String(),
+			clients.CorrelationHeader,
+			item.CorrelationID)
+		sf.runtime.DeadLetterMessage(edgexContext, envelope, messageError)
+		telemetry.RecordRetryOutcome(telemetry.RetryOutcomeRemoved)
+	}
+}
+
+// replayMessage decodes the received message and executes the pipeline from the start, as if it had just been received
+func (sf *storeForwardInfo) replayMessage(edgexContext *appcontext.Context, envelope types.MessageEnvelope,
+	pipeline *FunctionPipeline) *MessageError {
+
+	target, contentType, messageError := sf.runtime.decodeTarget(edgexContext, envelope)
+	if messageError != nil {
+		messageError.PipelineId = pipeline.Id
+		return messageError
+	}
+
+	edgexContext.LoggingClient.Trace("Replaying received message", clients.CorrelationHeader, edgexContext.CorrelationID)
+
+	return sf.runtime.ExecutePipeline(target, contentType, edgexContext, pipeline, 0, false)
+}
+
+func (sf *storeForwardInfo) createRetryContext(item contracts.StoredObject, config *common.ConfigurationStruct,
+	edgeXClients common.EdgeXClients) *appcontext.Context {
 	edgexContext := &appcontext.Context{
 		CorrelationID:         item.CorrelationID,
 		EventChecksum:         item.EventChecksum,
@@ -232,25 +396,97 @@ func (sf *storeForwardInfo) retryExportFunction(item contracts.StoredObject, con
 		ValueDescriptorClient: edgeXClients.ValueDescriptorClient,
 		CommandClient:         edgeXClients.CommandClient,
 		NotificationsClient:   edgeXClients.NotificationsClient,
+		SecretProvider:        sf.runtime.secretProvider,
 	}
 
+	// Retried functions see the values set by the functions executed before the data was stored
+	edgexContext.SetValues(item.ContextValues)
+
+	if sf.appCtx != nil {
+		// Retries are cancelled when the service is shutting down
+		edgexContext.SetRequestContext(sf.appCtx)
+	}
+
+	return edgexContext
+}
+
+// resolveBranch returns the flow of functions the stored item is to be retried with. For items stored from
+// within a fan-out branch, the branches of each fan-out function along the item's BranchPath are those recorded when
+// it fanned out. The fan-out function is invoked with appcontext.BranchResolution to obtain its branches if it hasn't
+// fanned out since the service started, since the stored data isn't the data it received.
+// An error is returned if the item's PipelinePosition doesn't exist within the flow.
+func (sf *storeForwardInfo) resolveBranch(edgexContext *appcontext.Context, pipeline *FunctionPipeline,
+	item contracts.StoredObject) ([]appcontext.AppFunction, error) {
+	transforms := pipeline.Transforms
+
+	for index, position := range item.BranchPath {
+		if position.FunctionIndex < 0 || position.FunctionIndex >= len(transforms) {
+			return nil, fmt.Errorf("fan-out function #%d not found", position.FunctionIndex)
+		}
+
+		branchPath := item.BranchPath[:index]
+		branches, ok := sf.runtime.fanOuts.lookup(pipeline, branchPath, position.FunctionIndex)
+		if !ok {
+			var err error
+			branches, err = resolveFanOut(edgexContext, transforms[position.FunctionIndex], position.FunctionIndex)
+			if err != nil {
+				return nil, err
+			}
+			sf.runtime.fanOuts.record(pipeline, branchPath, position.FunctionIndex, branches)
+		}
+
+		if position.Branch < 0 || position.Branch >= len(branches) {
+			return nil, fmt.Errorf("branch #%d not found for fan-out function #%d", position.Branch, position.FunctionIndex)
+		}
+
+		transforms = branches[position.Branch]
+	}
+
+	if item.PipelinePosition < 0 || item.PipelinePosition >= len(transforms) {
+		return nil, fmt.Errorf("function #%d not found", item.PipelinePosition)
+	}
+
+	return transforms, nil
+}
+
+// resolveFanOut invokes the fan-out function with appcontext.BranchResolution to obtain its branches
+func resolveFanOut(edgexContext *appcontext.Context, function appcontext.AppFunction,
+	functionIndex int) ([][]appcontext.AppFunction, error) {
+
+	_, result, panicErr := invokeFunction(function, edgexContext, appcontext.BranchResolution{})
+	if panicErr != nil {
+		return nil, fmt.Errorf("fan-out function #%d failed: %s", functionIndex, panicErr.Error())
+	}
+
+	fanOut, ok := result.(appcontext.FanOut)
+	if !ok {
+		return nil, fmt.Errorf("function #%d did not return a fan-out for appcontext.BranchResolution", functionIndex)
+	}
+
+	return fanOut.Branches, nil
+}
+
+func (sf *storeForwardInfo) retryExportFunction(item contracts.StoredObject, edgexContext *appcontext.Context,
+	pipeline *FunctionPipeline, transforms []appcontext.AppFunction) *MessageError {
+
 	edgexContext.LoggingClient.Trace("Retrying stored data", clients.CorrelationHeader, edgexContext.CorrelationID)
 
-	return sf.runtime.ExecutePipeline(
+	requestCtx, cancel := sf.runtime.createRequestContext(edgexContext, pipeline)
+	defer cancel()
+
+	requestCtx, span := startPipelineSpan(requestCtx, edgexContext, "retry pipeline "+pipeline.Id, pipeline)
+	edgexContext.SetRequestContext(requestCtx)
+
+	messageError := sf.runtime.executeFunctions(
 		item.Payload,
 		"",
 		edgexContext,
-		sf.runtime.transforms,
+		pipeline,
+		transforms,
+		item.BranchPath,
 		item.PipelinePosition,
-		true) == nil
-}
-
-func (sf *storeForwardInfo) calculatePipelineHash() string {
-	hash := "Pipeline-functions: "
-	for _, item := range sf.runtime.transforms {
-		name := runtime.FuncForPC(reflect.ValueOf(item).Pointer()).Name()
-		hash = hash + " " + name
-	}
+		true)
+	endPipelineSpan(span, messageError)
 
-	return hash
+	return messageError
 }
@@ -22,6 +22,9 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -91,7 +94,7 @@ func TestProcessRetryItems(t *testing.T) {
 			runtime.Initialize(creatMockStoreClient(), nil)
 			runtime.SetTransforms([]appcontext.AppFunction{transformPassthru, transformPassthru, test.TargetTransform})
 
-			version := runtime.storeForward.pipelineHash
+			version := runtime.GetDefaultPipeline().Hash
 			if test.BadVersion {
 				version = "some bad version"
 			}
@@ -145,7 +148,7 @@ func TestDoStoreAndForwardRetry(t *testing.T) {
 			runtime.Initialize(creatMockStoreClient(), nil)
 			runtime.SetTransforms([]appcontext.AppFunction{transformPassthru, test.TargetTransform})
 
-			object := contracts.NewStoredObject(serviceKey, payload, 1, runtime.storeForward.calculatePipelineHash())
+			object := contracts.NewStoredObject(serviceKey, payload, 1, runtime.GetDefaultPipeline().Hash)
 			object.CorrelationID = "CorrelationID"
 			object.EventID = "CorrelationID"
 			object.EventChecksum = "CorrelationID"
@@ -244,3 +247,331 @@ func validateContract(IDRequired bool, o contracts.StoredObject) error {
 
 	return nil
 }
+
+func TestProcessRetryItemsNamedPipeline(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel:        "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{MaxRetryCount: 10},
+		},
+	}
+
+	defaultWasCalled := false
+	namedWasCalled := false
+
+	defaultTransform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		defaultWasCalled = true
+		return false, nil
+	}
+	namedTransform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		namedWasCalled = true
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{defaultTransform})
+	err := runtime.AddFunctionsPipeline(FunctionPipeline{
+		Id:         "named",
+		Topics:     []string{"events"},
+		Transforms: []appcontext.AppFunction{namedTransform},
+	})
+	require.NoError(t, err)
+
+	named := runtime.GetPipelineById("named")
+	require.NotNil(t, named)
+
+	storedObject := contracts.NewStoredObject("dummy", []byte("payload"), 0, named.Hash)
+	storedObject.PipelineId = named.Id
+	missingObject := contracts.NewStoredObject("dummy", []byte("payload"), 0, named.Hash)
+	missingObject.PipelineId = "missing"
+
+	removes, updates := runtime.storeForward.processRetryItems(
+		[]contracts.StoredObject{storedObject, missingObject},
+		&config,
+		common.EdgeXClients{LoggingClient: lc})
+
+	assert.True(t, namedWasCalled, "named pipeline transform not called")
+	assert.False(t, defaultWasCalled, "default pipeline transform should not have been called")
+	assert.Equal(t, 2, len(removes), "Remove count not as expected")
+	assert.Equal(t, 0, len(updates), "Update count not as expected")
+}
+
+func TestProcessRetryItemsFanOutBranch(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel:        "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{MaxRetryCount: 10},
+		},
+	}
+
+	branch0WasCalled := false
+	branch1WasCalled := false
+
+	branch0 := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		branch0WasCalled = true
+		return false, nil
+	}
+	branch1 := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		branch1WasCalled = true
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{
+		appcontext.NewFanOut([]appcontext.AppFunction{branch0}, []appcontext.AppFunction{branch1}),
+	})
+	version := runtime.GetDefaultPipeline().Hash
+
+	storedObject := contracts.NewStoredObject("dummy", []byte("payload"), 0, version)
+	storedObject.BranchPath = []contracts.BranchPosition{{FunctionIndex: 0, Branch: 1}}
+	badBranchObject := contracts.NewStoredObject("dummy", []byte("payload"), 0, version)
+	badBranchObject.BranchPath = []contracts.BranchPosition{{FunctionIndex: 0, Branch: 5}}
+
+	removes, updates := runtime.storeForward.processRetryItems(
+		[]contracts.StoredObject{storedObject, badBranchObject},
+		&config,
+		common.EdgeXClients{LoggingClient: lc})
+
+	assert.True(t, branch1WasCalled, "branch #1 should have been retried")
+	assert.False(t, branch0WasCalled, "branch #0 should not have been retried")
+	assert.Equal(t, 2, len(removes), "Remove count not as expected")
+	assert.Equal(t, 0, len(updates), "Update count not as expected")
+}
+
+func TestProcessRetryItemsRecordedFanOutBranch(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel:        "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{MaxRetryCount: 10},
+		},
+	}
+
+	var branch1Received []interface{}
+	branch0 := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, nil
+	}
+	branch1 := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		branch1Received = append(branch1Received, params[0])
+		return false, nil
+	}
+
+	// A custom fan-out which only accepts the Event it receives, not the stored data or BranchResolution
+	fanOutCalls := 0
+	fanOut := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		fanOutCalls++
+		event := params[0].(models.Event)
+		return true, appcontext.FanOut{
+			Data:     event.Device,
+			Branches: [][]appcontext.AppFunction{{branch0}, {branch1}},
+		}
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{fanOut})
+	pipeline := runtime.GetDefaultPipeline()
+
+	ctx := &appcontext.Context{CorrelationID: "123", Configuration: &config, LoggingClient: lc}
+	messageError := runtime.ExecutePipeline(models.Event{Device: "device1"}, "", ctx, pipeline, 0, false)
+	require.Nil(t, messageError)
+
+	storedObject := contracts.NewStoredObject("dummy", []byte("exported"), 0, pipeline.Hash)
+	storedObject.BranchPath = []contracts.BranchPosition{{FunctionIndex: 0, Branch: 1}}
+
+	removes, updates := runtime.storeForward.processRetryItems(
+		[]contracts.StoredObject{storedObject},
+		&config,
+		common.EdgeXClients{LoggingClient: lc})
+
+	assert.Equal(t, 1, fanOutCalls, "fan-out should not be invoked again to resolve the branch")
+	assert.Equal(t, []interface{}{"device1", []byte("exported")}, branch1Received)
+	assert.Equal(t, 1, len(removes), "Remove count not as expected")
+	assert.Equal(t, 0, len(updates), "Update count not as expected")
+}
+
+func TestProcessRetryItemsVersionChangePolicy(t *testing.T) {
+	receivedPayload := []byte(`{"id":"event-1","device":"device-1"}`)
+	storedPayload := []byte("exported payload")
+
+	var replayed []interface{}
+	var retried []interface{}
+	failReplay := false
+
+	replayTransform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		replayed = append(replayed, params[0])
+		return true, storedPayload
+	}
+	exportTransform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		retried = append(retried, params[0])
+		if failReplay {
+			edgexcontext.SetRetryData(storedPayload)
+			return false, errors.New("export failed")
+		}
+		return false, nil
+	}
+
+	tests := []struct {
+		Name                string
+		Policy              string
+		ReceivedPayload     []byte
+		PipelinePosition    int
+		FailReplay          bool
+		ExpectedReplayed    int
+		ExpectedRetried     int
+		ExpectedRemoveCount int
+		ExpectedUpdateCount int
+		ExpectedStoredCount int
+	}{
+		{"Dead Letter", VersionChangePolicyDeadLetter, receivedPayload, 1, false, 0, 0, 1, 0, 0},
+		{"Default is Dead Letter", "", receivedPayload, 1, false, 0, 0, 1, 0, 0},
+		{"Invalid is Dead Letter", "bogus", receivedPayload, 1, false, 0, 0, 1, 0, 0},
+		{"Keep And Retry", VersionChangePolicyKeepAndRetry, nil, 1, false, 0, 1, 1, 0, 0},
+		{"Keep And Retry Failed", VersionChangePolicyKeepAndRetry, nil, 1, true, 0, 1, 0, 1, 0},
+		{"Keep And Retry Bad Position", VersionChangePolicyKeepAndRetry, nil, 5, false, 0, 0, 1, 0, 0},
+		{"Replay", VersionChangePolicyReplay, receivedPayload, 1, false, 1, 1, 1, 0, 0},
+		{"Replay Failed", VersionChangePolicyReplay, receivedPayload, 1, true, 1, 1, 1, 0, 1},
+		{"Replay Not Stored", VersionChangePolicyReplay, nil, 1, false, 0, 0, 1, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			replayed = nil
+			retried = nil
+			failReplay = test.FailReplay
+
+			config := common.ConfigurationStruct{
+				Writable: common.WritableInfo{
+					LogLevel: "DEBUG",
+					StoreAndForward: common.StoreAndForwardInfo{
+						Enabled:             true,
+						MaxRetryCount:       10,
+						VersionChangePolicy: test.Policy,
+					},
+				},
+			}
+
+			runtime := GolangRuntime{ServiceKey: "dummy"}
+			runtime.Initialize(creatMockStoreClient(), nil)
+			runtime.SetTransforms([]appcontext.AppFunction{replayTransform, exportTransform})
+			version := runtime.GetDefaultPipeline().Hash
+
+			storedObject := contracts.NewStoredObject("dummy", storedPayload, test.PipelinePosition, "previous version")
+			storedObject.ID = uuid.New().String()
+			storedObject.ReceivedPayload = test.ReceivedPayload
+			storedObject.ReceivedContentType = clients.ContentTypeJSON
+
+			removes, updates := runtime.storeForward.processRetryItems(
+				[]contracts.StoredObject{storedObject},
+				&config,
+				common.EdgeXClients{LoggingClient: lc})
+
+			assert.Len(t, replayed, test.ExpectedReplayed)
+			assert.Len(t, retried, test.ExpectedRetried)
+			assert.Len(t, removes, test.ExpectedRemoveCount)
+			require.Len(t, updates, test.ExpectedUpdateCount)
+			require.Len(t, mockObjectStore, test.ExpectedStoredCount)
+
+			if test.ExpectedReplayed > 0 {
+				event, ok := replayed[0].(models.Event)
+				require.True(t, ok, "Expected received message to be decoded")
+				assert.Equal(t, "event-1", event.ID)
+			}
+
+			if test.ExpectedUpdateCount > 0 {
+				assert.Equal(t, version, updates[0].Version, "Kept item should adopt the current version")
+				assert.Equal(t, 1, updates[0].RetryCount)
+			}
+
+			for _, stored := range mockObjectStore {
+				assert.Equal(t, version, stored.Version)
+				assert.Equal(t, 1, stored.PipelinePosition)
+				assert.Equal(t, receivedPayload, stored.ReceivedPayload, "Replayed message should be stored for replay")
+			}
+		})
+	}
+}
+
+func TestProcessRetryItemsLegacyVersion(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel:        "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{MaxRetryCount: 10},
+		},
+	}
+
+	retried := false
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		retried = true
+		return false, nil
+	}
+
+	runtime := GolangRuntime{}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+	storedObject := contracts.NewStoredObject("dummy", []byte("payload"), 0,
+		calculateLegacyPipelineHash([]appcontext.AppFunction{transform}))
+
+	removes, updates := runtime.storeForward.processRetryItems(
+		[]contracts.StoredObject{storedObject},
+		&config,
+		common.EdgeXClients{LoggingClient: lc})
+
+	assert.True(t, retried, "Item stored with the legacy version should be retried")
+	assert.Len(t, removes, 1)
+	assert.Len(t, updates, 0)
+}
+
+func TestStoreAndForwardContextValues(t *testing.T) {
+	config := common.ConfigurationStruct{
+		Writable: common.WritableInfo{
+			LogLevel:        "DEBUG",
+			StoreAndForward: common.StoreAndForwardInfo{Enabled: true, MaxRetryCount: 10},
+		},
+	}
+
+	retrying := false
+	var retriedLevel string
+	setValueTransform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		require.NoError(t, edgexcontext.SetValue("level", "high"))
+		return true, params[0]
+	}
+	exportTransform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		if !retrying {
+			edgexcontext.SetRetryData([]byte("retry data"))
+			return false, errors.New("export failed")
+		}
+
+		retriedLevel, _ = edgexcontext.StringValue("level")
+		return false, nil
+	}
+
+	runtime := GolangRuntime{ServiceKey: serviceKey}
+	runtime.Initialize(creatMockStoreClient(), nil)
+	runtime.SetTransforms([]appcontext.AppFunction{setValueTransform, exportTransform})
+
+	context := &appcontext.Context{Configuration: &config, LoggingClient: lc}
+	messageError := runtime.ProcessMessage(context, types.MessageEnvelope{
+		CorrelationID: "123",
+		Payload:       []byte(`{"device":"thermostat"}`),
+		ContentType:   clients.ContentTypeJSON,
+	})
+	require.NotNil(t, messageError)
+	require.True(t, messageError.StoredForRetry)
+
+	require.Len(t, mockObjectStore, 1)
+	var stored contracts.StoredObject
+	for _, object := range mockObjectStore {
+		stored = object
+	}
+	assert.Equal(t, map[string]string{"level": `"high"`}, stored.ContextValues)
+
+	retrying = true
+	removes, updates := runtime.storeForward.processRetryItems(
+		[]contracts.StoredObject{stored}, &config, common.EdgeXClients{LoggingClient: lc})
+	assert.Len(t, removes, 1)
+	assert.Len(t, updates, 0)
+	assert.Equal(t, "high", retriedLevel, "Retried function should see the stored value")
+}
@@ -0,0 +1,88 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"context"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+// startPipelineSpan starts the span for an execution of the pipeline. The span is in the trace derived from the
+// correlation ID unless the trigger has already started a span or received the trace from the sender.
+func startPipelineSpan(ctx context.Context, edgexcontext *appcontext.Context, name string,
+	pipeline *FunctionPipeline) (context.Context, *tracing.Span) {
+
+	ctx = tracing.ContextWithCorrelationID(ctx, edgexcontext.CorrelationID)
+	ctx, span := tracing.StartSpan(ctx, name, tracing.SpanKindInternal)
+	span.SetAttribute(tracing.AttributePipelineID, pipeline.Id)
+	span.SetAttribute(tracing.AttributeCorrelationID, edgexcontext.CorrelationID)
+
+	return ctx, span
+}
+
+// endPipelineSpan ends the pipeline's span with the outcome of the execution
+func endPipelineSpan(span *tracing.Span, messageError *MessageError) {
+	switch {
+	case messageError == nil:
+		span.SetAttribute(tracing.AttributeOutcome, outcomeSuccess)
+	case messageError.Kind == appcontext.ErrorKindFiltered:
+		span.SetAttribute(tracing.AttributeOutcome, outcomeFiltered)
+	default:
+		span.SetAttribute(tracing.AttributeOutcome, messageError.Kind.String())
+		span.SetError(messageError.Err)
+	}
+
+	span.End()
+}
+
+// startFunctionSpan starts the span for a function's execution, which is passed to the function via the
+// request context so that exports can propagate the trace.
+func startFunctionSpan(ctx context.Context, labels telemetry.FunctionLabels,
+	input interface{}) (context.Context, *tracing.Span) {
+
+	ctx, span := tracing.StartSpan(ctx, labels.Function, tracing.SpanKindInternal)
+	span.SetAttribute(tracing.AttributePipelineID, labels.PipelineId)
+	span.SetAttribute(tracing.AttributeFunctionName, labels.Function)
+	span.SetAttribute(tracing.AttributeFunctionPosition, labels.Position)
+	if size, ok := payloadSize(input); ok {
+		span.SetAttribute(tracing.AttributePayloadSize, size)
+	}
+
+	return ctx, span
+}
+
+// endFunctionSpan ends the function's span with the outcome, which is an error kind when err is set
+func endFunctionSpan(span *tracing.Span, outcome string, err error) {
+	span.SetAttribute(tracing.AttributeOutcome, outcome)
+	span.SetError(err)
+	span.End()
+}
+
+// payloadSize returns the size of the function's input when it is raw data
+func payloadSize(input interface{}) (int, bool) {
+	switch data := input.(type) {
+	case []byte:
+		return len(data), true
+	case string:
+		return len(data), true
+	default:
+		return 0, false
+	}
+}
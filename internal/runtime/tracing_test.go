@@ -0,0 +1,101 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+type recordingExporter struct {
+	lock  sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (exporter *recordingExporter) ExportSpans(_ context.Context, spans []tracing.SpanData) error {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	exporter.spans = append(exporter.spans, spans...)
+	return nil
+}
+
+func (exporter *recordingExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+func TestExecutePipelineTracing(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := tracing.NewTracer(exporter, lc)
+	tracing.SetTracer(tracer)
+	defer tracing.SetTracer(nil)
+
+	correlationID := "4bf92f35-77b3-4da6-a3ce-929d0e0e4736"
+	ctx := appcontext.Context{
+		LoggingClient: lc,
+		CorrelationID: correlationID,
+	}
+
+	var exportParent tracing.SpanContext
+	passThrough := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return true, params[0]
+	}
+	export := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		exportParent = tracing.SpanContextFromContext(edgexcontext.RequestContext())
+		return false, appcontext.NewPermanentError(errors.New("export failed"))
+	}
+
+	runtime := GolangRuntime{}
+	runtime.SetTransforms([]appcontext.AppFunction{passThrough, export})
+
+	actual := runtime.ExecutePipeline([]byte("My Payload"), "", &ctx, runtime.GetDefaultPipeline(), 0, false)
+	require.NotNil(t, actual)
+
+	tracer.Shutdown()
+	require.Equal(t, 3, len(exporter.spans))
+
+	first := exporter.spans[0]
+	second := exporter.spans[1]
+	pipeline := exporter.spans[2]
+
+	traceID := tracing.TraceIDFromCorrelationID(correlationID)
+	assert.Equal(t, "pipeline "+DefaultPipelineId, pipeline.Name)
+	assert.Equal(t, traceID, pipeline.TraceID)
+	assert.Equal(t, tracing.StatusError, pipeline.Status)
+	assert.Equal(t, correlationID, pipeline.Attributes[tracing.AttributeCorrelationID])
+
+	assert.Equal(t, functionName(passThrough), first.Name)
+	assert.Equal(t, pipeline.SpanID, first.ParentSpanID)
+	assert.Equal(t, traceID, first.TraceID)
+	assert.Equal(t, "0", first.Attributes[tracing.AttributeFunctionPosition])
+	assert.Equal(t, len("My Payload"), first.Attributes[tracing.AttributePayloadSize])
+	assert.Equal(t, outcomeSuccess, first.Attributes[tracing.AttributeOutcome])
+	assert.Equal(t, tracing.StatusUnset, first.Status)
+
+	assert.Equal(t, pipeline.SpanID, second.ParentSpanID)
+	assert.Equal(t, appcontext.ErrorKindPermanent.String(), second.Attributes[tracing.AttributeOutcome])
+	assert.Equal(t, tracing.StatusError, second.Status)
+	assert.Equal(t, tracing.SpanContext{TraceID: traceID, SpanID: second.SpanID}, exportParent,
+		"Expected the function's span to be passed to the function")
+}
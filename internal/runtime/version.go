@@ -0,0 +1,164 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+)
+
+const (
+	// VersionChangePolicyDeadLetter removes stored data whose pipeline version has changed, dead lettering it
+	// when dead lettering is enabled. This is the default policy.
+	VersionChangePolicyDeadLetter = "dead-letter"
+	// VersionChangePolicyKeepAndRetry retries stored data whose pipeline version has changed at its stored position
+	// with the current pipeline, as long as that position still exists.
+	VersionChangePolicyKeepAndRetry = "keep-and-retry"
+	// VersionChangePolicyReplay replays the received message from the start of the current pipeline when the version
+	// of the stored data's pipeline has changed. The received message is stored along with the data when this policy is
+	// set. Data stored without the received message is dead lettered.
+	VersionChangePolicyReplay = "replay"
+
+	fingerprintPrefix = "sha256:"
+)
+
+// closureSuffix matches the compiler generated suffix of anonymous functions, i.e. '.func1' or '.func2.1', which
+// changes when closures are added or removed from the enclosing function.
+var closureSuffix = regexp.MustCompile(`(\.func\d+)(\.\d+)*$`)
+
+// FunctionDescriptor identifies a pipeline function and its configuration so that the pipeline's version changes
+// when the configuration changes, i.e. the url of HTTPPost in the configurable pipeline.
+type FunctionDescriptor struct {
+	// Name identifies the function, i.e. the name of the configurable pipeline function
+	Name string
+	// Parameters are the function's configuration parameters
+	Parameters map[string]string
+	// Condition is the condition under which the function is executed, empty if always executed
+	Condition string
+}
+
+// calculatePipelineHash returns the fingerprint of the pipeline, which is its version used by Store and Forward.
+// The pipeline's explicit Version is used when set, otherwise the fingerprint is derived from the function's
+// descriptors or, when not set, the function's names and the parameters recorded by appcontext.DescribeFunction.
+// The configuration of functions defined in code is otherwise unknown, so changes to it don't change the version.
+func calculatePipelineHash(pipeline *FunctionPipeline) string {
+	fingerprint := sha256.New()
+
+	if len(pipeline.Version) > 0 {
+		writeFingerprintLine(fingerprint, "version", pipeline.Version)
+	} else {
+		useDescriptors := len(pipeline.Descriptors) == len(pipeline.Transforms)
+		for index, function := range pipeline.Transforms {
+			descriptor := FunctionDescriptor{
+				Name:       normalizeFunctionName(functionName(function)),
+				Parameters: appcontext.FunctionParameters(function),
+			}
+			if useDescriptors {
+				descriptor = pipeline.Descriptors[index]
+			}
+
+			writeFingerprintLine(fingerprint, "function", descriptor.Name)
+
+			keys := make([]string, 0, len(descriptor.Parameters))
+			for key := range descriptor.Parameters {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				writeFingerprintLine(fingerprint, "parameter", key, descriptor.Parameters[key])
+			}
+
+			if len(descriptor.Condition) > 0 {
+				writeFingerprintLine(fingerprint, "condition", descriptor.Condition)
+			}
+		}
+	}
+
+	return fingerprintPrefix + hex.EncodeToString(fingerprint.Sum(nil))
+}
+
+// calculateLegacyPipelineHash returns the version used prior to fingerprints so that data stored by earlier
+// releases isn't treated as having a changed version after upgrading.
+func calculateLegacyPipelineHash(transforms []appcontext.AppFunction) string {
+	hash := "Pipeline-functions: "
+	for _, item := range transforms {
+		hash = hash + " " + functionName(item)
+	}
+
+	return hash
+}
+
+// writeFingerprintLine writes the quoted values so that values containing separators can't produce the
+// same fingerprint as different values
+func writeFingerprintLine(fingerprint hash.Hash, kind string, values ...string) {
+	quoted := make([]string, len(values))
+	for index, value := range values {
+		quoted[index] = strconv.Quote(value)
+	}
+
+	fmt.Fprintf(fingerprint, "%s %s\n", kind, strings.Join(quoted, "="))
+}
+
+// normalizeFunctionName removes the closure suffix so the name of a function returned by a constructor, i.e.
+// transforms.NewFilter(...).FilterByDeviceName, doesn't depend on the other closures in the constructor
+func normalizeFunctionName(name string) string {
+	return closureSuffix.ReplaceAllString(name, "")
+}
+
+func functionName(function appcontext.AppFunction) string {
+	return appcontext.FunctionName(function)
+}
+
+// matchesVersion returns true if the version of the stored data matches the pipeline's current version
+func (pipeline *FunctionPipeline) matchesVersion(version string) bool {
+	return version == pipeline.Hash || (len(pipeline.legacyHash) > 0 && version == pipeline.legacyHash)
+}
+
+// updateHash recalculates the pipeline's version. Must be called whenever the functions, descriptors or explicit
+// version of the pipeline change.
+func (pipeline *FunctionPipeline) updateHash() {
+	pipeline.Hash = calculatePipelineHash(pipeline)
+	pipeline.legacyHash = calculateLegacyPipelineHash(pipeline.Transforms)
+}
+
+// versionChangePolicy returns the configured VersionChangePolicy, defaulting to dead-letter
+func versionChangePolicy(config *common.ConfigurationStruct, lc logger.LoggingClient) string {
+	policy := strings.ToLower(strings.TrimSpace(config.Writable.StoreAndForward.VersionChangePolicy))
+	switch policy {
+	case VersionChangePolicyDeadLetter, VersionChangePolicyKeepAndRetry, VersionChangePolicyReplay:
+		return policy
+	case "":
+		return VersionChangePolicyDeadLetter
+	default:
+		if lc != nil {
+			lc.Warn(fmt.Sprintf("StoreAndForward VersionChangePolicy '%s' is invalid, defaulting to %s",
+				policy, VersionChangePolicyDeadLetter))
+		}
+		return VersionChangePolicyDeadLetter
+	}
+}
@@ -0,0 +1,163 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
+)
+
+func TestCalculatePipelineHash(t *testing.T) {
+	httpPost := transforms.NewHTTPSender("http://host1", "", false).HTTPPost
+	filter := transforms.NewFilter([]string{"device1"}).FilterByDeviceName
+
+	functions := []appcontext.AppFunction{filter, httpPost}
+	descriptors := []FunctionDescriptor{
+		{Name: "FilterByDeviceName", Parameters: map[string]string{"devicenames": "device1"}},
+		{Name: "HTTPPost", Parameters: map[string]string{"url": "http://host1", "mimetype": ""}},
+	}
+	changedDescriptors := []FunctionDescriptor{
+		{Name: "FilterByDeviceName", Parameters: map[string]string{"devicenames": "device1"}},
+		{Name: "HTTPPost", Parameters: map[string]string{"url": "http://host2", "mimetype": ""}},
+	}
+	ambiguousDescriptors := []FunctionDescriptor{
+		{Name: "FilterByDeviceName", Parameters: map[string]string{"devicenames": "device1"}},
+		{Name: "HTTPPost", Parameters: map[string]string{"url": "http://host1\" parameter \"mimetype", "": ""}},
+	}
+
+	byName := calculatePipelineHash(&FunctionPipeline{Transforms: functions})
+	byDescriptors := calculatePipelineHash(&FunctionPipeline{Transforms: functions, Descriptors: descriptors})
+
+	assert.Contains(t, byName, fingerprintPrefix)
+	assert.Equal(t, byName, calculatePipelineHash(&FunctionPipeline{Transforms: functions}), "Should be deterministic")
+	assert.NotEqual(t, byName, calculatePipelineHash(&FunctionPipeline{Transforms: []appcontext.AppFunction{httpPost, filter}}),
+		"Order of functions should change the fingerprint")
+	assert.NotEqual(t, byName, byDescriptors)
+	assert.Equal(t, byDescriptors,
+		calculatePipelineHash(&FunctionPipeline{Transforms: functions, Descriptors: descriptors}), "Should be deterministic")
+	assert.NotEqual(t, byDescriptors,
+		calculatePipelineHash(&FunctionPipeline{Transforms: functions, Descriptors: changedDescriptors}),
+		"Parameter changes should change the fingerprint")
+	assert.NotEqual(t, byDescriptors,
+		calculatePipelineHash(&FunctionPipeline{Transforms: functions, Descriptors: ambiguousDescriptors}),
+		"Parameter values containing separators should change the fingerprint")
+	assert.Equal(t, byName,
+		calculatePipelineHash(&FunctionPipeline{Transforms: functions, Descriptors: descriptors[:1]}),
+		"Names should be used when the descriptors don't match the functions")
+
+	explicit := calculatePipelineHash(&FunctionPipeline{Transforms: functions, Version: "1.0"})
+	assert.Equal(t, explicit, calculatePipelineHash(&FunctionPipeline{Transforms: []appcontext.AppFunction{httpPost}, Version: "1.0"}),
+		"Explicit version should be used regardless of the functions")
+	assert.NotEqual(t, explicit, calculatePipelineHash(&FunctionPipeline{Transforms: functions, Version: "2.0"}))
+}
+
+func TestCalculatePipelineHashDescribedFunctions(t *testing.T) {
+	newPipeline := func(url string) *FunctionPipeline {
+		sender := transforms.NewHTTPSender(url, "", false)
+		httpPost := appcontext.DescribeFunction(sender.HTTPPost, map[string]string{"url": url})
+		return &FunctionPipeline{Transforms: []appcontext.AppFunction{httpPost}}
+	}
+
+	undescribed := &FunctionPipeline{Transforms: []appcontext.AppFunction{
+		transforms.NewHTTPSender("http://host1", "", false).HTTPPost,
+	}}
+
+	byParameters := calculatePipelineHash(newPipeline("http://host1"))
+	assert.Equal(t, byParameters, calculatePipelineHash(newPipeline("http://host1")), "Should be deterministic")
+	assert.NotEqual(t, byParameters, calculatePipelineHash(newPipeline("http://host2")),
+		"Parameters of functions defined in code should change the fingerprint")
+	assert.NotEqual(t, byParameters, calculatePipelineHash(undescribed))
+}
+
+func TestNormalizeFunctionName(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Function string
+		Expected string
+	}{
+		{"Function", "main.export", "main.export"},
+		{"Method Value", "transforms.(*HTTPSender).HTTPPost-fm", "transforms.(*HTTPSender).HTTPPost-fm"},
+		{"Closure", "main.main.func1", "main.main"},
+		{"Later Closure", "main.main.func12", "main.main"},
+		{"Nested Closure", "main.newExport.func2.1", "main.newExport"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, normalizeFunctionName(test.Function))
+		})
+	}
+}
+
+func TestPipelineVersion(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, nil
+	}
+	descriptors := []FunctionDescriptor{{Name: "Transform", Parameters: map[string]string{"key": "value"}}}
+
+	runtime := GolangRuntime{}
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+	pipeline := runtime.GetDefaultPipeline()
+	byName := pipeline.Hash
+	assert.True(t, pipeline.matchesVersion(byName))
+	assert.True(t, pipeline.matchesVersion(calculateLegacyPipelineHash([]appcontext.AppFunction{transform})),
+		"Version calculated by earlier releases should match")
+	assert.False(t, pipeline.matchesVersion("other"))
+
+	runtime.SetTransformsWithDescriptors([]appcontext.AppFunction{transform}, descriptors)
+	byDescriptors := runtime.GetDefaultPipeline().Hash
+	assert.NotEqual(t, byName, byDescriptors)
+
+	require.NoError(t, runtime.SetPipelineVersion(DefaultPipelineId, "1.0"))
+	explicit := runtime.GetDefaultPipeline().Hash
+	assert.NotEqual(t, byDescriptors, explicit)
+
+	runtime.SetTransforms([]appcontext.AppFunction{transform, transform})
+	assert.Equal(t, explicit, runtime.GetDefaultPipeline().Hash, "Explicit version should be kept when transforms change")
+
+	require.NoError(t, runtime.SetPipelineVersion(DefaultPipelineId, ""))
+	assert.NotEqual(t, explicit, runtime.GetDefaultPipeline().Hash)
+
+	assert.Error(t, runtime.SetPipelineVersion("missing", "1.0"))
+}
+
+func TestVersionChangePolicy(t *testing.T) {
+	tests := []struct {
+		Policy   string
+		Expected string
+	}{
+		{"", VersionChangePolicyDeadLetter},
+		{VersionChangePolicyDeadLetter, VersionChangePolicyDeadLetter},
+		{"Keep-And-Retry", VersionChangePolicyKeepAndRetry},
+		{" replay ", VersionChangePolicyReplay},
+		{"bogus", VersionChangePolicyDeadLetter},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Policy, func(t *testing.T) {
+			config := common.ConfigurationStruct{}
+			config.Writable.StoreAndForward.VersionChangePolicy = test.Policy
+			assert.Equal(t, test.Expected, versionChangePolicy(&config, lc))
+		})
+	}
+}
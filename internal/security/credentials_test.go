@@ -21,7 +21,7 @@ import (
 	"os"
 	"testing"
 
-	"github.com/edgexfoundry/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/stretchr/testify/require"
 )
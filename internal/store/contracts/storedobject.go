@@ -35,9 +35,16 @@ type StoredObject struct {
 	// RetryCount is how many times this has tried to be exported
 	RetryCount int
 
+	// PipelineId identifies the pipeline the data is to be retried with.
+	PipelineId string
+
 	// PipelinePosition is where to pickup in the pipeline
 	PipelinePosition int
 
+	// BranchPath identifies the fan-out branches taken to reach PipelinePosition, outermost first.
+	// It is empty when PipelinePosition is in the main flow of the pipeline.
+	BranchPath []BranchPosition
+
 	// Version is a hash of the functions to know if the pipeline has changed.
 	Version string
 
@@ -49,6 +56,26 @@ type StoredObject struct {
 
 	// EventChecksum is used to identify CBOR encoded data from the core services and mark it as pushed.
 	EventChecksum string
+
+	// ReceivedPayload is the message received by the trigger, retained so the data can be replayed from the start
+	// of the pipeline when the pipeline's version changes. Only set when the VersionChangePolicy is replay.
+	ReceivedPayload []byte
+
+	// ReceivedContentType is the content type of ReceivedPayload
+	ReceivedContentType string
+
+	// ContextValues are the JSON encoded values set on the Context, retained so the retried execution sees the same
+	// values.
+	ContextValues map[string]string
+}
+
+// BranchPosition identifies the branch taken at a fan-out function of the pipeline.
+type BranchPosition struct {
+	// FunctionIndex is the position of the fan-out function within its flow
+	FunctionIndex int
+
+	// Branch is the index of the branch taken
+	Branch int
 }
 
 // NewStoredObject creates a new instance of StoredObject and is the preferred way to create one.
@@ -41,9 +41,15 @@ type StoredObject struct {
 	// RetryCount is how many times this has tried to be exported
 	RetryCount int `bson:"retryCount"`
 
+	// PipelineId identifies the pipeline the data is to be retried with.
+	PipelineId string `bson:"pipelineId"`
+
 	// PipelinePosition is where to pickup in the pipeline
 	PipelinePosition int `bson:"pipelinePosition"`
 
+	// BranchPath identifies the fan-out branches taken to reach PipelinePosition, outermost first.
+	BranchPath []BranchPosition `bson:"branchPath,omitempty"`
+
 	// Version is a hash of the functions to know if the pipeline has changed.
 	Version string `bson:"version"`
 
@@ -55,6 +61,21 @@ type StoredObject struct {
 
 	// EventChecksum is used to identify CBOR encoded data from the core services and mark it as pushed.
 	EventChecksum string `bson:"eventChecksum"`
+
+	// ReceivedPayload is the message received by the trigger, retained for replaying from the start of the pipeline.
+	ReceivedPayload []byte `bson:"receivedPayload,omitempty"`
+
+	// ReceivedContentType is the content type of ReceivedPayload
+	ReceivedContentType string `bson:"receivedContentType,omitempty"`
+
+	// ContextValues are the JSON encoded values set on the Context
+	ContextValues map[string]string `bson:"contextValues,omitempty"`
+}
+
+// BranchPosition identifies the branch taken at a fan-out function of the pipeline.
+type BranchPosition struct {
+	FunctionIndex int `bson:"functionIndex"`
+	Branch        int `bson:"branch"`
 }
 
 // FromContract builds a model object out of the supplied contract.
@@ -68,11 +89,19 @@ func (o *StoredObject) FromContract(c contracts.StoredObject) error {
 	o.AppServiceKey = c.AppServiceKey
 	o.Payload = c.Payload
 	o.RetryCount = c.RetryCount
+	o.PipelineId = c.PipelineId
 	o.PipelinePosition = c.PipelinePosition
+	o.BranchPath = nil
+	for _, position := range c.BranchPath {
+		o.BranchPath = append(o.BranchPath, BranchPosition{FunctionIndex: position.FunctionIndex, Branch: position.Branch})
+	}
 	o.Version = c.Version
 	o.CorrelationID = c.CorrelationID
 	o.EventID = c.EventID
 	o.EventChecksum = c.EventChecksum
+	o.ReceivedPayload = c.ReceivedPayload
+	o.ReceivedContentType = c.ReceivedContentType
+	o.ContextValues = c.ContextValues
 
 	return nil
 }
@@ -87,9 +116,17 @@ func (o StoredObject) ToContract() contracts.StoredObject {
 
 	contract.ID = ToContractId(o.ObjectID, o.UUID)
 	contract.RetryCount = o.RetryCount
+	contract.PipelineId = o.PipelineId
+	for _, position := range o.BranchPath {
+		contract.BranchPath = append(contract.BranchPath,
+			contracts.BranchPosition{FunctionIndex: position.FunctionIndex, Branch: position.Branch})
+	}
 	contract.CorrelationID = o.CorrelationID
 	contract.EventID = o.EventID
 	contract.EventChecksum = o.EventChecksum
+	contract.ReceivedPayload = o.ReceivedPayload
+	contract.ReceivedContentType = o.ReceivedContentType
+	contract.ContextValues = o.ContextValues
 
 	return contract
 }
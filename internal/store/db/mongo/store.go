@@ -74,15 +74,20 @@ func (c Client) Store(o contracts.StoredObject) (string, error) {
 	}
 
 	doc = bson.M{
-		"uuid":             uuid,
-		"appServiceKey":    o.AppServiceKey,
-		"payload":          o.Payload,
-		"retryCount":       o.RetryCount,
-		"pipelinePosition": o.PipelinePosition,
-		"version":          o.Version,
-		"correlationID":    o.CorrelationID,
-		"eventID":          o.EventID,
-		"eventChecksum":    o.EventChecksum,
+		"uuid":                uuid,
+		"appServiceKey":       o.AppServiceKey,
+		"payload":             o.Payload,
+		"retryCount":          o.RetryCount,
+		"pipelineId":          o.PipelineId,
+		"pipelinePosition":    o.PipelinePosition,
+		"branchPath":          toBranchPath(o.BranchPath),
+		"version":             o.Version,
+		"correlationID":       o.CorrelationID,
+		"eventID":             o.EventID,
+		"eventChecksum":       o.EventChecksum,
+		"receivedPayload":     o.ReceivedPayload,
+		"receivedContentType": o.ReceivedContentType,
+		"contextValues":       o.ContextValues,
 	}
 
 	_, err = c.Client.Collection(mongoCollection).InsertOne(ctx, doc)
@@ -93,6 +98,15 @@ func (c Client) Store(o contracts.StoredObject) (string, error) {
 	return uuid, nil
 }
 
+// toBranchPath converts the contract's branch path to the model's, which has the bson tags
+func toBranchPath(branchPath []contracts.BranchPosition) []models.BranchPosition {
+	var path []models.BranchPosition
+	for _, position := range branchPath {
+		path = append(path, models.BranchPosition{FunctionIndex: position.FunctionIndex, Branch: position.Branch})
+	}
+	return path
+}
+
 // RetrieveFromStore gets an object from the data store.
 func (c Client) RetrieveFromStore(appServiceKey string) (objects []contracts.StoredObject, err error) {
 	// do not satisfy requests for a blank ASK, this will return ALL objects with ANY ASK
@@ -151,15 +165,20 @@ func (c Client) Update(o contracts.StoredObject) error {
 	}
 
 	update := bson.M{"$set": bson.M{
-		"uuid":             o.ID,
-		"appServiceKey":    o.AppServiceKey,
-		"payload":          o.Payload,
-		"retryCount":       o.RetryCount,
-		"pipelinePosition": o.PipelinePosition,
-		"version":          o.Version,
-		"correlationID":    o.CorrelationID,
-		"eventID":          o.EventID,
-		"eventChecksum":    o.EventChecksum,
+		"uuid":                o.ID,
+		"appServiceKey":       o.AppServiceKey,
+		"payload":             o.Payload,
+		"retryCount":          o.RetryCount,
+		"pipelineId":          o.PipelineId,
+		"pipelinePosition":    o.PipelinePosition,
+		"branchPath":          toBranchPath(o.BranchPath),
+		"version":             o.Version,
+		"correlationID":       o.CorrelationID,
+		"eventID":             o.EventID,
+		"eventChecksum":       o.EventChecksum,
+		"receivedPayload":     o.ReceivedPayload,
+		"receivedContentType": o.ReceivedContentType,
+		"contextValues":       o.ContextValues,
 	}}
 
 	_, err = c.Client.Collection(mongoCollection).UpdateOne(ctx, filter, update)
@@ -348,6 +348,38 @@ func TestClient_Update(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestClient_StoreAndUpdatePipeline(t *testing.T) {
+	TestContractPipeline := TestContractBase
+	TestContractPipeline.AppServiceKey = uuid.New().String()
+	TestContractPipeline.PipelineId = "readings"
+	TestContractPipeline.BranchPath = []contracts.BranchPosition{{FunctionIndex: 1, Branch: 2}}
+
+	client, _ := NewClient(TestValidNoAuthConfig)
+
+	var err error
+	TestContractPipeline.ID, err = client.Store(TestContractPipeline)
+	require.NoError(t, err)
+
+	actual, err := client.RetrieveFromStore(TestContractPipeline.AppServiceKey)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "readings", actual[0].PipelineId)
+	require.Equal(t, TestContractPipeline.BranchPath, actual[0].BranchPath)
+
+	TestContractPipeline.PipelineId = "alerts"
+	TestContractPipeline.BranchPath = []contracts.BranchPosition{{FunctionIndex: 0, Branch: 1}, {FunctionIndex: 3, Branch: 0}}
+	require.NoError(t, client.Update(TestContractPipeline))
+
+	actual, err = client.RetrieveFromStore(TestContractPipeline.AppServiceKey)
+	require.NoError(t, err)
+	require.Len(t, actual, 1)
+	require.Equal(t, "alerts", actual[0].PipelineId)
+	require.Equal(t, TestContractPipeline.BranchPath, actual[0].BranchPath)
+
+	err = client.Disconnect()
+	require.NoError(t, err)
+}
+
 func TestClient_RemoveFromStore(t *testing.T) {
 	TestContractValid := TestContractBase
 	TestContractValid.AppServiceKey = uuid.New().String()
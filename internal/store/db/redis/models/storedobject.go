@@ -35,9 +35,15 @@ type StoredObject struct {
 	// RetryCount is how many times this has tried to be exported
 	RetryCount int `json:"retryCount"`
 
+	// PipelineId identifies the pipeline the data is to be retried with.
+	PipelineId string `json:"pipelineId"`
+
 	// PipelinePosition is where to pickup in the pipeline
 	PipelinePosition int `json:"pipelinePosition"`
 
+	// BranchPath identifies the fan-out branches taken to reach PipelinePosition, outermost first.
+	BranchPath []BranchPosition `json:"branchPath"`
+
 	// Version is a hash of the functions to know if the pipeline has changed.
 	Version string `json:"version"`
 
@@ -49,20 +55,40 @@ type StoredObject struct {
 
 	// EventChecksum is used to identify CBOR encoded data from the core services and mark it as pushed.
 	EventChecksum string `json:"eventChecksum"`
+
+	// ReceivedPayload is the message received by the trigger, retained for replaying from the start of the pipeline.
+	ReceivedPayload []byte `json:"receivedPayload"`
+
+	// ReceivedContentType is the content type of ReceivedPayload
+	ReceivedContentType string `json:"receivedContentType"`
+
+	// ContextValues are the JSON encoded values set on the Context
+	ContextValues map[string]string `json:"contextValues"`
+}
+
+// BranchPosition identifies the branch taken at a fan-out function of the pipeline.
+type BranchPosition struct {
+	FunctionIndex int `json:"functionIndex"`
+	Branch        int `json:"branch"`
 }
 
 // ToContract builds a contract out of the supplied model.
 func (o StoredObject) ToContract() contracts.StoredObject {
 	return contracts.StoredObject{
-		ID:               o.ID,
-		AppServiceKey:    o.AppServiceKey,
-		Payload:          o.Payload,
-		RetryCount:       o.RetryCount,
-		PipelinePosition: o.PipelinePosition,
-		Version:          o.Version,
-		CorrelationID:    o.CorrelationID,
-		EventID:          o.EventID,
-		EventChecksum:    o.EventChecksum,
+		ID:                  o.ID,
+		AppServiceKey:       o.AppServiceKey,
+		Payload:             o.Payload,
+		RetryCount:          o.RetryCount,
+		PipelineId:          o.PipelineId,
+		PipelinePosition:    o.PipelinePosition,
+		BranchPath:          toContractBranchPath(o.BranchPath),
+		Version:             o.Version,
+		CorrelationID:       o.CorrelationID,
+		EventID:             o.EventID,
+		EventChecksum:       o.EventChecksum,
+		ReceivedPayload:     o.ReceivedPayload,
+		ReceivedContentType: o.ReceivedContentType,
+		ContextValues:       o.ContextValues,
 	}
 }
 
@@ -72,29 +98,68 @@ func (o *StoredObject) FromContract(c contracts.StoredObject) {
 	o.AppServiceKey = c.AppServiceKey
 	o.Payload = c.Payload
 	o.RetryCount = c.RetryCount
+	o.PipelineId = c.PipelineId
 	o.PipelinePosition = c.PipelinePosition
+	o.BranchPath = fromContractBranchPath(c.BranchPath)
 	o.Version = c.Version
 	o.CorrelationID = c.CorrelationID
 	o.EventID = c.EventID
 	o.EventChecksum = c.EventChecksum
+	o.ReceivedPayload = c.ReceivedPayload
+	o.ReceivedContentType = c.ReceivedContentType
+	o.ContextValues = c.ContextValues
+}
+
+func toContractBranchPath(path []BranchPosition) []contracts.BranchPosition {
+	if len(path) == 0 {
+		return nil
+	}
+
+	contractPath := make([]contracts.BranchPosition, len(path))
+	for index, position := range path {
+		contractPath[index] = contracts.BranchPosition{FunctionIndex: position.FunctionIndex, Branch: position.Branch}
+	}
+
+	return contractPath
+}
+
+func fromContractBranchPath(path []contracts.BranchPosition) []BranchPosition {
+	if len(path) == 0 {
+		return nil
+	}
+
+	modelPath := make([]BranchPosition, len(path))
+	for index, position := range path {
+		modelPath[index] = BranchPosition{FunctionIndex: position.FunctionIndex, Branch: position.Branch}
+	}
+
+	return modelPath
 }
 
 // MarshalJSON returns the object as a JSON encoded byte array.
 func (o StoredObject) MarshalJSON() ([]byte, error) {
 	test := struct {
-		ID               *string `json:"id,omitempty"`
-		AppServiceKey    *string `json:"appServiceKey,omitempty"`
-		Payload          []byte  `json:"payload,omitempty"`
-		RetryCount       int     `json:"retryCount,omitempty"`
-		PipelinePosition int     `json:"pipelinePosition,omitempty"`
-		Version          *string `json:"version,omitempty"`
-		CorrelationID    *string `json:"correlationID,omitempty"`
-		EventID          *string `json:"eventID,omitempty"`
-		EventChecksum    *string `json:"eventChecksum,omitempty"`
+		ID                  *string           `json:"id,omitempty"`
+		AppServiceKey       *string           `json:"appServiceKey,omitempty"`
+		Payload             []byte            `json:"payload,omitempty"`
+		RetryCount          int               `json:"retryCount,omitempty"`
+		PipelineId          *string           `json:"pipelineId,omitempty"`
+		PipelinePosition    int               `json:"pipelinePosition,omitempty"`
+		BranchPath          []BranchPosition  `json:"branchPath,omitempty"`
+		Version             *string           `json:"version,omitempty"`
+		CorrelationID       *string           `json:"correlationID,omitempty"`
+		EventID             *string           `json:"eventID,omitempty"`
+		EventChecksum       *string           `json:"eventChecksum,omitempty"`
+		ReceivedPayload     []byte            `json:"receivedPayload,omitempty"`
+		ReceivedContentType *string           `json:"receivedContentType,omitempty"`
+		ContextValues       map[string]string `json:"contextValues,omitempty"`
 	}{
 		Payload:          o.Payload,
 		RetryCount:       o.RetryCount,
 		PipelinePosition: o.PipelinePosition,
+		BranchPath:       o.BranchPath,
+		ReceivedPayload:  o.ReceivedPayload,
+		ContextValues:    o.ContextValues,
 	}
 
 	// Empty strings are null
@@ -104,6 +169,9 @@ func (o StoredObject) MarshalJSON() ([]byte, error) {
 	if o.AppServiceKey != "" {
 		test.AppServiceKey = &o.AppServiceKey
 	}
+	if o.PipelineId != "" {
+		test.PipelineId = &o.PipelineId
+	}
 	if o.Version != "" {
 		test.Version = &o.Version
 	}
@@ -116,6 +184,9 @@ func (o StoredObject) MarshalJSON() ([]byte, error) {
 	if o.EventChecksum != "" {
 		test.EventChecksum = &o.EventChecksum
 	}
+	if o.ReceivedContentType != "" {
+		test.ReceivedContentType = &o.ReceivedContentType
+	}
 
 	return json.Marshal(test)
 }
@@ -123,15 +194,20 @@ func (o StoredObject) MarshalJSON() ([]byte, error) {
 // UnmarshalJSON returns an object from JSON.
 func (o *StoredObject) UnmarshalJSON(data []byte) error {
 	alias := new(struct {
-		ID               *string `json:"id"`
-		AppServiceKey    *string `json:"appServiceKey"`
-		Payload          []byte  `json:"payload"`
-		RetryCount       int     `json:"retryCount"`
-		PipelinePosition int     `json:"pipelinePosition"`
-		Version          *string `json:"version"`
-		CorrelationID    *string `json:"correlationID"`
-		EventID          *string `json:"eventID"`
-		EventChecksum    *string `json:"eventChecksum"`
+		ID                  *string           `json:"id"`
+		AppServiceKey       *string           `json:"appServiceKey"`
+		Payload             []byte            `json:"payload"`
+		RetryCount          int               `json:"retryCount"`
+		PipelineId          *string           `json:"pipelineId"`
+		PipelinePosition    int               `json:"pipelinePosition"`
+		BranchPath          []BranchPosition  `json:"branchPath"`
+		Version             *string           `json:"version"`
+		CorrelationID       *string           `json:"correlationID"`
+		EventID             *string           `json:"eventID"`
+		EventChecksum       *string           `json:"eventChecksum"`
+		ReceivedPayload     []byte            `json:"receivedPayload"`
+		ReceivedContentType *string           `json:"receivedContentType"`
+		ContextValues       map[string]string `json:"contextValues"`
 	})
 
 	// Error with unmarshaling
@@ -146,6 +222,9 @@ func (o *StoredObject) UnmarshalJSON(data []byte) error {
 	if alias.AppServiceKey != nil {
 		o.AppServiceKey = *alias.AppServiceKey
 	}
+	if alias.PipelineId != nil {
+		o.PipelineId = *alias.PipelineId
+	}
 	if alias.Version != nil {
 		o.Version = *alias.Version
 	}
@@ -158,10 +237,16 @@ func (o *StoredObject) UnmarshalJSON(data []byte) error {
 	if alias.EventChecksum != nil {
 		o.EventChecksum = *alias.EventChecksum
 	}
+	if alias.ReceivedContentType != nil {
+		o.ReceivedContentType = *alias.ReceivedContentType
+	}
 
 	o.Payload = alias.Payload
 	o.RetryCount = alias.RetryCount
 	o.PipelinePosition = alias.PipelinePosition
+	o.BranchPath = alias.BranchPath
+	o.ReceivedPayload = alias.ReceivedPayload
+	o.ContextValues = alias.ContextValues
 
 	return nil
 }
@@ -24,6 +24,7 @@ import (
 
 var TestUUIDValid = "fb49a277-9edf-4489-a89c-235b365107f7"
 var TestPayload = []byte("brandon wrote this")
+var TestContextValues = map[string]string{"level": `"high"`}
 
 const (
 	TestAppServiceKey    = "apps"
@@ -45,6 +46,7 @@ var TestContractValid = contracts.StoredObject{
 	CorrelationID:    TestCorrelationID,
 	EventID:          TestEventID,
 	EventChecksum:    TestEventChecksum,
+	ContextValues:    TestContextValues,
 }
 
 var TestModelValid = StoredObject{
@@ -57,6 +59,7 @@ var TestModelValid = StoredObject{
 	CorrelationID:    TestCorrelationID,
 	EventID:          TestEventID,
 	EventChecksum:    TestEventChecksum,
+	ContextValues:    TestContextValues,
 }
 
 var TestModelEmpty = StoredObject{}
@@ -121,7 +124,7 @@ func TestStoredObject_MarshalJSON(t *testing.T) {
 			"Successful marshalling",
 			TestModelValid,
 			false,
-			`{"id":"fb49a277-9edf-4489-a89c-235b365107f7","appServiceKey":"apps","payload":"YnJhbmRvbiB3cm90ZSB0aGlz","retryCount":2,"pipelinePosition":1337,"version":"your","correlationID":"test","eventID":"probably","eventChecksum":"failed :("}`,
+			`{"id":"fb49a277-9edf-4489-a89c-235b365107f7","appServiceKey":"apps","payload":"YnJhbmRvbiB3cm90ZSB0aGlz","retryCount":2,"pipelinePosition":1337,"version":"your","correlationID":"test","eventID":"probably","eventChecksum":"failed :(","contextValues":{"level":"\"high\""}}`,
 		},
 		{
 			"Successful, empty",
@@ -158,7 +161,7 @@ func TestStoredObject_UnmarshalJSON(t *testing.T) {
 		{
 			"Valid",
 			TestModelValid,
-			args{[]byte(`{"id":"fb49a277-9edf-4489-a89c-235b365107f7","appServiceKey":"apps","payload":[98,114,97,110,100,111,110,32,119,114,111,116,101,32,116,104,105,115],"retryCount":2,"pipelinePosition":1337,"version":"your","correlationID":"test","eventID":"probably","eventChecksum":"failed :("}`)},
+			args{[]byte(`{"id":"fb49a277-9edf-4489-a89c-235b365107f7","appServiceKey":"apps","payload":[98,114,97,110,100,111,110,32,119,114,111,116,101,32,116,104,105,115],"retryCount":2,"pipelinePosition":1337,"version":"your","correlationID":"test","eventID":"probably","eventChecksum":"failed :(","contextValues":{"level":"\"high\""}}`)},
 			false,
 		},
 		{
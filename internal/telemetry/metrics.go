@@ -0,0 +1,271 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package telemetry
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PrometheusContentType is the content type of the Prometheus text exposition format
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the buckets used for duration histograms
+var DefaultDurationBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	metricTypeCounter   = "counter"
+	metricTypeGauge     = "gauge"
+	metricTypeHistogram = "histogram"
+
+	labelValueSeparator = "\xff"
+)
+
+// Registry holds metric families and writes them in the Prometheus text exposition format
+type Registry struct {
+	mutex    sync.Mutex
+	families []*metricFamily
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+type metricFamily struct {
+	name       string
+	help       string
+	metricType string
+	labelNames []string
+	buckets    []float64
+	mutex      sync.Mutex
+	series     map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	// Only used for histograms
+	bucketCounts []uint64
+	count        uint64
+}
+
+// CounterVec is a counter partitioned by its labels
+type CounterVec struct {
+	family *metricFamily
+}
+
+// GaugeVec is a gauge partitioned by its labels
+type GaugeVec struct {
+	family *metricFamily
+}
+
+// HistogramVec is a histogram partitioned by its labels
+type HistogramVec struct {
+	family *metricFamily
+}
+
+// NewCounterVec adds a counter with the specified labels to the registry
+func (registry *Registry) NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	return &CounterVec{family: registry.add(name, help, metricTypeCounter, labelNames, nil)}
+}
+
+// NewGaugeVec adds a gauge with the specified labels to the registry
+func (registry *Registry) NewGaugeVec(name string, help string, labelNames ...string) *GaugeVec {
+	return &GaugeVec{family: registry.add(name, help, metricTypeGauge, labelNames, nil)}
+}
+
+// NewHistogramVec adds a histogram with the specified bucket upper bounds and labels to the registry
+func (registry *Registry) NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	return &HistogramVec{family: registry.add(name, help, metricTypeHistogram, labelNames, sorted)}
+}
+
+func (registry *Registry) add(name string, help string, metricType string, labelNames []string, buckets []float64) *metricFamily {
+	family := &metricFamily{
+		name:       name,
+		help:       help,
+		metricType: metricType,
+		labelNames: labelNames,
+		buckets:    buckets,
+		series:     make(map[string]*series),
+	}
+
+	registry.mutex.Lock()
+	registry.families = append(registry.families, family)
+	registry.mutex.Unlock()
+
+	return family
+}
+
+// Inc increments the counter for the label values by one
+func (counter *CounterVec) Inc(labelValues ...string) {
+	counter.Add(1, labelValues...)
+}
+
+// Add increments the counter for the label values by the value, which must not be negative
+func (counter *CounterVec) Add(value float64, labelValues ...string) {
+	if value < 0 {
+		return
+	}
+
+	counter.family.update(labelValues, func(s *series) { s.value += value })
+}
+
+// Set sets the gauge for the label values to the value
+func (gauge *GaugeVec) Set(value float64, labelValues ...string) {
+	gauge.family.update(labelValues, func(s *series) { s.value = value })
+}
+
+// Observe adds the value to the histogram for the label values
+func (histogram *HistogramVec) Observe(value float64, labelValues ...string) {
+	buckets := histogram.family.buckets
+	histogram.family.update(labelValues, func(s *series) {
+		if s.bucketCounts == nil {
+			s.bucketCounts = make([]uint64, len(buckets))
+		}
+
+		for index, upperBound := range buckets {
+			if value <= upperBound {
+				s.bucketCounts[index]++
+			}
+		}
+
+		s.count++
+		s.value += value
+	})
+}
+
+func (family *metricFamily) update(labelValues []string, updateFunc func(s *series)) {
+	// Pad or truncate so that mismatched label values don't produce an invalid exposition
+	values := make([]string, len(family.labelNames))
+	copy(values, labelValues)
+	key := strings.Join(values, labelValueSeparator)
+
+	family.mutex.Lock()
+	defer family.mutex.Unlock()
+
+	s, exists := family.series[key]
+	if !exists {
+		s = &series{labelValues: values}
+		family.series[key] = s
+	}
+
+	updateFunc(s)
+}
+
+// WritePrometheus writes all the metrics in the Prometheus text exposition format
+func (registry *Registry) WritePrometheus(writer io.Writer) error {
+	registry.mutex.Lock()
+	families := make([]*metricFamily, len(registry.families))
+	copy(families, registry.families)
+	registry.mutex.Unlock()
+
+	buffered := bufio.NewWriter(writer)
+	for _, family := range families {
+		family.write(buffered)
+	}
+
+	return buffered.Flush()
+}
+
+func (family *metricFamily) write(writer *bufio.Writer) {
+	family.mutex.Lock()
+	defer family.mutex.Unlock()
+
+	if len(family.series) == 0 {
+		return
+	}
+
+	fmt.Fprintf(writer, "# HELP %s %s\n", family.name, escapeHelp(family.help))
+	fmt.Fprintf(writer, "# TYPE %s %s\n", family.name, family.metricType)
+
+	keys := make([]string, 0, len(family.series))
+	for key := range family.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := family.series[key]
+		if family.metricType != metricTypeHistogram {
+			writeSample(writer, family.name, family.labelNames, s.labelValues, "", "", s.value)
+			continue
+		}
+
+		for index, upperBound := range family.buckets {
+			writeSample(writer, family.name+"_bucket", family.labelNames, s.labelValues,
+				"le", formatFloat(upperBound), float64(s.bucketCounts[index]))
+		}
+		writeSample(writer, family.name+"_bucket", family.labelNames, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(writer, family.name+"_sum", family.labelNames, s.labelValues, "", "", s.value)
+		writeSample(writer, family.name+"_count", family.labelNames, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+func writeSample(writer *bufio.Writer, name string, labelNames []string, labelValues []string,
+	extraLabel string, extraValue string, value float64) {
+
+	writer.WriteString(name)
+
+	pairs := make([]string, 0, len(labelNames)+1)
+	for index, labelName := range labelNames {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labelName, escapeLabelValue(labelValues[index])))
+	}
+	if extraLabel != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extraLabel, extraValue))
+	}
+
+	if len(pairs) > 0 {
+		writer.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	writer.WriteString(" " + formatFloat(value) + "\n")
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
@@ -0,0 +1,97 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package telemetry
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "Test counter.", "name")
+	gauge := registry.NewGaugeVec("test_depth", "Test gauge.")
+	histogram := registry.NewHistogramVec("test_seconds", "Test histogram.", []float64{1, 0.5}, "name")
+	registry.NewCounterVec("test_unused_total", "Never incremented.", "name")
+
+	counter.Inc("b")
+	counter.Inc("a")
+	counter.Add(2, "a")
+	counter.Add(-1, "a")
+	gauge.Set(5)
+	histogram.Observe(0.25, "a")
+	histogram.Observe(0.75, "a")
+	histogram.Observe(2, "a")
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, registry.WritePrometheus(buffer))
+
+	expected := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{name="a"} 3
+test_total{name="b"} 1
+# HELP test_depth Test gauge.
+# TYPE test_depth gauge
+test_depth 5
+# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{name="a",le="0.5"} 1
+test_seconds_bucket{name="a",le="1"} 2
+test_seconds_bucket{name="a",le="+Inf"} 3
+test_seconds_sum{name="a"} 3
+test_seconds_count{name="a"} 3
+`
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestWritePrometheusEscapesLabelValues(t *testing.T) {
+	registry := NewRegistry()
+	counter := registry.NewCounterVec("test_total", "Help with \\ and\nnewline.", "first", "second")
+
+	counter.Inc("quote\"back\\slash\nnewline")
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, registry.WritePrometheus(buffer))
+
+	expected := `# HELP test_total Help with \\ and\nnewline.
+# TYPE test_total counter
+test_total{first="quote\"back\\slash\nnewline",second=""} 1
+`
+	assert.Equal(t, expected, buffer.String())
+}
+
+func TestRecordFunctionInvocation(t *testing.T) {
+	labels := FunctionLabels{PipelineId: "test-pipeline", Position: "0", Function: "test.function"}
+
+	RecordFunctionInvocation(labels, 20*time.Millisecond)
+	RecordFunctionError(labels, "retryable")
+	RecordFunctionFiltered(labels)
+
+	buffer := &bytes.Buffer{}
+	require.NoError(t, DefaultRegistry.WritePrometheus(buffer))
+
+	seriesLabels := `pipeline="test-pipeline",position="0",function="test.function"`
+	assert.Contains(t, buffer.String(), "app_pipeline_function_invocations_total{"+seriesLabels+"} 1\n")
+	assert.Contains(t, buffer.String(), "app_pipeline_function_errors_total{"+seriesLabels+`,kind="retryable"} 1`+"\n")
+	assert.Contains(t, buffer.String(), "app_pipeline_function_filtered_total{"+seriesLabels+"} 1\n")
+	assert.Contains(t, buffer.String(), "app_pipeline_function_duration_seconds_bucket{"+seriesLabels+`,le="0.025"} 1`+"\n")
+	assert.Contains(t, buffer.String(), "app_pipeline_function_duration_seconds_bucket{"+seriesLabels+`,le="0.01"} 0`+"\n")
+}
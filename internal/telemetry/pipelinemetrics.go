@@ -0,0 +1,135 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package telemetry
+
+import (
+	"time"
+)
+
+// Retry outcomes recorded for the items stored for later retry
+const (
+	RetryOutcomeSucceeded          = "succeeded"
+	RetryOutcomeFailed             = "failed"
+	RetryOutcomeMaxRetriesExceeded = "max-retries-exceeded"
+	RetryOutcomeRemoved            = "removed"
+)
+
+// DefaultRegistry holds the pipeline, trigger and Store and Forward metrics exposed by the service
+var DefaultRegistry = NewRegistry()
+
+var (
+	functionInvocations = DefaultRegistry.NewCounterVec(
+		"app_pipeline_function_invocations_total",
+		"Number of times a pipeline function has been invoked.",
+		"pipeline", "position", "function")
+	functionErrors = DefaultRegistry.NewCounterVec(
+		"app_pipeline_function_errors_total",
+		"Number of times a pipeline function has returned an error or panicked.",
+		"pipeline", "position", "function", "kind")
+	functionFiltered = DefaultRegistry.NewCounterVec(
+		"app_pipeline_function_filtered_total",
+		"Number of times a pipeline function has stopped the pipeline without an error.",
+		"pipeline", "position", "function")
+	functionDuration = DefaultRegistry.NewHistogramVec(
+		"app_pipeline_function_duration_seconds",
+		"Time taken by a pipeline function to execute.",
+		DefaultDurationBuckets,
+		"pipeline", "position", "function")
+
+	triggerMessagesReceived = DefaultRegistry.NewCounterVec(
+		"app_trigger_messages_received_total",
+		"Number of messages received by a trigger.",
+		"trigger")
+	triggerMessagesPublished = DefaultRegistry.NewCounterVec(
+		"app_trigger_messages_published_total",
+		"Number of pipeline results published or returned by a trigger.",
+		"trigger")
+	triggerMessagesRateLimited = DefaultRegistry.NewCounterVec(
+		"app_trigger_messages_rate_limited_total",
+		"Number of messages rejected by a trigger for exceeding its rate limit.",
+		"trigger")
+	duplicateMessages = DefaultRegistry.NewCounterVec(
+		"app_messages_duplicate_total",
+		"Number of received messages dropped as duplicates, by the key identifying them.",
+		"key")
+
+	storeForwardQueueDepth = DefaultRegistry.NewGaugeVec(
+		"app_store_forward_queue_depth",
+		"Number of items stored for later retry.")
+	storeForwardRetries = DefaultRegistry.NewCounterVec(
+		"app_store_forward_retries_total",
+		"Number of retries of items stored for later retry, by outcome.",
+		"outcome")
+)
+
+// FunctionLabels identifies a pipeline function in the metrics
+type FunctionLabels struct {
+	PipelineId string
+	// Position is the index of the function, prefixed by the branch path for functions in fan-out branches
+	Position string
+	Function string
+}
+
+func (labels FunctionLabels) values() []string {
+	return []string{labels.PipelineId, labels.Position, labels.Function}
+}
+
+// RecordFunctionInvocation records the invocation of a pipeline function and the time it took
+func RecordFunctionInvocation(labels FunctionLabels, duration time.Duration) {
+	functionInvocations.Inc(labels.values()...)
+	functionDuration.Observe(duration.Seconds(), labels.values()...)
+}
+
+// RecordFunctionError records a pipeline function returning an error of the specified kind
+func RecordFunctionError(labels FunctionLabels, kind string) {
+	functionErrors.Inc(append(labels.values(), kind)...)
+}
+
+// RecordFunctionFiltered records a pipeline function stopping the pipeline without an error
+func RecordFunctionFiltered(labels FunctionLabels) {
+	functionFiltered.Inc(labels.values()...)
+}
+
+// RecordMessageReceived records a message received by the named trigger
+func RecordMessageReceived(trigger string) {
+	triggerMessagesReceived.Inc(trigger)
+}
+
+// RecordMessagePublished records a pipeline result published or returned by the named trigger
+func RecordMessagePublished(trigger string) {
+	triggerMessagesPublished.Inc(trigger)
+}
+
+// RecordMessageRateLimited records a message rejected by the named trigger for exceeding its rate limit
+func RecordMessageRateLimited(trigger string) {
+	triggerMessagesRateLimited.Inc(trigger)
+}
+
+// RecordMessageDuplicate records a received message dropped as a duplicate, identified by the named key
+func RecordMessageDuplicate(key string) {
+	duplicateMessages.Inc(key)
+}
+
+// SetStoreForwardQueueDepth records the number of items currently stored for later retry
+func SetStoreForwardQueueDepth(depth int) {
+	storeForwardQueueDepth.Set(float64(depth))
+}
+
+// RecordRetryOutcome records the outcome of retrying an item stored for later retry
+func RecordRetryOutcome(outcome string) {
+	storeForwardRetries.Inc(outcome)
+}
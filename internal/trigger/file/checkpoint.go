@@ -0,0 +1,103 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// checkpointFileName is the hidden file in the watched directory which records the files processed but not yet moved
+const checkpointFileName = ".checkpoint.json"
+
+// checkpointEntry identifies the processed file, so that a new file with the same name is still processed
+type checkpointEntry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	Failed  bool      `json:"failed"`
+}
+
+// checkpoint records the outcome of the files which have been processed before they are moved, so that a file isn't
+// processed again when the service restarts before it was moved
+type checkpoint struct {
+	path    string
+	entries map[string]checkpointEntry
+}
+
+// loadCheckpoint reads the checkpoint file, which is created when first saved
+func loadCheckpoint(path string) (*checkpoint, error) {
+	saved := &checkpoint{path: path, entries: make(map[string]checkpointEntry)}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return saved, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &saved.entries); err != nil {
+		return nil, fmt.Errorf("unable to decode checkpoint '%s': %w", path, err)
+	}
+
+	return saved, nil
+}
+
+// lookup returns the entry for the file if it was processed
+func (saved *checkpoint) lookup(name string, info os.FileInfo) (checkpointEntry, bool) {
+	entry, ok := saved.entries[name]
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return checkpointEntry{}, false
+	}
+
+	return entry, true
+}
+
+// record saves the outcome of processing the file
+func (saved *checkpoint) record(name string, info os.FileInfo, failed bool) error {
+	saved.entries[name] = checkpointEntry{Size: info.Size(), ModTime: info.ModTime(), Failed: failed}
+	return saved.save()
+}
+
+// remove saves the checkpoint without the file, once moved
+func (saved *checkpoint) remove(name string) error {
+	if _, ok := saved.entries[name]; !ok {
+		return nil
+	}
+
+	delete(saved.entries, name)
+	return saved.save()
+}
+
+// save writes the checkpoint to a temporary file which is renamed over the checkpoint file, so that it is never
+// left partially written
+func (saved *checkpoint) save() error {
+	data, err := json.Marshal(saved.entries)
+	if err != nil {
+		return err
+	}
+
+	temporary := saved.path + ".tmp"
+	if err := ioutil.WriteFile(temporary, data, 0640); err != nil {
+		return fmt.Errorf("unable to write checkpoint: %w", err)
+	}
+
+	return os.Rename(temporary, saved.path)
+}
@@ -0,0 +1,358 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package file
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/google/uuid"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+// metricsName identifies the trigger in the trigger metrics
+const metricsName = "file"
+
+const (
+	defaultPollInterval       = 5 * time.Second
+	defaultMinFileAge         = 2 * time.Second
+	defaultProcessedDirectory = "processed"
+	defaultFailedDirectory    = "failed"
+)
+
+// extensionContentTypes maps the file extensions to the content type of the files' messages. Other extensions use
+// the system's MIME types and unknown extensions leave the content type to be sniffed from the payload.
+var extensionContentTypes = map[string]string{
+	".json": codec.ContentTypeJSON,
+	".cbor": codec.ContentTypeCBOR,
+	".xml":  codec.ContentTypeXML,
+	".csv":  "text/csv",
+	".txt":  "text/plain",
+}
+
+// Trigger implements Trigger to support processing the files added to a directory. Each file is processed as a
+// single message and then moved to the processed or failed directory. Since there is nothing to return output data
+// to, it is discarded.
+type Trigger struct {
+	Configuration      *common.ConfigurationStruct
+	Runtime            *runtime.GolangRuntime
+	EdgeXClients       common.EdgeXClients
+	directory          string
+	processedDirectory string
+	failedDirectory    string
+	patterns           []string
+	minFileAge         time.Duration
+	checkpoint         *checkpoint
+	now                func() time.Time
+}
+
+// Initialize creates the processed and failed directories and starts polling the directory for new files
+func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context) (bootstrap.Deferred, error) {
+	logger := trigger.EdgeXClients.LoggingClient
+	watcherConfig := trigger.Configuration.Binding.FileWatcher
+
+	logger.Info(fmt.Sprintf("Initializing File Trigger for '%s'", watcherConfig.Directory))
+
+	pollInterval, err := parseDuration(watcherConfig.PollInterval, defaultPollInterval)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file watcher PollInterval: %w", err)
+	}
+	if err := trigger.configure(watcherConfig); err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("Watching '%s' every %s for files matching '%s'", trigger.directory, pollInterval.String(),
+		strings.Join(trigger.patterns, ",")))
+
+	appWg.Add(1)
+	go func() {
+		defer appWg.Done()
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			trigger.poll(appCtx)
+
+			select {
+			case <-appCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil, nil
+}
+
+// configure resolves the directories, creating those the files are moved to, and loads the checkpoint
+func (trigger *Trigger) configure(watcherConfig common.FileWatcherInfo) error {
+	if len(watcherConfig.Directory) == 0 {
+		return errors.New("file trigger requires the FileWatcher Directory")
+	}
+
+	var err error
+	trigger.minFileAge, err = parseDuration(watcherConfig.MinFileAge, defaultMinFileAge)
+	if err != nil {
+		return fmt.Errorf("invalid file watcher MinFileAge: %w", err)
+	}
+
+	trigger.patterns = nil
+	for _, pattern := range strings.Split(watcherConfig.Patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); len(pattern) > 0 {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid file watcher pattern '%s': %w", pattern, err)
+			}
+			trigger.patterns = append(trigger.patterns, pattern)
+		}
+	}
+	if len(trigger.patterns) == 0 {
+		trigger.patterns = []string{"*"}
+	}
+
+	trigger.directory = watcherConfig.Directory
+	trigger.processedDirectory = resolveDirectory(trigger.directory, watcherConfig.ProcessedDirectory, defaultProcessedDirectory)
+	trigger.failedDirectory = resolveDirectory(trigger.directory, watcherConfig.FailedDirectory, defaultFailedDirectory)
+	for _, directory := range []string{trigger.processedDirectory, trigger.failedDirectory} {
+		if err := os.MkdirAll(directory, 0750); err != nil {
+			return fmt.Errorf("unable to create directory '%s': %w", directory, err)
+		}
+	}
+
+	trigger.checkpoint, err = loadCheckpoint(filepath.Join(trigger.directory, checkpointFileName))
+	if err != nil {
+		return err
+	}
+
+	if trigger.now == nil {
+		trigger.now = time.Now
+	}
+	return nil
+}
+
+// poll processes the files ready to be processed, in the order of their names
+func (trigger *Trigger) poll(appCtx context.Context) {
+	infos, err := ioutil.ReadDir(trigger.directory)
+	if err != nil {
+		trigger.EdgeXClients.LoggingClient.Error("Unable to read watched directory", "error", err.Error())
+		return
+	}
+
+	for _, info := range infos {
+		if appCtx.Err() != nil {
+			return
+		}
+		if trigger.isReady(info) {
+			trigger.processFile(appCtx, info)
+		}
+	}
+}
+
+// isReady returns true if the file matches the patterns and hasn't been modified for at least the MinFileAge
+func (trigger *Trigger) isReady(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() || strings.HasPrefix(info.Name(), ".") {
+		return false
+	}
+	if trigger.now().Sub(info.ModTime()) < trigger.minFileAge {
+		return false
+	}
+
+	for _, pattern := range trigger.patterns {
+		if matched, _ := filepath.Match(pattern, info.Name()); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// processFile processes the file and moves it based on the outcome. The outcome is saved to the checkpoint before
+// the file is moved, so that a file which was processed but not moved is moved by the next poll rather than being
+// processed again.
+func (trigger *Trigger) processFile(appCtx context.Context, info os.FileInfo) {
+	logger := trigger.EdgeXClients.LoggingClient
+	name := info.Name()
+
+	if entry, ok := trigger.checkpoint.lookup(name, info); ok {
+		trigger.move(name, entry.Failed)
+		return
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(trigger.directory, name))
+	if err != nil {
+		logger.Error("Unable to read file", "file", name, "error", err.Error())
+		return
+	}
+
+	telemetry.RecordMessageReceived(metricsName)
+	failed := !trigger.processMessage(appCtx, name, data)
+
+	if err := trigger.checkpoint.record(name, info, failed); err != nil {
+		logger.Error("Unable to save file trigger checkpoint", "file", name, "error", err.Error())
+	}
+	trigger.move(name, failed)
+}
+
+// move moves the file to the processed or failed directory and removes it from the checkpoint. A failure is logged
+// and retried by the next poll since the file remains in the checkpoint.
+func (trigger *Trigger) move(name string, failed bool) {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	destination := trigger.processedDirectory
+	if failed {
+		destination = trigger.failedDirectory
+	}
+
+	destinationPath, err := uniquePath(destination, name)
+	if err == nil {
+		err = os.Rename(filepath.Join(trigger.directory, name), destinationPath)
+	}
+	if err != nil {
+		logger.Error("Unable to move file", "file", name, "destination", destination, "error", err.Error())
+		return
+	}
+
+	if err := trigger.checkpoint.remove(name); err != nil {
+		logger.Error("Unable to save file trigger checkpoint", "file", name, "error", err.Error())
+	}
+	logger.Debug("Moved file", "file", name, "destination", destinationPath)
+}
+
+// uniquePath returns the path of the file in the directory, adding a numeric suffix to the name, i.e.
+// readings-1.json, when a file with the same name was already moved there so it isn't overwritten
+func uniquePath(directory string, name string) (string, error) {
+	extension := filepath.Ext(name)
+	base := strings.TrimSuffix(name, extension)
+
+	candidate := filepath.Join(directory, name)
+	for suffix := 1; ; suffix++ {
+		_, err := os.Lstat(candidate)
+		if os.IsNotExist(err) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		candidate = filepath.Join(directory, fmt.Sprintf("%s-%d%s", base, suffix, extension))
+	}
+}
+
+// processMessage executes the pipeline for the file's data, returning false if it failed. Filtered data and data
+// stored for later retry by Store and Forward aren't considered failures. Failed data is sent to the dead letter
+// target when enabled. The file name is set as a value on the context.
+func (trigger *Trigger) processMessage(appCtx context.Context, name string, data []byte) bool {
+	logger := trigger.EdgeXClients.LoggingClient
+	correlationID := uuid.New().String()
+	logger.Trace("Read message from file", "file", name, clients.CorrelationHeader, correlationID)
+
+	edgexContext := &appcontext.Context{
+		CorrelationID:         correlationID,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+	}
+	_ = edgexContext.SetValue(appcontext.ValueKeyFileName, name)
+
+	// Pipeline functions are cancelled when the service is shutting down
+	requestCtx := tracing.ContextWithCorrelationID(appCtx, correlationID)
+	requestCtx, span := tracing.StartSpan(requestCtx, "file trigger", tracing.SpanKindConsumer)
+	defer span.End()
+	span.SetAttribute(tracing.AttributeCorrelationID, correlationID)
+	span.SetAttribute(tracing.AttributePayloadSize, len(data))
+	edgexContext.SetRequestContext(requestCtx)
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: correlationID,
+		ContentType:   contentTypeOf(name),
+		Payload:       data,
+	}
+
+	trigger.Runtime.CaptureMessage(edgexContext, envelope)
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		if messageError.Kind == appcontext.ErrorKindFiltered {
+			return true
+		}
+		span.SetError(messageError.Err)
+		trigger.Runtime.DeadLetterMessage(edgexContext, envelope, messageError)
+		return messageError.StoredForRetry
+	}
+
+	if outputs := edgexContext.Outputs(); len(outputs) > 0 {
+		logger.Debug(fmt.Sprintf("Discarding %d output messages since the file trigger has no destination", len(outputs)),
+			"file", name, clients.CorrelationHeader, correlationID)
+	}
+	return true
+}
+
+// contentTypeOf returns the content type for the file's extension, empty if unknown
+func contentTypeOf(name string) string {
+	extension := strings.ToLower(filepath.Ext(name))
+	if contentType, ok := extensionContentTypes[extension]; ok {
+		return contentType
+	}
+
+	contentType, _, err := mime.ParseMediaType(mime.TypeByExtension(extension))
+	if err != nil {
+		return ""
+	}
+	return contentType
+}
+
+// resolveDirectory returns the directory, relative to the watched directory unless absolute, or the default
+// subdirectory when not set
+func resolveDirectory(watched string, directory string, defaultDirectory string) string {
+	if len(directory) == 0 {
+		directory = defaultDirectory
+	}
+	if filepath.IsAbs(directory) {
+		return directory
+	}
+
+	return filepath.Join(watched, directory)
+}
+
+// parseDuration parses the duration, returning the default when not set
+func parseDuration(value string, defaultDuration time.Duration) (time.Duration, error) {
+	if len(value) == 0 {
+		return defaultDuration, nil
+	}
+
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,204 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package file
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+func newTestTrigger(t *testing.T, directory string, patterns string, transform appcontext.AppFunction) *Trigger {
+	testRuntime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms([]appcontext.AppFunction{transform})
+
+	config := &common.ConfigurationStruct{}
+	config.Binding.FileWatcher = common.FileWatcherInfo{Directory: directory, Patterns: patterns}
+
+	trigger := &Trigger{
+		Configuration: config,
+		Runtime:       testRuntime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+		// Files are always old enough to be processed
+		now: func() time.Time { return time.Now().Add(time.Hour) },
+	}
+	require.NoError(t, trigger.configure(config.Binding.FileWatcher))
+
+	return trigger
+}
+
+func writeFile(t *testing.T, directory string, name string, data string) {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(directory, name), []byte(data), 0640))
+}
+
+func TestPoll(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filetrigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	processed := map[string]string{}
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		name, _ := edgexcontext.StringValue(appcontext.ValueKeyFileName)
+		_, contentType := edgexcontext.ReceivedMessage()
+		processed[name] = contentType
+		if string(params[0].([]byte)) == "fail" {
+			return false, errors.New("failed")
+		}
+		return false, nil
+	}
+
+	trigger := newTestTrigger(t, directory, "*.json, *.csv", transform)
+	writeFile(t, directory, "readings.json", `{"value":1}`)
+	writeFile(t, directory, "readings.csv", "fail")
+	writeFile(t, directory, "readings.txt", "ignored")
+	writeFile(t, directory, ".hidden.json", "ignored")
+
+	trigger.poll(context.Background())
+
+	assert.Equal(t, map[string]string{"readings.json": "application/json", "readings.csv": "text/csv"}, processed)
+	assert.FileExists(t, filepath.Join(directory, "processed", "readings.json"))
+	assert.FileExists(t, filepath.Join(directory, "failed", "readings.csv"))
+	assert.FileExists(t, filepath.Join(directory, "readings.txt"))
+
+	saved, err := loadCheckpoint(filepath.Join(directory, checkpointFileName))
+	require.NoError(t, err)
+	assert.Empty(t, saved.entries, "Moved files should be removed from the checkpoint")
+
+	processed = map[string]string{}
+	trigger.poll(context.Background())
+	assert.Empty(t, processed, "Files should only be processed once")
+}
+
+func TestPollMinFileAge(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filetrigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	processed := 0
+	trigger := newTestTrigger(t, directory, "", func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	})
+	trigger.now = time.Now
+	writeFile(t, directory, "readings.json", `{"value":1}`)
+
+	trigger.poll(context.Background())
+	assert.Equal(t, 0, processed, "File which may still be written should not be processed")
+
+	trigger.minFileAge = 0
+	trigger.poll(context.Background())
+	assert.Equal(t, 1, processed)
+}
+
+func TestPollCheckpoint(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filetrigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	processed := 0
+	trigger := newTestTrigger(t, directory, "", func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	})
+
+	// Simulate a restart after the file was processed and failed, but before it was moved
+	writeFile(t, directory, "readings.json", `{"value":1}`)
+	info, err := os.Stat(filepath.Join(directory, "readings.json"))
+	require.NoError(t, err)
+	require.NoError(t, trigger.checkpoint.record("readings.json", info, true))
+	require.NoError(t, trigger.configure(trigger.Configuration.Binding.FileWatcher))
+
+	trigger.poll(context.Background())
+
+	assert.Equal(t, 0, processed, "File in the checkpoint should not be processed again")
+	assert.FileExists(t, filepath.Join(directory, "failed", "readings.json"))
+
+	// A new file with the same name is processed
+	writeFile(t, directory, "readings.json", `{"value":2, "more":true}`)
+	trigger.poll(context.Background())
+	assert.Equal(t, 1, processed)
+	assert.FileExists(t, filepath.Join(directory, "processed", "readings.json"))
+}
+
+func TestPollSameName(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filetrigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	processed := 0
+	trigger := newTestTrigger(t, directory, "", func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	})
+
+	for _, data := range []string{`{"value":1}`, `{"value":2}`, `{"value":3}`} {
+		writeFile(t, directory, "readings.json", data)
+		trigger.poll(context.Background())
+	}
+
+	require.Equal(t, 3, processed)
+	for name, data := range map[string]string{
+		"readings.json":   `{"value":1}`,
+		"readings-1.json": `{"value":2}`,
+		"readings-2.json": `{"value":3}`,
+	} {
+		moved, err := ioutil.ReadFile(filepath.Join(directory, "processed", name))
+		require.NoError(t, err, "Files with the same name should not be overwritten")
+		assert.Equal(t, data, string(moved))
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	directory, err := ioutil.TempDir("", "filetrigger")
+	require.NoError(t, err)
+	defer os.RemoveAll(directory)
+
+	trigger := &Trigger{}
+	assert.Error(t, trigger.configure(common.FileWatcherInfo{}), "Directory is required")
+	assert.Error(t, trigger.configure(common.FileWatcherInfo{Directory: directory, Patterns: "[a-"}))
+	assert.Error(t, trigger.configure(common.FileWatcherInfo{Directory: directory, MinFileAge: "old"}))
+
+	processedDirectory := filepath.Join(directory, "archive")
+	require.NoError(t, trigger.configure(common.FileWatcherInfo{Directory: directory, ProcessedDirectory: processedDirectory,
+		FailedDirectory: "errors"}))
+	assert.Equal(t, []string{"*"}, trigger.patterns)
+	assert.Equal(t, processedDirectory, trigger.processedDirectory)
+	assert.Equal(t, filepath.Join(directory, "errors"), trigger.failedDirectory)
+	assert.DirExists(t, trigger.failedDirectory)
+}
+
+func TestContentTypeOf(t *testing.T) {
+	assert.Equal(t, "application/json", contentTypeOf("readings.JSON"))
+	assert.Equal(t, "application/cbor", contentTypeOf("readings.cbor"))
+	assert.Equal(t, "text/csv", contentTypeOf("readings.csv"))
+	assert.Equal(t, "text/html", contentTypeOf("index.html"))
+	assert.Empty(t, contentTypeOf("readings"))
+	assert.Empty(t, contentTypeOf("readings.unknown"))
+}
@@ -0,0 +1,104 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+)
+
+const (
+	acceptHeader   = "Accept"
+	multipartMixed = "multipart/mixed"
+)
+
+// responseBody returns the body of the response for the pipeline's outputs along with its content type. A single
+// output is returned as is. Multiple outputs are returned as a JSON array when they are all JSON, unless the request
+// accepts multipart/mixed, otherwise as a multipart/mixed response with a part per output.
+func responseBody(outputs []appcontext.Output, accept string) ([]byte, string, error) {
+	switch len(outputs) {
+	case 0:
+		return nil, "", nil
+	case 1:
+		return outputs[0].Data, outputs[0].ContentType, nil
+	}
+
+	if !strings.Contains(strings.ToLower(accept), multipartMixed) && allJSON(outputs) {
+		return jsonArrayBody(outputs)
+	}
+
+	return multipartBody(outputs)
+}
+
+func allJSON(outputs []appcontext.Output) bool {
+	for _, output := range outputs {
+		contentType := codec.Normalize(output.ContentType)
+		isJSON := contentType == clients.ContentTypeJSON || strings.HasSuffix(contentType, "+json")
+		if len(contentType) > 0 && !isJSON {
+			return false
+		}
+
+		if !json.Valid(output.Data) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func jsonArrayBody(outputs []appcontext.Output) ([]byte, string, error) {
+	messages := make([]json.RawMessage, len(outputs))
+	for index, output := range outputs {
+		messages[index] = output.Data
+	}
+
+	body, err := json.Marshal(messages)
+	return body, clients.ContentTypeJSON, err
+}
+
+func multipartBody(outputs []appcontext.Output) ([]byte, string, error) {
+	buffer := &bytes.Buffer{}
+	writer := multipart.NewWriter(buffer)
+	for _, output := range outputs {
+		header := textproto.MIMEHeader{}
+		if len(output.ContentType) > 0 {
+			header.Set(clients.ContentType, output.ContentType)
+		}
+
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if _, err := part.Write(output.Data); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return buffer.Bytes(), multipartMixed + "; boundary=" + writer.Boundary(), nil
+}
@@ -20,7 +20,11 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
@@ -30,9 +34,15 @@ import (
 	"github.com/student3671/app-functions-sdk-go/internal"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
 	"github.com/student3671/app-functions-sdk-go/internal/webserver"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
 )
 
+// metricsName identifies the trigger in the trigger metrics
+const metricsName = "http"
+
 // Trigger implements Trigger to support Triggers
 type Trigger struct {
 	Configuration *common.ConfigurationStruct
@@ -40,6 +50,8 @@ type Trigger struct {
 	outputData    []byte
 	Webserver     *webserver.WebServer
 	EdgeXClients  common.EdgeXClients
+	appCtx        context.Context
+	limiter       *transforms.RateLimiter
 }
 
 // Initialize initializes the Trigger for logging and REST route
@@ -47,6 +59,23 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 	logger := trigger.EdgeXClients.LoggingClient
 
 	logger.Info("Initializing HTTP Trigger")
+	trigger.appCtx = appCtx
+
+	rateLimit := trigger.Configuration.Binding.RateLimit
+	if rateLimit.Enabled {
+		if !isAdmissionKey(rateLimit.Key) {
+			return nil, fmt.Errorf("rate limit key '%s' is not supported by the http trigger", rateLimit.Key)
+		}
+
+		limiter, err := transforms.NewRateLimiter(rateLimit.Rate, rateLimit.Burst, rateLimit.KeyRate, rateLimit.KeyBurst)
+		if err != nil {
+			return nil, fmt.Errorf("invalid http trigger rate limit: %w", err)
+		}
+		trigger.limiter = limiter
+		logger.Info(fmt.Sprintf("HTTP Trigger rate limited to %v messages per second and %v per '%s'",
+			rateLimit.Rate, rateLimit.KeyRate, rateLimit.Key))
+	}
+
 	trigger.Webserver.SetupTriggerRoute(internal.ApiTriggerRoute, trigger.requestHandler)
 	// Note: Trigger endpoint doesn't change for V2 API, so just using same handler.
 	trigger.Webserver.SetupTriggerRoute(internal.ApiV2TriggerRoute, trigger.requestHandler)
@@ -61,6 +90,10 @@ func (trigger *Trigger) requestHandler(writer http.ResponseWriter, r *http.Reque
 	logger := trigger.EdgeXClients.LoggingClient
 	contentType := r.Header.Get(clients.ContentType)
 
+	if !trigger.admit(writer, r) {
+		return
+	}
+
 	data, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		logger.Error("Error reading HTTP Body", "error", err)
@@ -69,6 +102,7 @@ func (trigger *Trigger) requestHandler(writer http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	telemetry.RecordMessageReceived(metricsName)
 	logger.Debug("Request Body read", "byte count", len(data))
 
 	correlationID := r.Header.Get(internal.CorrelationHeaderKey)
@@ -82,6 +116,22 @@ func (trigger *Trigger) requestHandler(writer http.ResponseWriter, r *http.Reque
 		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
 	}
 
+	setTransportValues(edgexContext, r)
+
+	requestCtx, cancel := trigger.requestContext(r)
+	defer cancel()
+
+	if parent, ok := tracing.Extract(r.Header); ok {
+		requestCtx = tracing.ContextWithRemoteParent(requestCtx, parent)
+	} else {
+		requestCtx = tracing.ContextWithCorrelationID(requestCtx, correlationID)
+	}
+	requestCtx, span := tracing.StartSpan(requestCtx, "http trigger", tracing.SpanKindServer)
+	defer span.End()
+	span.SetAttribute(tracing.AttributeCorrelationID, correlationID)
+	span.SetAttribute(tracing.AttributePayloadSize, len(data))
+	edgexContext.SetRequestContext(requestCtx)
+
 	logger.Trace("Received message from http", clients.CorrelationHeader, correlationID)
 	logger.Debug("Received message from http", clients.ContentType, contentType)
 
@@ -91,19 +141,165 @@ func (trigger *Trigger) requestHandler(writer http.ResponseWriter, r *http.Reque
 		Payload:       data,
 	}
 
+	trigger.Runtime.CaptureMessage(edgexContext, envelope)
 	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
 	if messageError != nil {
 		// ProcessMessage logs the error, so no need to log it here.
-		writer.WriteHeader(messageError.ErrorCode)
-		writer.Write([]byte(messageError.Err.Error()))
+		statusCode := responseStatusCode(messageError)
+		span.SetAttribute(tracing.AttributeHTTPStatusCode, statusCode)
+		if messageError.Kind != appcontext.ErrorKindFiltered {
+			span.SetError(messageError.Err)
+		}
+		writer.WriteHeader(statusCode)
+		if statusCode != http.StatusNoContent {
+			writer.Write([]byte(messageError.Err.Error()))
+		}
 		return
 	}
 
-	writer.Write(edgexContext.OutputData)
+	outputs := edgexContext.Outputs()
+	body, contentType, err := responseBody(outputs, r.Header.Get(acceptHeader))
+	if err != nil {
+		logger.Error("Failed to write the output data to the response", "error", err.Error(),
+			clients.CorrelationHeader, correlationID)
+		span.SetAttribute(tracing.AttributeHTTPStatusCode, http.StatusInternalServerError)
+		span.SetError(err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		writer.Write([]byte(err.Error()))
+		return
+	}
 
-	if edgexContext.OutputData != nil {
+	writeResponseMetadata(writer, edgexContext, contentType)
+	writer.Write(body)
+
+	for range outputs {
+		telemetry.RecordMessagePublished(metricsName)
+	}
+	if len(outputs) > 0 {
 		logger.Trace("Sent http response message", clients.CorrelationHeader, correlationID)
 	}
 
 	trigger.outputData = nil
 }
+
+// admit applies the rate limit, if enabled, before the request is read. Requests exceeding the limit are rejected
+// with 429 Too Many Requests and a Retry-After header set to when the limit allows another request.
+func (trigger *Trigger) admit(writer http.ResponseWriter, r *http.Request) bool {
+	if trigger.limiter == nil {
+		return true
+	}
+
+	allowed, retryAfter := trigger.limiter.Allow(admissionKey(trigger.Configuration.Binding.RateLimit.Key, r))
+	if allowed {
+		return true
+	}
+
+	telemetry.RecordMessageRateLimited(metricsName)
+	trigger.EdgeXClients.LoggingClient.Debug("Rejected http request exceeding the rate limit",
+		"retry after", retryAfter.String(),
+		clients.CorrelationHeader, r.Header.Get(internal.CorrelationHeaderKey))
+
+	writer.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	writer.WriteHeader(http.StatusTooManyRequests)
+	writer.Write([]byte(transforms.ErrRateLimitExceeded.Error()))
+	return false
+}
+
+// isAdmissionKey returns true if the rate limit key is available before the request is processed
+func isAdmissionKey(key string) bool {
+	key = strings.ToLower(key)
+	return len(key) == 0 || key == appcontext.ValueKeyRemoteAddress || key == transforms.RateLimitKeyCorrelationID ||
+		(strings.HasPrefix(key, appcontext.ValueKeyHTTPHeaderPrefix) && len(key) > len(appcontext.ValueKeyHTTPHeaderPrefix))
+}
+
+// admissionKey returns the request's value the per key rate limit is applied by, which matches the value set on the
+// context by setTransportValues other than the remote address being limited by host rather than host and port
+func admissionKey(key string, r *http.Request) string {
+	key = strings.ToLower(key)
+	switch {
+	case len(key) == 0:
+		return ""
+	case key == appcontext.ValueKeyRemoteAddress:
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			return host
+		}
+		return r.RemoteAddr
+	case key == transforms.RateLimitKeyCorrelationID:
+		return r.Header.Get(internal.CorrelationHeaderKey)
+	default:
+		name := http.CanonicalHeaderKey(strings.TrimPrefix(key, appcontext.ValueKeyHTTPHeaderPrefix))
+		return strings.Join(r.Header[name], ",")
+	}
+}
+
+// sensitiveHeaders are the canonical names of the headers carrying credentials. They aren't set as values on the
+// context since the values are persisted with the data stored for retry, dead letters and the journal.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Auth-Token":        true,
+}
+
+// setTransportValues sets the request's remote address and headers, other than the sensitive headers, as values on
+// the context
+func setTransportValues(edgexContext *appcontext.Context, r *http.Request) {
+	_ = edgexContext.SetValue(appcontext.ValueKeyRemoteAddress, r.RemoteAddr)
+	for name, values := range r.Header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		_ = edgexContext.SetValue(appcontext.ValueKeyHTTPHeaderPrefix+strings.ToLower(name), strings.Join(values, ","))
+	}
+}
+
+// writeResponseMetadata writes the headers and status code set by the pipeline along with the content type of the
+// response body. The status code defaults to 200 when not set.
+func writeResponseMetadata(writer http.ResponseWriter, edgexContext *appcontext.Context, contentType string) {
+	for name, values := range edgexContext.ResponseHeaders() {
+		for _, value := range values {
+			writer.Header().Add(name, value)
+		}
+	}
+
+	if len(contentType) > 0 {
+		writer.Header().Set(clients.ContentType, contentType)
+	}
+
+	if statusCode := edgexContext.ResponseStatusCode(); statusCode != 0 {
+		writer.WriteHeader(statusCode)
+	}
+}
+
+// responseStatusCode maps the classification of the pipeline function's error to the response's status code.
+// Unclassified errors use the error code set by the runtime.
+func responseStatusCode(messageError *runtime.MessageError) int {
+	switch messageError.Kind {
+	case appcontext.ErrorKindRetryable:
+		return http.StatusServiceUnavailable
+	case appcontext.ErrorKindPermanent:
+		return http.StatusUnprocessableEntity
+	case appcontext.ErrorKindFiltered:
+		return http.StatusNoContent
+	default:
+		return messageError.ErrorCode
+	}
+}
+
+// requestContext returns a context which is cancelled when the request is cancelled or the service is shutting down
+func (trigger *Trigger) requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(r.Context())
+	if trigger.appCtx != nil {
+		go func() {
+			select {
+			case <-trigger.appCtx.Done():
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	return ctx, cancel
+}
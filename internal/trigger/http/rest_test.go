@@ -0,0 +1,272 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
+)
+
+func TestRequestHandlerOutputMetadata(t *testing.T) {
+	payload := []byte(`{"id":"5888dea1bd36573f4681d6f9","device":"livingroomthermostat"}`)
+
+	tests := []struct {
+		Name                string
+		Transform           appcontext.AppFunction
+		ExpectedStatusCode  int
+		ExpectedContentType string
+		ExpectedHeader      string
+	}{
+		{
+			"Defaults",
+			func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				edgexcontext.Complete([]byte("Transformed"))
+				return false, nil
+			},
+			http.StatusOK,
+			"text/plain; charset=utf-8",
+			"",
+		},
+		{
+			"Output metadata",
+			func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				edgexcontext.SetResponseStatusCode(http.StatusAccepted)
+				edgexcontext.SetResponseHeader("X-Device", "livingroomthermostat")
+				return false, edgexcontext.CompleteAs("application/xml", params[0])
+			},
+			http.StatusAccepted,
+			"application/xml",
+			"livingroomthermostat",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			runtime := &runtime.GolangRuntime{}
+			runtime.Initialize(nil, nil)
+			runtime.SetTransforms([]appcontext.AppFunction{test.Transform})
+
+			trigger := Trigger{
+				Configuration: &common.ConfigurationStruct{},
+				Runtime:       runtime,
+				EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+			}
+
+			request := httptest.NewRequest(http.MethodPost, internal.ApiTriggerRoute, bytes.NewReader(payload))
+			request.Header.Set(clients.ContentType, clients.ContentTypeJSON)
+			recorder := httptest.NewRecorder()
+
+			trigger.requestHandler(recorder, request)
+
+			assert.Equal(t, test.ExpectedStatusCode, recorder.Code)
+			assert.Equal(t, test.ExpectedContentType, recorder.Header().Get(clients.ContentType))
+			assert.Equal(t, test.ExpectedHeader, recorder.Header().Get("X-Device"))
+			assert.NotEmpty(t, recorder.Body.Bytes())
+		})
+	}
+}
+
+func TestRequestHandlerMultipleOutputs(t *testing.T) {
+	payload := []byte(`{"id":"5888dea1bd36573f4681d6f9","device":"livingroomthermostat"}`)
+
+	tests := []struct {
+		Name        string
+		ContentType string
+		Accept      string
+		Multipart   bool
+	}{
+		{"JSON array", clients.ContentTypeJSON, "", false},
+		{"Multipart accepted", clients.ContentTypeJSON, "multipart/mixed", true},
+		{"Not JSON", "application/xml", "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				assert.NoError(t, edgexcontext.AddOutputAs(test.ContentType, params[0], ""))
+				assert.NoError(t, edgexcontext.AddOutputAs(test.ContentType, params[0], ""))
+				return false, nil
+			}
+
+			runtime := &runtime.GolangRuntime{}
+			runtime.Initialize(nil, nil)
+			runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+			trigger := Trigger{
+				Configuration: &common.ConfigurationStruct{},
+				Runtime:       runtime,
+				EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+			}
+
+			request := httptest.NewRequest(http.MethodPost, internal.ApiTriggerRoute, bytes.NewReader(payload))
+			request.Header.Set(clients.ContentType, clients.ContentTypeJSON)
+			if len(test.Accept) > 0 {
+				request.Header.Set(acceptHeader, test.Accept)
+			}
+			recorder := httptest.NewRecorder()
+
+			trigger.requestHandler(recorder, request)
+			require.Equal(t, http.StatusOK, recorder.Code)
+
+			if !test.Multipart {
+				assert.Equal(t, clients.ContentTypeJSON, recorder.Header().Get(clients.ContentType))
+				var events []models.Event
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &events))
+				require.Len(t, events, 2)
+				assert.Equal(t, "livingroomthermostat", events[1].Device)
+				return
+			}
+
+			mediaType, params, err := mime.ParseMediaType(recorder.Header().Get(clients.ContentType))
+			require.NoError(t, err)
+			require.Equal(t, multipartMixed, mediaType)
+
+			reader := multipart.NewReader(recorder.Body, params["boundary"])
+			for index := 0; index < 2; index++ {
+				part, err := reader.NextPart()
+				require.NoError(t, err)
+				assert.Equal(t, test.ContentType, part.Header.Get(clients.ContentType))
+			}
+			_, err = reader.NextPart()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestRequestHandlerTransportValues(t *testing.T) {
+	var remoteAddress, userAgent string
+	var authorizationSet, cookieSet bool
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		remoteAddress, _ = edgexcontext.StringValue(appcontext.ValueKeyRemoteAddress)
+		userAgent, _ = edgexcontext.StringValue(appcontext.ValueKeyHTTPHeaderPrefix + "user-agent")
+		_, authorizationSet = edgexcontext.StringValue(appcontext.ValueKeyHTTPHeaderPrefix + "authorization")
+		_, cookieSet = edgexcontext.StringValue(appcontext.ValueKeyHTTPHeaderPrefix + "cookie")
+		return false, nil
+	}
+
+	runtime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+	trigger := Trigger{
+		Configuration: &common.ConfigurationStruct{},
+		Runtime:       runtime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+	}
+
+	request := httptest.NewRequest(http.MethodPost, internal.ApiTriggerRoute, bytes.NewReader([]byte("data")))
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set("User-Agent", "gateway")
+	request.Header.Set("Authorization", "Bearer secret")
+	request.Header.Set("Cookie", "session=secret")
+	recorder := httptest.NewRecorder()
+
+	trigger.requestHandler(recorder, request)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "10.0.0.1:1234", remoteAddress)
+	assert.Equal(t, "gateway", userAgent)
+	assert.False(t, authorizationSet, "Authorization header should not be stored")
+	assert.False(t, cookieSet, "Cookie header should not be stored")
+}
+
+func TestRequestHandlerRateLimit(t *testing.T) {
+	processed := 0
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		processed++
+		return false, nil
+	}
+
+	runtime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+	limiter, err := transforms.NewRateLimiter(0, 0, 0.5, 1)
+	require.NoError(t, err)
+	config := &common.ConfigurationStruct{}
+	config.Binding.RateLimit = common.RateLimitInfo{Enabled: true, Key: "header.X-Api-Key", KeyRate: 0.5, KeyBurst: 1}
+	trigger := Trigger{
+		Configuration: config,
+		Runtime:       runtime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+		limiter:       limiter,
+	}
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, internal.ApiTriggerRoute, bytes.NewReader([]byte("data")))
+		request.Header.Set("X-Api-Key", apiKey)
+		recorder := httptest.NewRecorder()
+		trigger.requestHandler(recorder, request)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, send("client-1").Code)
+	assert.Equal(t, http.StatusOK, send("client-2").Code)
+
+	recorder := send("client-1")
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, 2, processed, "Rejected request should not be processed")
+}
+
+func TestAdmissionKey(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, internal.ApiTriggerRoute, nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set(internal.CorrelationHeaderKey, "correlation-1")
+	request.Header.Add("X-Api-Key", "a")
+	request.Header.Add("X-Api-Key", "b")
+
+	tests := []struct {
+		Key       string
+		Supported bool
+		Expected  string
+	}{
+		{"", true, ""},
+		{"remoteaddress", true, "10.0.0.1"},
+		{"CorrelationID", true, "correlation-1"},
+		{"header.x-api-key", true, "a,b"},
+		{"header.", false, ""},
+		{"device", false, ""},
+		{"receivedtopic", false, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Key, func(t *testing.T) {
+			require.Equal(t, test.Supported, isAdmissionKey(test.Key))
+			if test.Supported {
+				assert.Equal(t, test.Expected, admissionKey(test.Key, request))
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package messagebus
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
@@ -28,8 +29,13 @@ import (
 	"github.com/student3671/app-functions-sdk-go/appcontext"
 	"github.com/student3671/app-functions-sdk-go/internal/common"
 	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
 )
 
+// metricsName identifies the trigger in the trigger metrics
+const metricsName = "messagebus"
+
 // Trigger implements Trigger to support MessageBusData
 type Trigger struct {
 	Configuration *common.ConfigurationStruct
@@ -37,6 +43,9 @@ type Trigger struct {
 	client        messaging.MessageClient
 	topics        []types.TopicChannel
 	EdgeXClients  common.EdgeXClients
+	// KeyExtractor, if set, enables processing messages with the same key in the order received.
+	// Takes precedence over the WorkerPool OrderingKey setting.
+	KeyExtractor KeyExtractor
 }
 
 // Initialize ...
@@ -50,16 +59,36 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 	if err != nil {
 		return nil, err
 	}
-	trigger.topics = []types.TopicChannel{{Topic: trigger.Configuration.Binding.SubscribeTopic, Messages: make(chan types.MessageEnvelope)}}
+
+	// Without a SubscribeTopic the empty topic is subscribed to, which receives all messages
+	subscribeTopics := trigger.Configuration.Binding.SubscribeTopics()
+	if len(subscribeTopics) == 0 {
+		subscribeTopics = []string{""}
+	}
+	trigger.topics = make([]types.TopicChannel, len(subscribeTopics))
+	for index, topic := range subscribeTopics {
+		trigger.topics[index] = types.TopicChannel{Topic: topic, Messages: make(chan types.MessageEnvelope)}
+	}
 	messageErrors := make(chan error)
 
+	pool, err := newWorkerPool(trigger.Configuration.Binding.WorkerPool, trigger.KeyExtractor, logger,
+		func(ctx context.Context, message receivedMessage) {
+			trigger.processMessage(ctx, message)
+		})
+	if err != nil {
+		return nil, err
+	}
+
 	err = trigger.client.Connect()
 	if err != nil {
 		return nil, err
 	}
 
-	logger.Info(fmt.Sprintf("Subscribing to topic: '%s' @ %s://%s:%d",
-		trigger.Configuration.Binding.SubscribeTopic,
+	// Dead letters are published with the trigger's client since some message bus types only allow one publisher
+	trigger.Runtime.SetDeadLetterPublisher(trigger.client)
+
+	logger.Info(fmt.Sprintf("Subscribing to topics: '%s' @ %s://%s:%d",
+		strings.Join(subscribeTopics, ","),
 		trigger.Configuration.MessageBus.SubscribeHost.Protocol,
 		trigger.Configuration.MessageBus.SubscribeHost.Host,
 		trigger.Configuration.MessageBus.SubscribeHost.Port))
@@ -75,10 +104,18 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 			trigger.Configuration.MessageBus.PublishHost.Port))
 	}
 
+	logger.Info(fmt.Sprintf("Processing messages with %d workers, queue size of %d and '%s' queue full policy. Ordered by key: %t",
+		pool.workers, pool.queueSize(), pool.policy, pool.ordered()))
+
+	pool.start(appWg, appCtx)
+	received := trigger.receive(appWg, appCtx)
+
 	appWg.Add(1)
 
 	go func() {
 		defer appWg.Done()
+		// Workers finish processing the in-flight and queued messages once the queues are closed
+		defer pool.stop()
 
 		for receiveMessage {
 			select {
@@ -88,40 +125,9 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 			case msgErr := <-messageErrors:
 				logger.Error(fmt.Sprintf("Failed to receive message from bus, %v", msgErr))
 
-			case msgs := <-trigger.topics[0].Messages:
-				go func() {
-					logger.Trace("Received message from bus", "topic", trigger.Configuration.Binding.SubscribeTopic, clients.CorrelationHeader, msgs.CorrelationID)
-
-					edgexContext := &appcontext.Context{
-						CorrelationID:         msgs.CorrelationID,
-						Configuration:         trigger.Configuration,
-						LoggingClient:         trigger.EdgeXClients.LoggingClient,
-						EventClient:           trigger.EdgeXClients.EventClient,
-						ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
-						CommandClient:         trigger.EdgeXClients.CommandClient,
-						NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
-					}
-
-					messageError := trigger.Runtime.ProcessMessage(edgexContext, msgs)
-					if messageError != nil {
-						// ProcessMessage logs the error, so no need to log it here.
-						return
-					}
-
-					if edgexContext.OutputData != nil {
-						outputEnvelope := types.MessageEnvelope{
-							CorrelationID: edgexContext.CorrelationID,
-							Payload:       edgexContext.OutputData,
-							ContentType:   clients.ContentTypeJSON,
-						}
-						err := trigger.client.Publish(outputEnvelope, trigger.Configuration.Binding.PublishTopic)
-						if err != nil {
-							logger.Error(fmt.Sprintf("Failed to publish Message to bus, %v", err))
-						}
-
-						logger.Trace("Published message to bus", "topic", trigger.Configuration.Binding.PublishTopic, clients.CorrelationHeader, msgs.CorrelationID)
-					}
-				}()
+			case message := <-received:
+				telemetry.RecordMessageReceived(metricsName)
+				pool.submit(appCtx, message)
 			}
 		}
 	}()
@@ -135,3 +141,111 @@ func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context
 	}
 	return deferred, nil
 }
+
+// receive forwards the messages received on each of the subscribed topics, along with the topic, to the returned
+// channel until the service is shutting down
+func (trigger *Trigger) receive(appWg *sync.WaitGroup, appCtx context.Context) <-chan receivedMessage {
+	received := make(chan receivedMessage)
+
+	for _, topic := range trigger.topics {
+		appWg.Add(1)
+		go func(topic types.TopicChannel) {
+			defer appWg.Done()
+
+			for {
+				select {
+				case <-appCtx.Done():
+					return
+
+				case envelope := <-topic.Messages:
+					select {
+					case received <- receivedMessage{MessageEnvelope: envelope, topic: topic.Topic}:
+					case <-appCtx.Done():
+						return
+					}
+				}
+			}
+		}(topic)
+	}
+
+	return received
+}
+
+// processMessage executes the pipeline for the received message and publishes the output data, if any. The topic
+// the message was received on is set on the context, so that pipelines can be selected by topic. The pipeline
+// functions are cancelled when processCtx is done.
+func (trigger *Trigger) processMessage(processCtx context.Context, message receivedMessage) {
+	logger := trigger.EdgeXClients.LoggingClient
+	msgs := message.MessageEnvelope
+	logger.Trace("Received message from bus", "topic", message.topic, clients.CorrelationHeader, msgs.CorrelationID)
+
+	edgexContext := &appcontext.Context{
+		CorrelationID:         msgs.CorrelationID,
+		ReceivedTopic:         message.topic,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+	}
+	_ = edgexContext.SetValue(appcontext.ValueKeyReceivedTopic, edgexContext.ReceivedTopic)
+
+	requestCtx := tracing.ContextWithCorrelationID(processCtx, msgs.CorrelationID)
+	requestCtx, span := tracing.StartSpan(requestCtx, "messagebus trigger", tracing.SpanKindConsumer)
+	defer span.End()
+	span.SetAttribute(tracing.AttributeCorrelationID, msgs.CorrelationID)
+	span.SetAttribute(tracing.AttributeTopic, edgexContext.ReceivedTopic)
+	span.SetAttribute(tracing.AttributePayloadSize, len(msgs.Payload))
+	edgexContext.SetRequestContext(requestCtx)
+
+	trigger.Runtime.CaptureMessage(edgexContext, msgs)
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, msgs)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		if messageError.Kind != appcontext.ErrorKindFiltered {
+			span.SetError(messageError.Err)
+		}
+		trigger.Runtime.DeadLetterMessage(edgexContext, msgs, messageError)
+		return
+	}
+
+	for _, output := range edgexContext.Outputs() {
+		trigger.publish(requestCtx, edgexContext, output)
+	}
+}
+
+// publish publishes the output message, defaulting to JSON and the Binding PublishTopic. Failures are logged so that
+// the remaining outputs are still published.
+func (trigger *Trigger) publish(requestCtx context.Context, edgexContext *appcontext.Context, output appcontext.Output) {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	contentType := output.ContentType
+	if len(contentType) == 0 {
+		contentType = clients.ContentTypeJSON
+	}
+
+	publishTopic := output.Topic
+	if len(publishTopic) == 0 {
+		publishTopic = trigger.Configuration.Binding.PublishTopic
+	}
+
+	outputEnvelope := types.MessageEnvelope{
+		CorrelationID: edgexContext.CorrelationID,
+		Payload:       output.Data,
+		ContentType:   contentType,
+	}
+	_, publishSpan := tracing.StartSpan(requestCtx, "messagebus publish", tracing.SpanKindProducer)
+	publishSpan.SetAttribute(tracing.AttributeTopic, publishTopic)
+	publishSpan.SetAttribute(tracing.AttributePayloadSize, len(outputEnvelope.Payload))
+	err := trigger.client.Publish(outputEnvelope, publishTopic)
+	publishSpan.SetError(err)
+	publishSpan.End()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to publish Message to bus, %v", err), "topic", publishTopic)
+		return
+	}
+
+	telemetry.RecordMessagePublished(metricsName)
+	logger.Trace("Published message to bus", "topic", publishTopic, clients.CorrelationHeader, edgexContext.CorrelationID)
+}
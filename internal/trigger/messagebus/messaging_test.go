@@ -75,6 +75,65 @@ func TestInitialize(t *testing.T) {
 	assert.NotNil(t, trigger.topics[0].Messages)
 }
 
+func TestInitializeMultipleTopics(t *testing.T) {
+
+	config := common.ConfigurationStruct{
+		Binding: common.BindingInfo{
+			Type:           "meSsaGebus",
+			PublishTopic:   "publish",
+			SubscribeTopic: "events, alerts",
+		},
+		MessageBus: types.MessageBusConfig{
+			Type: "zero",
+			PublishHost: types.HostInfo{
+				Host:     "*",
+				Port:     5590,
+				Protocol: "tcp",
+			},
+			SubscribeHost: types.HostInfo{
+				Host:     "localhost",
+				Port:     5590,
+				Protocol: "tcp",
+			},
+		},
+	}
+
+	runtime := &runtime.GolangRuntime{}
+
+	trigger := Trigger{Configuration: &config, Runtime: runtime, EdgeXClients: common.EdgeXClients{LoggingClient: logClient}}
+	_, err := trigger.Initialize(&sync.WaitGroup{}, context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, len(trigger.topics))
+	assert.Equal(t, "events", trigger.topics[0].Topic)
+	assert.Equal(t, "alerts", trigger.topics[1].Topic)
+}
+
+func TestReceiveSetsTopic(t *testing.T) {
+	trigger := Trigger{
+		topics: []types.TopicChannel{
+			{Topic: "events", Messages: make(chan types.MessageEnvelope)},
+			{Topic: "alerts", Messages: make(chan types.MessageEnvelope)},
+		},
+	}
+
+	appWg := &sync.WaitGroup{}
+	appCtx, cancel := context.WithCancel(context.Background())
+	received := trigger.receive(appWg, appCtx)
+
+	trigger.topics[1].Messages <- types.MessageEnvelope{CorrelationID: "1"}
+	message := <-received
+	assert.Equal(t, "alerts", message.topic)
+	assert.Equal(t, "1", message.CorrelationID)
+
+	trigger.topics[0].Messages <- types.MessageEnvelope{CorrelationID: "2"}
+	message = <-received
+	assert.Equal(t, "events", message.topic)
+	assert.Equal(t, "2", message.CorrelationID)
+
+	cancel()
+	appWg.Wait()
+}
+
 func TestInitializeBadConfiguration(t *testing.T) {
 
 	config := common.ConfigurationStruct{
@@ -268,3 +327,164 @@ func TestInitializeAndProcessEventWithOutput(t *testing.T) {
 		}
 	}
 }
+
+// recordingClient records the published messages
+type recordingClient struct {
+	lock      sync.Mutex
+	published map[string][]types.MessageEnvelope
+}
+
+func (client *recordingClient) Connect() error {
+	return nil
+}
+
+func (client *recordingClient) Publish(message types.MessageEnvelope, topic string) error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	if client.published == nil {
+		client.published = make(map[string][]types.MessageEnvelope)
+	}
+	client.published[topic] = append(client.published[topic], message)
+	return nil
+}
+
+func (client *recordingClient) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return nil
+}
+
+func (client *recordingClient) Disconnect() error {
+	return nil
+}
+
+func TestProcessMessageOutputMetadata(t *testing.T) {
+	payload := []byte(`{"id":"5888dea1bd36573f4681d6f9","device":"livingroomthermostat"}`)
+
+	tests := []struct {
+		Name                string
+		Transform           appcontext.AppFunction
+		ExpectedTopic       string
+		ExpectedContentType string
+	}{
+		{
+			"Defaults",
+			func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				edgexcontext.Complete([]byte("Transformed"))
+				return false, nil
+			},
+			"PublishTopic",
+			clients.ContentTypeJSON,
+		},
+		{
+			"Content type and topic",
+			func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				topic, err := edgexcontext.ExpandTopic("events/{devicename}", params[0])
+				require.NoError(t, err)
+				edgexcontext.SetOutputTopic(topic)
+				return false, edgexcontext.CompleteAs(clients.ContentTypeCBOR, params[0])
+			},
+			"events/livingroomthermostat",
+			clients.ContentTypeCBOR,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			config := common.ConfigurationStruct{
+				Binding: common.BindingInfo{
+					Type:           "messagebus",
+					PublishTopic:   "PublishTopic",
+					SubscribeTopic: "SubscribeTopic",
+				},
+			}
+
+			runtime := &runtime.GolangRuntime{}
+			runtime.Initialize(nil, nil)
+			runtime.SetTransforms([]appcontext.AppFunction{test.Transform})
+
+			client := &recordingClient{}
+			trigger := Trigger{
+				Configuration: &config,
+				Runtime:       runtime,
+				EdgeXClients:  common.EdgeXClients{LoggingClient: logClient},
+				client:        client,
+				topics:        []types.TopicChannel{{Topic: config.Binding.SubscribeTopic}},
+			}
+
+			trigger.processMessage(context.Background(), receivedMessage{
+				MessageEnvelope: types.MessageEnvelope{
+					CorrelationID: "123",
+					Payload:       payload,
+					ContentType:   clients.ContentTypeJSON,
+				},
+				topic: "SubscribeTopic",
+			})
+
+			require.Len(t, client.published[test.ExpectedTopic], 1)
+			published := client.published[test.ExpectedTopic][0]
+			assert.Equal(t, test.ExpectedContentType, published.ContentType)
+			assert.Equal(t, "123", published.CorrelationID)
+		})
+	}
+}
+
+func TestProcessMessageMultipleOutputs(t *testing.T) {
+	payload := []byte(`{"id":"5888dea1bd36573f4681d6f9","device":"livingroomthermostat","readings":[{"name":"temperature","value":"38"},{"name":"humidity","value":"50"}]}`)
+
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		topic, _ := edgexcontext.StringValue(appcontext.ValueKeyReceivedTopic)
+		assert.Equal(t, "SubscribeTopic", topic)
+
+		event := params[0].(models.Event)
+		for _, reading := range event.Readings {
+			err := edgexcontext.AddOutputAs(clients.ContentTypeJSON, reading, "readings/"+reading.Name)
+			require.NoError(t, err)
+		}
+		edgexcontext.Complete([]byte("Transformed"))
+		return false, nil
+	}
+
+	config := common.ConfigurationStruct{
+		Binding: common.BindingInfo{
+			Type:           "messagebus",
+			PublishTopic:   "PublishTopic",
+			SubscribeTopic: "SubscribeTopic",
+		},
+	}
+
+	runtime := &runtime.GolangRuntime{}
+	runtime.Initialize(nil, nil)
+	runtime.SetTransforms([]appcontext.AppFunction{transform})
+
+	client := &recordingClient{}
+	trigger := Trigger{
+		Configuration: &config,
+		Runtime:       runtime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logClient},
+		client:        client,
+		topics:        []types.TopicChannel{{Topic: config.Binding.SubscribeTopic}},
+	}
+
+	trigger.processMessage(context.Background(), receivedMessage{
+		MessageEnvelope: types.MessageEnvelope{
+			CorrelationID: "123",
+			Payload:       payload,
+			ContentType:   clients.ContentTypeJSON,
+		},
+		topic: "SubscribeTopic",
+	})
+
+	require.Len(t, client.published, 3)
+	require.Len(t, client.published["PublishTopic"], 1)
+	assert.Equal(t, "Transformed", string(client.published["PublishTopic"][0].Payload))
+
+	for _, name := range []string{"temperature", "humidity"} {
+		published := client.published["readings/"+name]
+		require.Len(t, published, 1)
+		assert.Equal(t, clients.ContentTypeJSON, published[0].ContentType)
+
+		var reading models.Reading
+		require.NoError(t, json.Unmarshal(published[0].Payload, &reading))
+		assert.Equal(t, name, reading.Name)
+	}
+}
@@ -0,0 +1,99 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package messagebus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/fxamacker/cbor/v2"
+)
+
+const (
+	orderingKeyDevice  = "device"
+	orderingKeyReading = "reading"
+)
+
+// KeyExtractor returns the key of the received message. Messages with the same key are processed in the order
+// they are received. Messages with an empty key are not ordered.
+type KeyExtractor func(envelope types.MessageEnvelope) string
+
+// keyExtractorFor returns the built-in key extractor for the OrderingKey setting, nil if ordering is not enabled.
+func keyExtractorFor(orderingKey string) (KeyExtractor, error) {
+	switch strings.ToLower(strings.TrimSpace(orderingKey)) {
+	case "":
+		return nil, nil
+	case orderingKeyDevice:
+		return deviceKey, nil
+	case orderingKeyReading:
+		return readingKey, nil
+	default:
+		return nil, fmt.Errorf("WorkerPool OrderingKey '%s' is invalid, must be one of %s or %s",
+			orderingKey, orderingKeyDevice, orderingKeyReading)
+	}
+}
+
+// deviceKey returns the device name of the Event in the message
+func deviceKey(envelope types.MessageEnvelope) string {
+	event, ok := decodeEventKeys(envelope)
+	if !ok {
+		return ""
+	}
+
+	return event.Device
+}
+
+// readingKey returns the name of the first Reading of the Event in the message
+func readingKey(envelope types.MessageEnvelope) string {
+	event, ok := decodeEventKeys(envelope)
+	if !ok || len(event.Readings) == 0 {
+		return ""
+	}
+
+	return event.Readings[0].Name
+}
+
+// eventKeys holds the fields of an Event the built-in key extractors order by. Unlike models.Event it isn't
+// validated when decoded, so that an Event which fails validation is still ordered by its key, failing when it
+// is processed rather than being processed out of order.
+type eventKeys struct {
+	Device   string `json:"device" codec:"device"`
+	Readings []struct {
+		Name string `json:"name" codec:"name"`
+	} `json:"readings" codec:"readings"`
+}
+
+// decodeEventKeys decodes the key fields of the Event in the message. The message is decoded again when it is
+// processed, since the pipeline's TargetType may not be an Event.
+func decodeEventKeys(envelope types.MessageEnvelope) (eventKeys, bool) {
+	var event eventKeys
+	var err error
+
+	switch envelope.ContentType {
+	case clients.ContentTypeJSON:
+		err = json.Unmarshal(envelope.Payload, &event)
+	case clients.ContentTypeCBOR:
+		err = cbor.Unmarshal(envelope.Payload, &event)
+	default:
+		return event, false
+	}
+
+	return event, err == nil
+}
@@ -0,0 +1,77 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package messagebus
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyExtractorFor(t *testing.T) {
+	extractor, err := keyExtractorFor("")
+	require.NoError(t, err)
+	assert.Nil(t, extractor)
+
+	extractor, err = keyExtractorFor("Device")
+	require.NoError(t, err)
+	assert.NotNil(t, extractor)
+
+	extractor, err = keyExtractorFor("reading")
+	require.NoError(t, err)
+	assert.NotNil(t, extractor)
+
+	_, err = keyExtractorFor("bogus")
+	assert.Error(t, err)
+}
+
+func TestBuiltInKeyExtractors(t *testing.T) {
+	event := models.Event{Device: "device1", Readings: []models.Reading{
+		{Name: "temperature", Value: "21.5"},
+		{Name: "humidity", Value: "40"},
+	}}
+	// An Event which fails validation is still ordered by its key
+	invalidPayload := []byte(`{"device":"device1","readings":[{"name":"temperature"}]}`)
+	jsonPayload, _ := json.Marshal(event)
+	cborPayload, _ := cbor.Marshal(event)
+
+	tests := []struct {
+		name            string
+		envelope        types.MessageEnvelope
+		expectedDevice  string
+		expectedReading string
+	}{
+		{"JSON", types.MessageEnvelope{ContentType: clients.ContentTypeJSON, Payload: jsonPayload}, "device1", "temperature"},
+		{"CBOR", types.MessageEnvelope{ContentType: clients.ContentTypeCBOR, Payload: cborPayload}, "device1", "temperature"},
+		{"Invalid Event", types.MessageEnvelope{ContentType: clients.ContentTypeJSON, Payload: invalidPayload}, "device1", "temperature"},
+		{"Bad JSON", types.MessageEnvelope{ContentType: clients.ContentTypeJSON, Payload: []byte("{bad")}, "", ""},
+		{"Unknown content type", types.MessageEnvelope{ContentType: "text/plain", Payload: jsonPayload}, "", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expectedDevice, deviceKey(test.envelope))
+			assert.Equal(t, test.expectedReading, readingKey(test.envelope))
+		})
+	}
+}
@@ -0,0 +1,277 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package messagebus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+)
+
+const (
+	queueFullPolicyBlock      = "block"
+	queueFullPolicyDropOldest = "drop-oldest"
+	queueFullPolicyDropNewest = "drop-newest"
+
+	defaultQueueSize    = 100
+	defaultDrainTimeout = 10 * time.Second
+)
+
+// receivedMessage is a message received by the trigger along with the subscribed topic it was received on
+type receivedMessage struct {
+	types.MessageEnvelope
+	topic string
+}
+
+// workerPool processes the received messages with a fixed number of workers. Messages wait in a bounded queue
+// for a worker and the queue full policy determines what happens to received messages when the queue is full.
+// When ordering is enabled each worker has its own queue and messages are assigned to a worker by their key,
+// so that messages with the same key are processed sequentially in the order received. Once the service is shutting
+// down the in-flight and queued messages continue to be processed until the drain timeout expires.
+type workerPool struct {
+	workers      int
+	policy       string
+	queues       []chan receivedMessage
+	keyExtractor KeyExtractor
+	nextQueue    int
+	drainTimeout time.Duration
+	process      func(ctx context.Context, message receivedMessage)
+	logger       logger.LoggingClient
+}
+
+// newWorkerPool creates the worker pool for the configuration. The keyExtractor, if not nil, enables ordering
+// and takes precedence over the configured OrderingKey.
+func newWorkerPool(config common.WorkerPoolInfo, keyExtractor KeyExtractor, lc logger.LoggingClient,
+	process func(ctx context.Context, message receivedMessage)) (*workerPool, error) {
+
+	if config.Workers < 0 {
+		return nil, errors.New("WorkerPool Workers can not be less than 0")
+	}
+
+	if config.QueueSize < 0 {
+		return nil, errors.New("WorkerPool QueueSize can not be less than 0")
+	}
+
+	policy := strings.ToLower(strings.TrimSpace(config.QueueFullPolicy))
+	switch policy {
+	case "":
+		policy = queueFullPolicyBlock
+	case queueFullPolicyBlock, queueFullPolicyDropOldest, queueFullPolicyDropNewest:
+	default:
+		return nil, fmt.Errorf("WorkerPool QueueFullPolicy '%s' is invalid, must be one of %s, %s or %s",
+			config.QueueFullPolicy, queueFullPolicyBlock, queueFullPolicyDropOldest, queueFullPolicyDropNewest)
+	}
+
+	drainTimeout := defaultDrainTimeout
+	if len(config.DrainTimeout) > 0 {
+		var err error
+		drainTimeout, err = time.ParseDuration(config.DrainTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("WorkerPool DrainTimeout '%s' is invalid: %w", config.DrainTimeout, err)
+		}
+		if drainTimeout < 0 {
+			return nil, errors.New("WorkerPool DrainTimeout can not be less than 0")
+		}
+	}
+
+	if keyExtractor == nil {
+		var err error
+		keyExtractor, err = keyExtractorFor(config.OrderingKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	workers := config.Workers
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+
+	queueSize := config.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+
+	// Without ordering the workers share a single queue
+	queueCount := 1
+	if keyExtractor != nil {
+		queueCount = workers
+		// The queue size is the total for all the workers' queues
+		queueSize = queueSize / workers
+		if queueSize == 0 {
+			queueSize = 1
+		}
+	}
+
+	queues := make([]chan receivedMessage, queueCount)
+	for index := range queues {
+		queues[index] = make(chan receivedMessage, queueSize)
+	}
+
+	return &workerPool{
+		workers:      workers,
+		policy:       policy,
+		queues:       queues,
+		keyExtractor: keyExtractor,
+		drainTimeout: drainTimeout,
+		process:      process,
+		logger:       lc,
+	}, nil
+}
+
+// ordered returns true when messages with the same key are processed in order
+func (pool *workerPool) ordered() bool {
+	return pool.keyExtractor != nil
+}
+
+// queueSize returns the total number of messages which can wait for a worker
+func (pool *workerPool) queueSize() int {
+	return cap(pool.queues[0]) * len(pool.queues)
+}
+
+// start starts the workers, which are tracked by appWg. Once the service is shutting down, the workers continue to
+// process the in-flight and queued messages until the queues are closed by stop and empty. The messages are processed
+// with a context which is only cancelled once the drain timeout expires, after which the remaining queued messages
+// are discarded.
+func (pool *workerPool) start(appWg *sync.WaitGroup, appCtx context.Context) {
+	processCtx, cancelProcessing := context.WithCancel(context.Background())
+	workersWg := &sync.WaitGroup{}
+
+	for i := 0; i < pool.workers; i++ {
+		queue := pool.queues[i%len(pool.queues)]
+
+		appWg.Add(1)
+		workersWg.Add(1)
+		go func() {
+			defer appWg.Done()
+			defer workersWg.Done()
+
+			for message := range queue {
+				if processCtx.Err() != nil {
+					pool.logger.Debug("Drain timeout expired, discarding queued message",
+						clients.CorrelationHeader, message.CorrelationID)
+					continue
+				}
+
+				pool.process(processCtx, message)
+			}
+		}()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		workersWg.Wait()
+		close(drained)
+	}()
+
+	appWg.Add(1)
+	go func() {
+		defer appWg.Done()
+		defer cancelProcessing()
+
+		select {
+		case <-drained:
+			return
+		case <-appCtx.Done():
+		}
+
+		timer := time.NewTimer(pool.drainTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-drained:
+		case <-timer.C:
+			pool.logger.Warn(fmt.Sprintf("Messages not processed within the %s drain timeout, cancelling in-flight "+
+				"and discarding queued messages", pool.drainTimeout))
+		}
+	}()
+}
+
+// submit queues the message for processing, applying the queue full policy when the queue is full.
+// Must only be called from a single go routine and not after stop.
+func (pool *workerPool) submit(appCtx context.Context, message receivedMessage) {
+	queue := pool.selectQueue(message)
+
+	switch pool.policy {
+	case queueFullPolicyDropNewest:
+		select {
+		case queue <- message:
+		default:
+			pool.logger.Warn("Message queue is full, dropping received message",
+				clients.CorrelationHeader, message.CorrelationID)
+		}
+
+	case queueFullPolicyDropOldest:
+		for {
+			select {
+			case queue <- message:
+				return
+			default:
+			}
+
+			select {
+			case oldest := <-queue:
+				pool.logger.Warn("Message queue is full, dropping oldest queued message",
+					clients.CorrelationHeader, oldest.CorrelationID)
+			default:
+			}
+		}
+
+	default:
+		select {
+		case queue <- message:
+		case <-appCtx.Done():
+		}
+	}
+}
+
+// selectQueue returns the queue of the worker for the message's key. Messages without a key are
+// distributed evenly between the workers.
+func (pool *workerPool) selectQueue(message receivedMessage) chan receivedMessage {
+	if len(pool.queues) == 1 {
+		return pool.queues[0]
+	}
+
+	key := pool.keyExtractor(message.MessageEnvelope)
+	if key == "" {
+		pool.logger.Debug("Message has no ordering key, it is not ordered",
+			clients.CorrelationHeader, message.CorrelationID)
+		pool.nextQueue = (pool.nextQueue + 1) % len(pool.queues)
+		return pool.queues[pool.nextQueue]
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(key))
+	return pool.queues[hash.Sum32()%uint32(len(pool.queues))]
+}
+
+// stop closes the queues so the workers exit once they are empty
+func (pool *workerPool) stop() {
+	for _, queue := range pool.queues {
+		close(queue)
+	}
+}
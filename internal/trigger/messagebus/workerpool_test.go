@@ -0,0 +1,229 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package messagebus
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+)
+
+func TestNewWorkerPool(t *testing.T) {
+	tests := []struct {
+		name              string
+		config            common.WorkerPoolInfo
+		expectError       bool
+		expectedQueueSize int
+		expectedPolicy    string
+	}{
+		{"Defaults", common.WorkerPoolInfo{}, false, defaultQueueSize, queueFullPolicyBlock},
+		{"Drop oldest", common.WorkerPoolInfo{Workers: 2, QueueSize: 5, QueueFullPolicy: "Drop-Oldest"}, false, 5, queueFullPolicyDropOldest},
+		{"Drop newest", common.WorkerPoolInfo{QueueFullPolicy: "drop-newest"}, false, defaultQueueSize, queueFullPolicyDropNewest},
+		{"Bad policy", common.WorkerPoolInfo{QueueFullPolicy: "bogus"}, true, 0, ""},
+		{"Negative workers", common.WorkerPoolInfo{Workers: -1}, true, 0, ""},
+		{"Negative queue size", common.WorkerPoolInfo{QueueSize: -1}, true, 0, ""},
+		{"Drain timeout", common.WorkerPoolInfo{DrainTimeout: "1m"}, false, defaultQueueSize, queueFullPolicyBlock},
+		{"Bad drain timeout", common.WorkerPoolInfo{DrainTimeout: "bogus"}, true, 0, ""},
+		{"Negative drain timeout", common.WorkerPoolInfo{DrainTimeout: "-1s"}, true, 0, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool, err := newWorkerPool(test.config, nil, logClient, func(ctx context.Context, message receivedMessage) {})
+			if test.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, pool.workers > 0)
+			assert.Equal(t, test.expectedQueueSize, pool.queueSize())
+			assert.Equal(t, test.expectedPolicy, pool.policy)
+		})
+	}
+}
+
+func TestWorkerPoolQueueFull(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   string
+		expected []string
+	}{
+		{"Drop newest", queueFullPolicyDropNewest, []string{"1", "2"}},
+		{"Drop oldest", queueFullPolicyDropOldest, []string{"2", "3"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := common.WorkerPoolInfo{Workers: 1, QueueSize: 2, QueueFullPolicy: test.policy}
+			pool, err := newWorkerPool(config, nil, logClient, func(ctx context.Context, message receivedMessage) {})
+			require.NoError(t, err)
+
+			// Workers are not started so the queue fills up
+			for _, id := range []string{"1", "2", "3"} {
+				pool.submit(context.Background(), receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: id}})
+			}
+
+			pool.stop()
+			var actual []string
+			for message := range pool.queues[0] {
+				actual = append(actual, message.CorrelationID)
+			}
+
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestWorkerPoolBlockUntilShutdown(t *testing.T) {
+	config := common.WorkerPoolInfo{Workers: 1, QueueSize: 1}
+	pool, err := newWorkerPool(config, nil, logClient, func(ctx context.Context, message receivedMessage) {})
+	require.NoError(t, err)
+
+	appCtx, cancel := context.WithCancel(context.Background())
+	pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "1"}})
+
+	submitted := make(chan bool)
+	go func() {
+		pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "2"}})
+		submitted <- true
+	}()
+
+	cancel()
+	assert.True(t, <-submitted, "Expected blocked submit to return once shutting down")
+	assert.Equal(t, 1, len(pool.queues[0]))
+}
+
+func TestWorkerPoolDrainOnShutdown(t *testing.T) {
+	appWg := &sync.WaitGroup{}
+	appCtx, cancel := context.WithCancel(context.Background())
+
+	processing := make(chan bool)
+	release := make(chan bool)
+	var processed []string
+	var cancelled []bool
+	mutex := sync.Mutex{}
+
+	config := common.WorkerPoolInfo{Workers: 1, QueueSize: 5}
+	pool, err := newWorkerPool(config, nil, logClient, func(ctx context.Context, message receivedMessage) {
+		if message.CorrelationID == "in-flight" {
+			processing <- true
+			<-release
+		}
+		mutex.Lock()
+		processed = append(processed, message.CorrelationID)
+		cancelled = append(cancelled, ctx.Err() != nil)
+		mutex.Unlock()
+	})
+	require.NoError(t, err)
+
+	pool.start(appWg, appCtx)
+	pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "in-flight"}})
+	<-processing
+	pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "queued-1"}})
+	pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "queued-2"}})
+
+	cancel()
+	pool.stop()
+	close(release)
+	appWg.Wait()
+
+	assert.Equal(t, []string{"in-flight", "queued-1", "queued-2"}, processed,
+		"Expected in-flight and queued messages to be processed after shutdown started")
+	assert.Equal(t, []bool{false, false, false}, cancelled, "Expected messages to be processed with a live context")
+}
+
+func TestWorkerPoolDrainTimeout(t *testing.T) {
+	appWg := &sync.WaitGroup{}
+	appCtx, cancel := context.WithCancel(context.Background())
+
+	processing := make(chan bool)
+	var processed []string
+	mutex := sync.Mutex{}
+
+	config := common.WorkerPoolInfo{Workers: 1, QueueSize: 5, DrainTimeout: "10ms"}
+	pool, err := newWorkerPool(config, nil, logClient, func(ctx context.Context, message receivedMessage) {
+		if message.CorrelationID == "in-flight" {
+			processing <- true
+			// The in-flight message's context is cancelled once the drain timeout expires
+			<-ctx.Done()
+		}
+		mutex.Lock()
+		processed = append(processed, message.CorrelationID)
+		mutex.Unlock()
+	})
+	require.NoError(t, err)
+
+	pool.start(appWg, appCtx)
+	pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "in-flight"}})
+	<-processing
+	pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{CorrelationID: "queued"}})
+
+	cancel()
+	pool.stop()
+	appWg.Wait()
+
+	assert.Equal(t, []string{"in-flight"}, processed, "Expected queued message to be discarded after the drain timeout")
+}
+
+func TestWorkerPoolOrdered(t *testing.T) {
+	appWg := &sync.WaitGroup{}
+	appCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	processed := make(map[string][]int)
+	mutex := sync.Mutex{}
+
+	keyExtractor := func(envelope types.MessageEnvelope) string {
+		return envelope.ContentType
+	}
+
+	config := common.WorkerPoolInfo{Workers: 4, QueueSize: 100}
+	pool, err := newWorkerPool(config, keyExtractor, logClient, func(ctx context.Context, message receivedMessage) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		processed[message.ContentType] = append(processed[message.ContentType], int(message.Payload[0]))
+	})
+	require.NoError(t, err)
+	require.True(t, pool.ordered())
+	assert.Equal(t, 4, len(pool.queues))
+	assert.Equal(t, 100, pool.queueSize())
+
+	pool.start(appWg, appCtx)
+
+	keys := []string{"device1", "device2", "device3"}
+	for index := 0; index < 20; index++ {
+		for _, key := range keys {
+			pool.submit(appCtx, receivedMessage{MessageEnvelope: types.MessageEnvelope{ContentType: key, Payload: []byte{byte(index)}}})
+		}
+	}
+
+	pool.stop()
+	appWg.Wait()
+
+	for _, key := range keys {
+		require.Equal(t, 20, len(processed[key]))
+		for index, value := range processed[key] {
+			assert.Equal(t, index, value, "Messages for key %s processed out of order", key)
+		}
+	}
+}
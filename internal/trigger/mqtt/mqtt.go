@@ -0,0 +1,377 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/google/uuid"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/security"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
+)
+
+// metricsName identifies the trigger in the trigger metrics
+const metricsName = "mqtt"
+
+const (
+	defaultTimeout = 30 * time.Second
+	// disconnectQuiesce is how long, in milliseconds, to wait for in-flight work to complete when disconnecting
+	disconnectQuiesce = 250
+
+	// The queue full policies are those of the messagebus trigger's WorkerPool
+	queueFullPolicyBlock      = "block"
+	queueFullPolicyDropOldest = "drop-oldest"
+	queueFullPolicyDropNewest = "drop-newest"
+
+	defaultQueueSize = 100
+)
+
+// Trigger implements Trigger to support messages received from an external MQTT broker
+type Trigger struct {
+	Configuration  *common.ConfigurationStruct
+	Runtime        *runtime.GolangRuntime
+	EdgeXClients   common.EdgeXClients
+	SecretProvider security.SecretProvider
+	client         MQTT.Client
+	topics         []string
+}
+
+// Initialize connects to the broker and subscribes to the Binding's topics. The received messages are processed in
+// the order received and the output data is published to the Binding's PublishTopic on the same broker.
+//
+// The received messages wait to be processed in a queue bounded by the Binding's WorkerPool QueueSize, with its
+// QueueFullPolicy determining what happens to received messages when the queue is full. The client acknowledges
+// QoS 1 and 2 messages once they are queued rather than once they are processed, so the queued messages are lost
+// if the service stops before processing them.
+func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context) (bootstrap.Deferred, error) {
+	logger := trigger.EdgeXClients.LoggingClient
+	brokerConfig := trigger.Configuration.MqttBroker
+
+	logger.Info(fmt.Sprintf("Initializing MQTT Trigger for '%s'", brokerConfig.Url))
+
+	trigger.topics = trigger.Configuration.Binding.SubscribeTopics()
+	if len(trigger.topics) == 0 {
+		return nil, errors.New("mqtt trigger requires at least one Binding SubscribeTopic")
+	}
+
+	// The handler only blocks when the queue is full with the block policy, since paho routes the received messages
+	// and the acknowledgements of the published output data in the same goroutine. Blocking stops the client reading
+	// from the broker, which in turn delays publishing output data with a QoS above 0 until the queue has space.
+	queue, err := newMessageQueue(trigger.Configuration.Binding.WorkerPool, logger)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := trigger.clientOptions(func(client MQTT.Client, message MQTT.Message) {
+		queue.push(appCtx, message)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trigger.client = MQTT.NewClient(opts)
+	if err := waitForToken(trigger.client.Connect(), opts.ConnectTimeout); err != nil {
+		return nil, fmt.Errorf("could not connect to mqtt broker '%s': %w", brokerConfig.Url, err)
+	}
+
+	if len(trigger.Configuration.Binding.PublishTopic) > 0 {
+		logger.Info(fmt.Sprintf("Publishing to topic: '%s' @ %s", trigger.Configuration.Binding.PublishTopic, brokerConfig.Url))
+	}
+	logger.Info(fmt.Sprintf("Processing messages in the order received, queue size of %d and '%s' queue full policy",
+		cap(queue.messages), queue.policy))
+
+	appWg.Add(1)
+	go func() {
+		defer appWg.Done()
+		trigger.processMessages(appCtx, queue)
+	}()
+
+	deferred := func() {
+		logger.Info("Disconnecting from the mqtt broker")
+		trigger.client.Disconnect(disconnectQuiesce)
+	}
+	return deferred, nil
+}
+
+// messageQueue is a bounded FIFO queue of the received messages, so that the client's message handler doesn't wait
+// for the messages to be processed while they are still processed in the order received
+type messageQueue struct {
+	policy   string
+	messages chan MQTT.Message
+	logger   logger.LoggingClient
+}
+
+// newMessageQueue creates the queue with the QueueSize and QueueFullPolicy of the WorkerPool configuration
+func newMessageQueue(config common.WorkerPoolInfo, lc logger.LoggingClient) (*messageQueue, error) {
+	if config.QueueSize < 0 {
+		return nil, errors.New("WorkerPool QueueSize can not be less than 0")
+	}
+
+	policy := strings.ToLower(strings.TrimSpace(config.QueueFullPolicy))
+	switch policy {
+	case "":
+		policy = queueFullPolicyBlock
+	case queueFullPolicyBlock, queueFullPolicyDropOldest, queueFullPolicyDropNewest:
+	default:
+		return nil, fmt.Errorf("WorkerPool QueueFullPolicy '%s' is invalid, must be one of %s, %s or %s",
+			config.QueueFullPolicy, queueFullPolicyBlock, queueFullPolicyDropOldest, queueFullPolicyDropNewest)
+	}
+
+	queueSize := config.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultQueueSize
+	}
+
+	return &messageQueue{
+		policy:   policy,
+		messages: make(chan MQTT.Message, queueSize),
+		logger:   lc,
+	}, nil
+}
+
+// push adds the message to the end of the queue, applying the queue full policy when the queue is full. Must only
+// be called from the client's message handler.
+func (queue *messageQueue) push(appCtx context.Context, message MQTT.Message) {
+	switch queue.policy {
+	case queueFullPolicyDropNewest:
+		select {
+		case queue.messages <- message:
+		default:
+			queue.logger.Warn("Message queue is full, dropping received message", "topic", message.Topic())
+		}
+
+	case queueFullPolicyDropOldest:
+		for {
+			select {
+			case queue.messages <- message:
+				return
+			default:
+			}
+
+			select {
+			case oldest := <-queue.messages:
+				queue.logger.Warn("Message queue is full, dropping oldest queued message", "topic", oldest.Topic())
+			default:
+			}
+		}
+
+	default:
+		select {
+		case queue.messages <- message:
+		case <-appCtx.Done():
+		}
+	}
+}
+
+// processMessages processes the queued messages one at a time until the service is shutting down
+func (trigger *Trigger) processMessages(appCtx context.Context, queue *messageQueue) {
+	for {
+		select {
+		case <-appCtx.Done():
+			return
+
+		case message := <-queue.messages:
+			if appCtx.Err() != nil {
+				return
+			}
+			telemetry.RecordMessageReceived(metricsName)
+			trigger.processMessage(appCtx, message.Topic(), message.Payload())
+		}
+	}
+}
+
+// clientOptions returns the options to connect to the broker, retrieving the secrets required by the AuthMode from
+// the SecretProvider. The topics are subscribed to each time the client connects, so that the subscriptions are
+// restored when the client reconnects.
+func (trigger *Trigger) clientOptions(handler MQTT.MessageHandler) (*MQTT.ClientOptions, error) {
+	logger := trigger.EdgeXClients.LoggingClient
+	brokerConfig := trigger.Configuration.MqttBroker
+
+	keepAlive, err := parseDuration(brokerConfig.KeepAlive)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mqtt KeepAlive: %w", err)
+	}
+	connectTimeout, err := parseDuration(brokerConfig.ConnectTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mqtt ConnectTimeout: %w", err)
+	}
+
+	opts := MQTT.NewClientOptions()
+	opts.AddBroker(brokerConfig.Url)
+	opts.SetClientID(brokerConfig.ClientId)
+	opts.SetAutoReconnect(brokerConfig.AutoReconnect)
+	opts.SetKeepAlive(keepAlive)
+	opts.SetConnectTimeout(connectTimeout)
+	opts.SetConnectionLostHandler(func(client MQTT.Client, err error) {
+		logger.Warn("Lost connection to the mqtt broker", "error", err.Error(), "reconnect", brokerConfig.AutoReconnect)
+	})
+	opts.SetOnConnectHandler(func(client MQTT.Client) {
+		trigger.subscribe(client, handler)
+	})
+
+	authMode := strings.ToLower(brokerConfig.AuthMode)
+	if len(authMode) == 0 {
+		authMode = transforms.AuthModeNone
+	}
+	var secrets map[string]string
+	if authMode != transforms.AuthModeNone {
+		if trigger.SecretProvider == nil {
+			return nil, errors.New("mqtt AuthMode requires a SecretProvider")
+		}
+		secrets, err = trigger.SecretProvider.GetSecrets(brokerConfig.SecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get mqtt secrets from '%s': %w", brokerConfig.SecretPath, err)
+		}
+	}
+	if err := transforms.ConfigureMQTTClientAuth(opts, authMode, brokerConfig.SkipCertVerify, secrets); err != nil {
+		return nil, fmt.Errorf("unable to configure mqtt auth: %w", err)
+	}
+
+	return opts, nil
+}
+
+// subscribe subscribes to the topics, logging any failure since it is called by the client when connected
+func (trigger *Trigger) subscribe(client MQTT.Client, handler MQTT.MessageHandler) {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	filters := make(map[string]byte, len(trigger.topics))
+	for _, topic := range trigger.topics {
+		filters[topic] = trigger.Configuration.MqttBroker.QoS
+	}
+
+	if err := waitForToken(client.SubscribeMultiple(filters, handler), defaultTimeout); err != nil {
+		logger.Error("Failed to subscribe to mqtt topics", "error", err.Error(), "topics", strings.Join(trigger.topics, ","))
+		return
+	}
+
+	logger.Info(fmt.Sprintf("Subscribed to topics: '%s' @ %s", strings.Join(trigger.topics, ","),
+		trigger.Configuration.MqttBroker.Url))
+}
+
+// processMessage executes the pipeline for the message received on the topic and publishes the output data, if any.
+// MQTT messages carry no correlation ID, so a new one is generated for each message.
+func (trigger *Trigger) processMessage(appCtx context.Context, topic string, payload []byte) {
+	logger := trigger.EdgeXClients.LoggingClient
+	correlationID := uuid.New().String()
+	logger.Trace("Received message from mqtt", "topic", topic, clients.CorrelationHeader, correlationID)
+
+	edgexContext := &appcontext.Context{
+		CorrelationID:         correlationID,
+		ReceivedTopic:         topic,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+	}
+	_ = edgexContext.SetValue(appcontext.ValueKeyReceivedTopic, topic)
+
+	// Pipeline functions are cancelled when the service is shutting down
+	requestCtx := tracing.ContextWithCorrelationID(appCtx, correlationID)
+	requestCtx, span := tracing.StartSpan(requestCtx, "mqtt trigger", tracing.SpanKindConsumer)
+	defer span.End()
+	span.SetAttribute(tracing.AttributeCorrelationID, correlationID)
+	span.SetAttribute(tracing.AttributeTopic, topic)
+	span.SetAttribute(tracing.AttributePayloadSize, len(payload))
+	edgexContext.SetRequestContext(requestCtx)
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: correlationID,
+		ContentType:   trigger.Configuration.MqttBroker.ContentType,
+		Payload:       payload,
+	}
+
+	trigger.Runtime.CaptureMessage(edgexContext, envelope)
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		if messageError.Kind != appcontext.ErrorKindFiltered {
+			span.SetError(messageError.Err)
+		}
+		trigger.Runtime.DeadLetterMessage(edgexContext, envelope, messageError)
+		return
+	}
+
+	for _, output := range edgexContext.Outputs() {
+		trigger.publish(requestCtx, edgexContext, output)
+	}
+}
+
+// publish publishes the output data to the output's topic, defaulting to the Binding PublishTopic. The content type
+// isn't published since MQTT 3.1.1 messages have no headers to carry it. Failures are logged so that the remaining
+// outputs are still published.
+func (trigger *Trigger) publish(requestCtx context.Context, edgexContext *appcontext.Context, output appcontext.Output) {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	publishTopic := output.Topic
+	if len(publishTopic) == 0 {
+		publishTopic = trigger.Configuration.Binding.PublishTopic
+	}
+	if len(publishTopic) == 0 {
+		logger.Debug("No topic to publish the output data to", clients.CorrelationHeader, edgexContext.CorrelationID)
+		return
+	}
+
+	_, publishSpan := tracing.StartSpan(requestCtx, "mqtt publish", tracing.SpanKindProducer)
+	publishSpan.SetAttribute(tracing.AttributeTopic, publishTopic)
+	publishSpan.SetAttribute(tracing.AttributePayloadSize, len(output.Data))
+	brokerConfig := trigger.Configuration.MqttBroker
+	err := waitForToken(trigger.client.Publish(publishTopic, brokerConfig.QoS, brokerConfig.Retain, output.Data), defaultTimeout)
+	publishSpan.SetError(err)
+	publishSpan.End()
+	if err != nil {
+		logger.Error(fmt.Sprintf("Failed to publish message to mqtt broker, %v", err), "topic", publishTopic)
+		return
+	}
+
+	telemetry.RecordMessagePublished(metricsName)
+	logger.Trace("Published message to mqtt broker", "topic", publishTopic, clients.CorrelationHeader, edgexContext.CorrelationID)
+}
+
+// waitForToken waits for the client operation to complete, returning its error or an error if it times out
+func waitForToken(token MQTT.Token, timeout time.Duration) error {
+	if !token.WaitTimeout(timeout) {
+		return errors.New("timed out waiting for the mqtt broker")
+	}
+
+	return token.Error()
+}
+
+// parseDuration parses the duration, returning the default timeout when not set
+func parseDuration(value string) (time.Duration, error) {
+	if len(value) == 0 {
+		return defaultTimeout, nil
+	}
+
+	return time.ParseDuration(value)
+}
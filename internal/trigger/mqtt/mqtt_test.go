@@ -0,0 +1,294 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package mqtt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	MQTT "github.com/eclipse/paho.mqtt.golang"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/security"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
+)
+
+type completedToken struct {
+	MQTT.Token
+	err error
+}
+
+func (token completedToken) Wait() bool                     { return true }
+func (token completedToken) WaitTimeout(time.Duration) bool { return true }
+func (token completedToken) Error() error                   { return token.err }
+
+type published struct {
+	topic   string
+	payload []byte
+}
+
+// fakeClient records the published messages and subscribed topics
+type fakeClient struct {
+	MQTT.Client
+	published  []published
+	subscribed map[string]byte
+	err        error
+}
+
+func (client *fakeClient) Publish(topic string, qos byte, retained bool, payload interface{}) MQTT.Token {
+	client.published = append(client.published, published{topic: topic, payload: payload.([]byte)})
+	return completedToken{err: client.err}
+}
+
+func (client *fakeClient) SubscribeMultiple(filters map[string]byte, callback MQTT.MessageHandler) MQTT.Token {
+	client.subscribed = filters
+	return completedToken{err: client.err}
+}
+
+type fakeSecretProvider struct {
+	security.SecretProvider
+	secrets map[string]string
+}
+
+func (provider fakeSecretProvider) GetSecrets(path string, keys ...string) (map[string]string, error) {
+	if provider.secrets == nil {
+		return nil, errors.New("no secrets")
+	}
+	return provider.secrets, nil
+}
+
+func newTestTrigger(transforms ...appcontext.AppFunction) (*Trigger, *fakeClient) {
+	testRuntime := &runtime.GolangRuntime{}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms(transforms)
+
+	config := &common.ConfigurationStruct{}
+	config.Binding.SubscribeTopic = "edgex/events/#, sensors/+/temperature,"
+	config.Binding.PublishTopic = "export"
+	config.MqttBroker.QoS = 1
+
+	client := &fakeClient{}
+	trigger := &Trigger{
+		Configuration: config,
+		Runtime:       testRuntime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+		client:        client,
+		topics:        config.Binding.SubscribeTopics(),
+	}
+
+	return trigger, client
+}
+
+func TestProcessMessage(t *testing.T) {
+	var receivedTopic, topicValue, correlationID string
+	var received models.Event
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		receivedTopic = edgexcontext.ReceivedTopic
+		topicValue, _ = edgexcontext.StringValue(appcontext.ValueKeyReceivedTopic)
+		correlationID = edgexcontext.CorrelationID
+		received = params[0].(models.Event)
+		edgexcontext.Complete([]byte("output"))
+		edgexcontext.AddOutput(appcontext.Output{Data: []byte("alert"), Topic: "alerts"})
+		return false, nil
+	}
+
+	trigger, client := newTestTrigger(transform)
+	trigger.processMessage(context.Background(), "edgex/events/thermostat", []byte(`{"device":"thermostat"}`))
+
+	assert.Equal(t, "thermostat", received.Device, "Payload should have been sniffed as JSON")
+	assert.Equal(t, "edgex/events/thermostat", receivedTopic)
+	assert.Equal(t, "edgex/events/thermostat", topicValue)
+	assert.NotEmpty(t, correlationID)
+	assert.Equal(t, []published{{"export", []byte("output")}, {"alerts", []byte("alert")}}, client.published)
+}
+
+func TestProcessMessageNoPublishTopic(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		edgexcontext.Complete([]byte("output"))
+		return false, nil
+	}
+
+	trigger, client := newTestTrigger(transform)
+	trigger.Configuration.Binding.PublishTopic = ""
+	trigger.processMessage(context.Background(), "edgex/events/thermostat", []byte(`{"device":"thermostat"}`))
+
+	assert.Empty(t, client.published)
+}
+
+// fakeMessage is a message received from the broker
+type fakeMessage struct {
+	MQTT.Message
+	topic   string
+	payload []byte
+}
+
+func (message fakeMessage) Topic() string   { return message.topic }
+func (message fakeMessage) Payload() []byte { return message.payload }
+
+func TestProcessMessagesInOrder(t *testing.T) {
+	var received []string
+	done := make(chan struct{})
+	release := make(chan struct{})
+	trigger, _ := newTestTrigger(func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		<-release
+		received = append(received, string(params[0].([]byte)))
+		if len(received) == 3 {
+			close(done)
+		}
+		return false, nil
+	})
+	trigger.Runtime.TargetType = &[]byte{}
+
+	appCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	queue, err := newMessageQueue(common.WorkerPoolInfo{}, logger.NewMockClient())
+	require.NoError(t, err)
+	go trigger.processMessages(appCtx, queue)
+
+	// The messages are queued without blocking while the first one is being processed
+	for _, payload := range []string{"one", "two", "three"} {
+		queue.push(appCtx, fakeMessage{topic: "sensors", payload: []byte(payload)})
+	}
+	close(release)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Queued messages were not processed")
+	}
+	assert.Equal(t, []string{"one", "two", "three"}, received)
+}
+
+func TestMessageQueueFullPolicy(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Policy   string
+		Expected []string
+	}{
+		{"Drop newest", "drop-newest", []string{"one", "two"}},
+		{"Drop oldest", "Drop-Oldest", []string{"two", "three"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			config := common.WorkerPoolInfo{QueueSize: 2, QueueFullPolicy: test.Policy}
+			queue, err := newMessageQueue(config, logger.NewMockClient())
+			require.NoError(t, err)
+
+			for _, payload := range []string{"one", "two", "three"} {
+				queue.push(context.Background(), fakeMessage{topic: "sensors", payload: []byte(payload)})
+			}
+
+			var queued []string
+			for len(queue.messages) > 0 {
+				queued = append(queued, string((<-queue.messages).Payload()))
+			}
+			assert.Equal(t, test.Expected, queued)
+		})
+	}
+}
+
+func TestMessageQueueBlockCancelled(t *testing.T) {
+	queue, err := newMessageQueue(common.WorkerPoolInfo{QueueSize: 1}, logger.NewMockClient())
+	require.NoError(t, err)
+
+	appCtx, cancel := context.WithCancel(context.Background())
+	queue.push(appCtx, fakeMessage{topic: "sensors", payload: []byte("one")})
+
+	pushed := make(chan struct{})
+	go func() {
+		queue.push(appCtx, fakeMessage{topic: "sensors", payload: []byte("two")})
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		require.Fail(t, "Expected push to block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-pushed:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Expected push to return once the service is shutting down")
+	}
+}
+
+func TestNewMessageQueueInvalid(t *testing.T) {
+	_, err := newMessageQueue(common.WorkerPoolInfo{QueueSize: -1}, logger.NewMockClient())
+	assert.Error(t, err)
+
+	_, err = newMessageQueue(common.WorkerPoolInfo{QueueFullPolicy: "wait"}, logger.NewMockClient())
+	assert.Error(t, err)
+}
+
+func TestSubscribe(t *testing.T) {
+	trigger, client := newTestTrigger()
+
+	trigger.subscribe(client, nil)
+
+	assert.Equal(t, map[string]byte{"edgex/events/#": 1, "sensors/+/temperature": 1}, client.subscribed)
+}
+
+func TestClientOptions(t *testing.T) {
+	tests := []struct {
+		Name           string
+		AuthMode       string
+		Secrets        map[string]string
+		KeepAlive      string
+		ExpectError    bool
+		ExpectUsername string
+	}{
+		{"No auth", "", nil, "", false, ""},
+		{"Username password", transforms.AuthModeUsernamePassword,
+			map[string]string{transforms.MQTTSecretUsername: "user", transforms.MQTTSecretPassword: "pass"}, "", false, "user"},
+		{"Missing password", transforms.AuthModeUsernamePassword,
+			map[string]string{transforms.MQTTSecretUsername: "user"}, "", true, ""},
+		{"No secrets", transforms.AuthModeCert, nil, "", true, ""},
+		{"Invalid auth mode", "token", map[string]string{}, "", true, ""},
+		{"Invalid keep alive", "", nil, "often", true, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			trigger, _ := newTestTrigger()
+			trigger.Configuration.MqttBroker.Url = "tcp://localhost:1883"
+			trigger.Configuration.MqttBroker.AuthMode = test.AuthMode
+			trigger.Configuration.MqttBroker.KeepAlive = test.KeepAlive
+			trigger.SecretProvider = fakeSecretProvider{secrets: test.Secrets}
+
+			opts, err := trigger.clientOptions(nil)
+			if test.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.ExpectUsername, opts.Username)
+			assert.Equal(t, defaultTimeout, opts.ConnectTimeout)
+		})
+	}
+}
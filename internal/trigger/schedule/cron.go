@@ -0,0 +1,214 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronDescriptors are the shorthands supported in place of the five fields
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronField is the range of values of a field and, optionally, the names which can be used for them
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12,
+		names: []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// maxCronYears bounds the search for the next time, since some expressions, i.e. '0 0 30 2 *', never match
+const maxCronYears = 5
+
+// cronSchedule fires at the times matching a cron expression with the minute, hour, day of month, month and day of
+// week fields. Fields support '*', lists, ranges and steps, i.e. '*/15 8-18 * * mon-fri'. As with cron, a time matches
+// when either the day of month or the day of week matches if both are restricted.
+type cronSchedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+	// anyDay is true when either of the day fields is '*', in which case both must match
+	anyDay   bool
+	location *time.Location
+}
+
+// parseCron parses the cron expression, evaluated in the location
+func parseCron(expression string, location *time.Location) (*cronSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expression)]; ok {
+		expression = descriptor
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression '%s' must have %d fields", expression, len(cronFields))
+	}
+
+	var bits [5]uint64
+	for index, field := range fields {
+		var err error
+		if bits[index], err = cronFields[index].parse(field); err != nil {
+			return nil, fmt.Errorf("invalid cron expression '%s': %w", expression, err)
+		}
+	}
+
+	// Sunday can be either 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	return &cronSchedule{
+		minutes:     bits[0],
+		hours:       bits[1],
+		daysOfMonth: bits[2],
+		months:      bits[3],
+		daysOfWeek:  bits[4],
+		anyDay:      fields[2] == "*" || fields[4] == "*",
+		location:    location,
+	}, nil
+}
+
+// parse returns the bits set for the values of the comma separated list of ranges
+func (field cronField) parse(value string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(value, ",") {
+		rangeBits, err := field.parseRange(part)
+		if err != nil {
+			return 0, err
+		}
+		bits |= rangeBits
+	}
+
+	return bits, nil
+}
+
+// parseRange parses '*', a value or a range, each optionally followed by a step, i.e. '*/5' or '1-10/2'
+func (field cronField) parseRange(value string) (uint64, error) {
+	step := 1
+	if slash := strings.Index(value, "/"); slash >= 0 {
+		parsed, err := strconv.Atoi(value[slash+1:])
+		if err != nil || parsed <= 0 {
+			return 0, fmt.Errorf("invalid %s step in '%s'", field.name, value)
+		}
+		step = parsed
+		value = value[:slash]
+	}
+
+	low, high := field.min, field.max
+	if value != "*" {
+		bounds := strings.SplitN(value, "-", 2)
+		var err error
+		if low, err = field.parseValue(bounds[0]); err != nil {
+			return 0, err
+		}
+		high = low
+		if len(bounds) == 2 {
+			if high, err = field.parseValue(bounds[1]); err != nil {
+				return 0, err
+			}
+		} else if step > 1 {
+			// A value with a step, i.e. '5/15', ranges to the maximum
+			high = field.max
+		}
+		if low > high {
+			return 0, fmt.Errorf("invalid %s range '%s'", field.name, value)
+		}
+	}
+
+	var bits uint64
+	for current := low; current <= high; current += step {
+		bits |= 1 << uint(current)
+	}
+	return bits, nil
+}
+
+// parseValue parses a number or name within the field's range
+func (field cronField) parseValue(value string) (int, error) {
+	for index, name := range field.names {
+		if strings.EqualFold(value, name) {
+			return index + field.min, nil
+		}
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < field.min || parsed > field.max {
+		return 0, fmt.Errorf("invalid %s '%s', must be between %d and %d", field.name, value, field.min, field.max)
+	}
+	return parsed, nil
+}
+
+// next returns the first time after the specified time which matches the expression
+func (schedule *cronSchedule) next(after time.Time) (time.Time, error) {
+	current := after.In(schedule.location).Truncate(time.Minute).Add(time.Minute)
+	limit := current.AddDate(maxCronYears, 0, 0)
+
+	for current.Before(limit) {
+		if schedule.months&(1<<uint(current.Month())) == 0 {
+			current = time.Date(current.Year(), current.Month()+1, 1, 0, 0, 0, 0, schedule.location)
+			continue
+		}
+		if !schedule.matchesDay(current) {
+			current = time.Date(current.Year(), current.Month(), current.Day()+1, 0, 0, 0, 0, schedule.location)
+			continue
+		}
+		if schedule.hours&(1<<uint(current.Hour())) == 0 {
+			current = time.Date(current.Year(), current.Month(), current.Day(), current.Hour()+1, 0, 0, 0,
+				schedule.location)
+			continue
+		}
+		if schedule.minutes&(1<<uint(current.Minute())) == 0 {
+			current = current.Add(time.Minute)
+			continue
+		}
+
+		return current, nil
+	}
+
+	return time.Time{}, fmt.Errorf("cron expression has no time within the next %d years", maxCronYears)
+}
+
+func (schedule *cronSchedule) matchesDay(current time.Time) bool {
+	dayOfMonth := schedule.daysOfMonth&(1<<uint(current.Day())) != 0
+	dayOfWeek := schedule.daysOfWeek&(1<<uint(current.Weekday())) != 0
+	if schedule.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
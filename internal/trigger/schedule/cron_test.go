@@ -0,0 +1,92 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	start := time.Date(2020, time.July, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		Name       string
+		Expression string
+		Expected   time.Time
+	}{
+		{"Every minute", "* * * * *", time.Date(2020, time.July, 15, 10, 8, 0, 0, time.UTC)},
+		{"Step", "*/15 * * * *", time.Date(2020, time.July, 15, 10, 15, 0, 0, time.UTC)},
+		{"List", "5,50 * * * *", time.Date(2020, time.July, 15, 10, 50, 0, 0, time.UTC)},
+		{"Next hour", "5 * * * *", time.Date(2020, time.July, 15, 11, 5, 0, 0, time.UTC)},
+		{"Range", "0 8-9 * * *", time.Date(2020, time.July, 16, 8, 0, 0, 0, time.UTC)},
+		{"Day of week names", "0 9 * * mon-fri", time.Date(2020, time.July, 16, 9, 0, 0, 0, time.UTC)},
+		{"Sunday as 7", "0 0 * * 7", time.Date(2020, time.July, 19, 0, 0, 0, 0, time.UTC)},
+		{"Month name", "0 0 1 Jan *", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"Day of month or week", "0 0 1 * sat", time.Date(2020, time.July, 18, 0, 0, 0, 0, time.UTC)},
+		{"Leap day", "0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"Descriptor", "@hourly", time.Date(2020, time.July, 15, 11, 0, 0, 0, time.UTC)},
+		{"Value with step", "10/20 * * * *", time.Date(2020, time.July, 15, 10, 10, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			cron, err := parseCron(test.Expression, time.UTC)
+			require.NoError(t, err)
+
+			next, err := cron.next(start)
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, next)
+		})
+	}
+}
+
+func TestCronNextTimeZone(t *testing.T) {
+	location, err := time.LoadLocation("America/Chicago")
+	require.NoError(t, err)
+
+	cron, err := parseCron("0 6 * * *", location)
+	require.NoError(t, err)
+
+	next, err := cron.next(time.Date(2020, time.July, 15, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2020, time.July, 15, 11, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func TestCronNever(t *testing.T) {
+	cron, err := parseCron("0 0 30 2 *", time.UTC)
+	require.NoError(t, err)
+
+	_, err = cron.next(time.Now())
+	assert.Error(t, err)
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	expressions := []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+		"* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *", "* * * foo *", "1-2-3 * * * *"}
+
+	for _, expression := range expressions {
+		t.Run(expression, func(t *testing.T) {
+			_, err := parseCron(expression, time.UTC)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,288 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-bootstrap/bootstrap"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/google/uuid"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/internal/telemetry"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+// metricsName identifies the trigger in the trigger metrics
+const metricsName = "schedule"
+
+// Policies for firings of the schedule while the previous execution is running
+const (
+	OverlapPolicySkip  = "skip"
+	OverlapPolicyAllow = "allow"
+)
+
+// Policies for the firings missed while an execution was running
+const (
+	MissedRunPolicySkip    = "skip"
+	MissedRunPolicyRunOnce = "run-once"
+)
+
+// schedule returns the time the trigger fires next after the specified time
+type schedule interface {
+	next(after time.Time) (time.Time, error)
+}
+
+// intervalSchedule fires at a fixed interval
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+func (schedule intervalSchedule) next(after time.Time) (time.Time, error) {
+	return after.Add(schedule.interval), nil
+}
+
+// Trigger implements Trigger to support executing the pipeline on a timer, i.e. to poll a REST API, emit heartbeats
+// or flush aggregates. Each execution is passed the configured Payload. Since there is nothing to return output data
+// to, it is discarded.
+type Trigger struct {
+	Configuration   *common.ConfigurationStruct
+	Runtime         *runtime.GolangRuntime
+	EdgeXClients    common.EdgeXClients
+	schedule        schedule
+	overlapPolicy   string
+	missedRunPolicy string
+	lock            sync.Mutex
+	running         int
+	missed          bool
+}
+
+// Initialize parses the schedule and starts firing it until the service is shutting down
+func (trigger *Trigger) Initialize(appWg *sync.WaitGroup, appCtx context.Context) (bootstrap.Deferred, error) {
+	logger := trigger.EdgeXClients.LoggingClient
+
+	logger.Info("Initializing Schedule Trigger")
+
+	if err := trigger.configure(trigger.Configuration.Binding.Schedule); err != nil {
+		return nil, err
+	}
+
+	// Empty ticks can only be passed to a pipeline taking the raw bytes, otherwise every execution would fail to
+	// decode the TargetType
+	_, isBytes := trigger.Runtime.TargetType.(*[]byte)
+	if !isBytes && len(trigger.Configuration.Binding.Schedule.Payload) == 0 {
+		return nil, errors.New("schedule trigger requires a Payload unless the TargetType is []byte")
+	}
+
+	next, err := trigger.schedule.next(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("Schedule Trigger first firing at %s with '%s' overlap and '%s' missed run policies",
+		next.Format(time.RFC3339), trigger.overlapPolicy, trigger.missedRunPolicy))
+
+	appWg.Add(1)
+	go func() {
+		defer appWg.Done()
+
+		for {
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-appCtx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+
+			trigger.fire(appWg, appCtx, next)
+
+			// Firings missed while the service wasn't running, i.e. the host was suspended, are collapsed into
+			// the one which just fired
+			if next, err = trigger.schedule.next(time.Now()); err != nil {
+				logger.Error("Schedule Trigger stopped", "error", err.Error())
+				return
+			}
+		}
+	}()
+
+	return nil, nil
+}
+
+// configure parses the schedule and validates the policies
+func (trigger *Trigger) configure(scheduleConfig common.ScheduleInfo) error {
+	switch {
+	case len(scheduleConfig.Interval) > 0 && len(scheduleConfig.Cron) > 0:
+		return errors.New("schedule trigger requires either an Interval or a Cron expression, not both")
+
+	case len(scheduleConfig.Interval) > 0:
+		interval, err := time.ParseDuration(scheduleConfig.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid schedule Interval: %w", err)
+		}
+		if interval <= 0 {
+			return errors.New("schedule Interval must be greater than zero")
+		}
+		trigger.schedule = intervalSchedule{interval: interval}
+
+	case len(scheduleConfig.Cron) > 0:
+		location := time.Local
+		if len(scheduleConfig.TimeZone) > 0 {
+			var err error
+			if location, err = time.LoadLocation(scheduleConfig.TimeZone); err != nil {
+				return fmt.Errorf("invalid schedule TimeZone: %w", err)
+			}
+		}
+		cron, err := parseCron(scheduleConfig.Cron, location)
+		if err != nil {
+			return err
+		}
+		trigger.schedule = cron
+
+	default:
+		return errors.New("schedule trigger requires an Interval or a Cron expression")
+	}
+
+	trigger.overlapPolicy = strings.ToLower(scheduleConfig.OverlapPolicy)
+	switch trigger.overlapPolicy {
+	case "":
+		trigger.overlapPolicy = OverlapPolicySkip
+	case OverlapPolicySkip, OverlapPolicyAllow:
+	default:
+		return fmt.Errorf("schedule OverlapPolicy '%s' is not supported", scheduleConfig.OverlapPolicy)
+	}
+
+	trigger.missedRunPolicy = strings.ToLower(scheduleConfig.MissedRunPolicy)
+	switch trigger.missedRunPolicy {
+	case "":
+		trigger.missedRunPolicy = MissedRunPolicySkip
+	case MissedRunPolicySkip, MissedRunPolicyRunOnce:
+	default:
+		return fmt.Errorf("schedule MissedRunPolicy '%s' is not supported", scheduleConfig.MissedRunPolicy)
+	}
+
+	return nil
+}
+
+// fire starts an execution for the scheduled time unless the previous execution is still running and overlapping
+// executions aren't allowed, in which case the firing is missed
+func (trigger *Trigger) fire(appWg *sync.WaitGroup, appCtx context.Context, scheduled time.Time) {
+	trigger.lock.Lock()
+	if trigger.running > 0 && trigger.overlapPolicy == OverlapPolicySkip {
+		trigger.missed = true
+		trigger.lock.Unlock()
+		trigger.EdgeXClients.LoggingClient.Debug("Schedule fired while the previous execution is running, skipping",
+			"scheduled", scheduled.Format(time.RFC3339))
+		return
+	}
+	trigger.running++
+	trigger.lock.Unlock()
+
+	appWg.Add(1)
+	go func() {
+		defer appWg.Done()
+
+		for {
+			trigger.execute(appCtx, scheduled)
+			if !trigger.completed(appCtx) {
+				return
+			}
+
+			trigger.EdgeXClients.LoggingClient.Debug("Executing once for the firings missed by the previous execution")
+			scheduled = time.Now()
+		}
+	}()
+}
+
+// completed records the completion of an execution and returns true if another is to be executed immediately for
+// the firings missed while it was running
+func (trigger *Trigger) completed(appCtx context.Context) bool {
+	trigger.lock.Lock()
+	defer trigger.lock.Unlock()
+
+	trigger.running--
+	if trigger.running > 0 || !trigger.missed {
+		return false
+	}
+
+	trigger.missed = false
+	if trigger.missedRunPolicy != MissedRunPolicyRunOnce || appCtx.Err() != nil {
+		return false
+	}
+
+	trigger.running++
+	return true
+}
+
+// execute executes the pipeline with the configured Payload. The scheduled time is set as a value on the context.
+func (trigger *Trigger) execute(appCtx context.Context, scheduled time.Time) {
+	logger := trigger.EdgeXClients.LoggingClient
+	scheduleConfig := trigger.Configuration.Binding.Schedule
+	correlationID := uuid.New().String()
+	logger.Trace("Schedule fired", "scheduled", scheduled.Format(time.RFC3339), clients.CorrelationHeader, correlationID)
+
+	telemetry.RecordMessageReceived(metricsName)
+
+	edgexContext := &appcontext.Context{
+		CorrelationID:         correlationID,
+		Configuration:         trigger.Configuration,
+		LoggingClient:         trigger.EdgeXClients.LoggingClient,
+		EventClient:           trigger.EdgeXClients.EventClient,
+		ValueDescriptorClient: trigger.EdgeXClients.ValueDescriptorClient,
+		CommandClient:         trigger.EdgeXClients.CommandClient,
+		NotificationsClient:   trigger.EdgeXClients.NotificationsClient,
+	}
+	_ = edgexContext.SetValue(appcontext.ValueKeyScheduledTime, scheduled.Format(time.RFC3339))
+
+	// Pipeline functions are cancelled when the service is shutting down
+	requestCtx := tracing.ContextWithCorrelationID(appCtx, correlationID)
+	requestCtx, span := tracing.StartSpan(requestCtx, "schedule trigger", tracing.SpanKindInternal)
+	defer span.End()
+	span.SetAttribute(tracing.AttributeCorrelationID, correlationID)
+	span.SetAttribute(tracing.AttributePayloadSize, len(scheduleConfig.Payload))
+	edgexContext.SetRequestContext(requestCtx)
+
+	envelope := types.MessageEnvelope{
+		CorrelationID: correlationID,
+		ContentType:   scheduleConfig.ContentType,
+		Payload:       []byte(scheduleConfig.Payload),
+	}
+
+	trigger.Runtime.CaptureMessage(edgexContext, envelope)
+	messageError := trigger.Runtime.ProcessMessage(edgexContext, envelope)
+	if messageError != nil {
+		// ProcessMessage logs the error, so no need to log it here.
+		if messageError.Kind != appcontext.ErrorKindFiltered {
+			span.SetError(messageError.Err)
+		}
+		trigger.Runtime.DeadLetterMessage(edgexContext, envelope, messageError)
+		return
+	}
+
+	if outputs := edgexContext.Outputs(); len(outputs) > 0 {
+		logger.Debug(fmt.Sprintf("Discarding %d output messages since the schedule trigger has no destination",
+			len(outputs)), clients.CorrelationHeader, correlationID)
+	}
+}
@@ -0,0 +1,217 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+// recordingPublisher records the topics of the published dead letters
+type recordingPublisher struct {
+	topics []string
+}
+
+func (publisher *recordingPublisher) Connect() error {
+	return nil
+}
+
+func (publisher *recordingPublisher) Publish(message types.MessageEnvelope, topic string) error {
+	publisher.topics = append(publisher.topics, topic)
+	return nil
+}
+
+func (publisher *recordingPublisher) Subscribe(topics []types.TopicChannel, messageErrors chan error) error {
+	return nil
+}
+
+func (publisher *recordingPublisher) Disconnect() error {
+	return nil
+}
+
+func newTestTrigger(t *testing.T, scheduleConfig common.ScheduleInfo, transform appcontext.AppFunction) *Trigger {
+	testRuntime := &runtime.GolangRuntime{TargetType: &[]byte{}}
+	testRuntime.Initialize(nil, nil)
+	testRuntime.SetTransforms([]appcontext.AppFunction{transform})
+
+	config := &common.ConfigurationStruct{}
+	config.Binding.Schedule = scheduleConfig
+
+	trigger := &Trigger{
+		Configuration: config,
+		Runtime:       testRuntime,
+		EdgeXClients:  common.EdgeXClients{LoggingClient: logger.NewMockClient()},
+	}
+	require.NoError(t, trigger.configure(scheduleConfig))
+
+	return trigger
+}
+
+func TestConfigure(t *testing.T) {
+	tests := []struct {
+		Name        string
+		Config      common.ScheduleInfo
+		ExpectError bool
+	}{
+		{"Interval", common.ScheduleInfo{Interval: "30s"}, false},
+		{"Cron", common.ScheduleInfo{Cron: "*/5 * * * *", TimeZone: "UTC", OverlapPolicy: "Allow",
+			MissedRunPolicy: "run-once"}, false},
+		{"Neither", common.ScheduleInfo{}, true},
+		{"Both", common.ScheduleInfo{Interval: "30s", Cron: "* * * * *"}, true},
+		{"Invalid interval", common.ScheduleInfo{Interval: "often"}, true},
+		{"Zero interval", common.ScheduleInfo{Interval: "0s"}, true},
+		{"Invalid cron", common.ScheduleInfo{Cron: "* * *"}, true},
+		{"Invalid time zone", common.ScheduleInfo{Cron: "* * * * *", TimeZone: "Mars/Olympus"}, true},
+		{"Invalid overlap policy", common.ScheduleInfo{Interval: "30s", OverlapPolicy: "queue"}, true},
+		{"Invalid missed run policy", common.ScheduleInfo{Interval: "30s", MissedRunPolicy: "run-all"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			trigger := &Trigger{}
+			err := trigger.configure(test.Config)
+			if test.ExpectError {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.NotNil(t, trigger.schedule)
+		})
+	}
+}
+
+func TestExecute(t *testing.T) {
+	var payload, scheduled string
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		payload = string(params[0].([]byte))
+		scheduled, _ = edgexcontext.StringValue(appcontext.ValueKeyScheduledTime)
+		return false, nil
+	}
+
+	trigger := newTestTrigger(t, common.ScheduleInfo{Interval: "1m", Payload: "tick"}, transform)
+	scheduledTime := time.Date(2020, time.July, 15, 10, 0, 0, 0, time.UTC)
+	trigger.execute(context.Background(), scheduledTime)
+
+	assert.Equal(t, "tick", payload)
+	assert.Equal(t, "2020-07-15T10:00:00Z", scheduled)
+}
+
+func TestInitializeEmptyPayload(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, nil
+	}
+
+	appCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// An empty tick can't be decoded into the default Event TargetType
+	trigger := newTestTrigger(t, common.ScheduleInfo{Interval: "1m"}, transform)
+	trigger.Runtime.TargetType = nil
+	_, err := trigger.Initialize(&sync.WaitGroup{}, appCtx)
+	assert.Error(t, err)
+
+	trigger.Configuration.Binding.Schedule.Payload = `{"device":"heartbeat"}`
+	_, err = trigger.Initialize(&sync.WaitGroup{}, appCtx)
+	assert.NoError(t, err)
+
+	// But can be passed to a pipeline taking the raw bytes
+	trigger = newTestTrigger(t, common.ScheduleInfo{Interval: "1m"}, transform)
+	_, err = trigger.Initialize(&sync.WaitGroup{}, appCtx)
+	assert.NoError(t, err)
+}
+
+func TestExecuteDeadLetter(t *testing.T) {
+	transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		return false, errors.New("failed")
+	}
+
+	trigger := newTestTrigger(t, common.ScheduleInfo{Interval: "1m", Payload: "tick"}, transform)
+	publisher := &recordingPublisher{}
+	trigger.Configuration.DeadLetter = common.DeadLetterInfo{Enabled: true, Target: runtime.DeadLetterTargetMessageBus,
+		Topic: "deadletters"}
+	trigger.Runtime.SetDeadLetterPublisher(publisher)
+
+	trigger.execute(context.Background(), time.Now())
+
+	require.Len(t, publisher.topics, 1)
+	assert.Equal(t, "deadletters", publisher.topics[0])
+}
+
+func TestFirePolicies(t *testing.T) {
+	tests := []struct {
+		Name            string
+		OverlapPolicy   string
+		MissedRunPolicy string
+		Expected        int
+	}{
+		{"Skip", OverlapPolicySkip, MissedRunPolicySkip, 1},
+		{"Run once", OverlapPolicySkip, MissedRunPolicyRunOnce, 2},
+		{"Allow", OverlapPolicyAllow, MissedRunPolicySkip, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			release := make(chan struct{})
+			var lock sync.Mutex
+			executions := 0
+			transform := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+				lock.Lock()
+				executions++
+				first := executions == 1
+				lock.Unlock()
+				if first {
+					<-release
+				}
+				return false, nil
+			}
+
+			trigger := newTestTrigger(t, common.ScheduleInfo{Interval: "1m", OverlapPolicy: test.OverlapPolicy,
+				MissedRunPolicy: test.MissedRunPolicy}, transform)
+
+			wg := &sync.WaitGroup{}
+			trigger.fire(wg, context.Background(), time.Now())
+			require.Eventually(t, func() bool {
+				lock.Lock()
+				defer lock.Unlock()
+				return executions == 1
+			}, time.Second, time.Millisecond)
+
+			// Both fire while the first execution is running
+			trigger.fire(wg, context.Background(), time.Now())
+			trigger.fire(wg, context.Background(), time.Now())
+			close(release)
+			wg.Wait()
+
+			assert.Equal(t, test.Expected, executions)
+			assert.Equal(t, 0, trigger.running)
+			assert.False(t, trigger.missed)
+		})
+	}
+}
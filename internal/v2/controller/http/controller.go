@@ -128,12 +128,12 @@ func (v2c *V2HttpController) Secrets(writer http.ResponseWriter, request *http.R
 
 	if err := v2c.secretProvider.StoreSecrets(path, secrets); err != nil {
 		msg := fmt.Sprintf("Storing secrets failed: %v", err)
-		response := common.NewBaseResponse(secretRequest.RequestID, msg, http.StatusInternalServerError)
+		response := common.NewBaseResponse(secretRequest.RequestId, msg, http.StatusInternalServerError)
 		v2c.sendResponse(writer, request, internal.ApiV2SecretsRoute, response, http.StatusInternalServerError)
 		return
 	}
 
-	response := common.NewBaseResponseNoMessage(secretRequest.RequestID, http.StatusCreated)
+	response := common.NewBaseResponse(secretRequest.RequestId, "", http.StatusCreated)
 	v2c.sendResponse(writer, request, internal.ApiV2SecretsRoute, response, http.StatusCreated)
 }
 
@@ -36,10 +36,10 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/edgexfoundry/app-functions-sdk-go/internal"
-	sdkCommon "github.com/edgexfoundry/app-functions-sdk-go/internal/common"
-	"github.com/edgexfoundry/app-functions-sdk-go/internal/security"
-	"github.com/edgexfoundry/app-functions-sdk-go/internal/v2/dtos/requests"
+	"github.com/student3671/app-functions-sdk-go/internal"
+	sdkCommon "github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/security"
+	"github.com/student3671/app-functions-sdk-go/internal/v2/dtos/requests"
 )
 
 var expectedCorrelationId = uuid.New().String()
@@ -127,13 +127,13 @@ func TestMetricsRequest(t *testing.T) {
 	require.NoError(t, err)
 
 	assert.Equal(t, contractsV2.ApiVersion, actual.ApiVersion)
-	assert.NotZero(t, actual.MemAlloc)
-	assert.NotZero(t, actual.MemFrees)
-	assert.NotZero(t, actual.MemLiveObjects)
-	assert.NotZero(t, actual.MemMallocs)
-	assert.NotZero(t, actual.MemSys)
-	assert.NotZero(t, actual.MemTotalAlloc)
-	assert.NotNil(t, actual.CpuBusyAvg)
+	assert.NotZero(t, actual.Metrics.MemAlloc)
+	assert.NotZero(t, actual.Metrics.MemFrees)
+	assert.NotZero(t, actual.Metrics.MemLiveObjects)
+	assert.NotZero(t, actual.Metrics.MemMallocs)
+	assert.NotZero(t, actual.Metrics.MemSys)
+	assert.NotZero(t, actual.Metrics.MemTotalAlloc)
+	assert.NotNil(t, actual.Metrics.CpuBusyAvg)
 }
 
 func TestConfigRequest(t *testing.T) {
@@ -184,7 +184,7 @@ func TestSecretsRequest(t *testing.T) {
 	assert.NotNil(t, target)
 
 	validRequest := requests.SecretsRequest{
-		BaseRequest: common.BaseRequest{RequestID: expectedRequestId},
+		BaseRequest: common.BaseRequest{RequestId: expectedRequestId},
 		Path:        "mqtt",
 		Secrets: []requests.SecretsKeyValue{
 			{Key: "username", Value: "username"},
@@ -197,7 +197,7 @@ func TestSecretsRequest(t *testing.T) {
 	validPathWithSlash := validRequest
 	validPathWithSlash.Path = "/mqtt"
 	noRequestId := validRequest
-	noRequestId.RequestID = ""
+	noRequestId.RequestId = ""
 	noSecrets := validRequest
 	noSecrets.Secrets = []requests.SecretsKeyValue{}
 	missingSecretKey := validRequest
@@ -260,7 +260,7 @@ func TestSecretsRequest(t *testing.T) {
 				return // Test complete for error cases
 			}
 
-			assert.Equal(t, expectedRequestId, actualResponse.RequestID, "RequestID not as expected")
+			assert.Equal(t, expectedRequestId, actualResponse.RequestId, "RequestID not as expected")
 			assert.Empty(t, actualResponse.Message, "Message not empty, as expected")
 		})
 	}
@@ -30,7 +30,7 @@ const (
 )
 
 var validRequest = SecretsRequest{
-	BaseRequest: common.BaseRequest{RequestID: TestUUID},
+	BaseRequest: common.BaseRequest{RequestId: TestUUID},
 	Path:        "",
 	Secrets: []SecretsKeyValue{
 		{Key: "password", Value: "password"},
@@ -50,7 +50,7 @@ func TestSecretsRequest_Validate(t *testing.T) {
 	validWithPath := validRequest
 	validWithPath.Path = "mqtt"
 	noRequestId := validRequest
-	noRequestId.RequestID = ""
+	noRequestId.RequestId = ""
 	noSecrets := validRequest
 	noSecrets.Secrets = []SecretsKeyValue{}
 	missingSecretKey := validRequest
@@ -0,0 +1,146 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package webserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/student3671/app-functions-sdk-go/internal"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+// DeadLetterManager provides access to the dead letters written to the store
+type DeadLetterManager interface {
+	ListDeadLetters() ([]runtime.DeadLetter, error)
+	ReinjectDeadLetter(id string) error
+	RemoveDeadLetter(id string) error
+}
+
+// SetupDeadLetterRoutes adds the routes to list, re-inject and remove dead letters
+func (webserver *WebServer) SetupDeadLetterRoutes(manager DeadLetterManager) {
+	webserver.LoggingClient.Info("Registering dead letter routes...")
+
+	webserver.router.HandleFunc(internal.ApiDeadLettersRoute, func(writer http.ResponseWriter, _ *http.Request) {
+		webserver.deadLettersHandler(writer, manager)
+	}).Methods(http.MethodGet)
+
+	webserver.router.HandleFunc(internal.ApiDeadLetterReinjectRoute, func(writer http.ResponseWriter, req *http.Request) {
+		webserver.reinjectDeadLetterHandler(writer, req, manager)
+	}).Methods(http.MethodPost)
+
+	webserver.router.HandleFunc(internal.ApiDeadLetterByIdRoute, func(writer http.ResponseWriter, req *http.Request) {
+		webserver.removeDeadLetterHandler(writer, req, manager)
+	}).Methods(http.MethodDelete)
+}
+
+// swagger:operation GET /deadletters Dead_Letters ListDeadLetters
+//
+// List Dead Letters
+//
+// Gets the messages which could not be processed. Only available when the DeadLetter Target is store.
+//
+// ---
+// produces:
+// - application/json
+//
+// Schemes:
+//  - http
+//
+// Responses:
+//  '200':
+//    description: Get dead letters
+//  '500':
+//    description: Internal Server Error
+//
+func (webserver *WebServer) deadLettersHandler(writer http.ResponseWriter, manager DeadLetterManager) {
+	letters, err := manager.ListDeadLetters()
+	if err != nil {
+		webserver.writeResponse(writer, fmt.Sprintf("Unable to list dead letters: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	webserver.encode(letters, writer)
+}
+
+// swagger:operation POST /deadletters/{id}/reinject Dead_Letters ReinjectDeadLetter
+//
+// Re-inject Dead Letter
+//
+// Processes the dead letter again and removes it if successful.
+//
+// ---
+// Schemes:
+//  - http
+//
+// Responses:
+//  '204':
+//    description: Dead letter processed and removed
+//  '404':
+//    description: Dead letter not found
+//  '500':
+//    description: Internal Server Error
+//
+func (webserver *WebServer) reinjectDeadLetterHandler(writer http.ResponseWriter, req *http.Request, manager DeadLetterManager) {
+	id := mux.Vars(req)[internal.DeadLetterIdVar]
+	if err := manager.ReinjectDeadLetter(id); err != nil {
+		webserver.writeDeadLetterError(writer, id, "re-inject", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// swagger:operation DELETE /deadletters/{id} Dead_Letters RemoveDeadLetter
+//
+// Remove Dead Letter
+//
+// Removes the dead letter without processing it.
+//
+// ---
+// Schemes:
+//  - http
+//
+// Responses:
+//  '204':
+//    description: Dead letter removed
+//  '404':
+//    description: Dead letter not found
+//  '500':
+//    description: Internal Server Error
+//
+func (webserver *WebServer) removeDeadLetterHandler(writer http.ResponseWriter, req *http.Request, manager DeadLetterManager) {
+	id := mux.Vars(req)[internal.DeadLetterIdVar]
+	if err := manager.RemoveDeadLetter(id); err != nil {
+		webserver.writeDeadLetterError(writer, id, "remove", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+func (webserver *WebServer) writeDeadLetterError(writer http.ResponseWriter, id string, action string, err error) {
+	statusCode := http.StatusInternalServerError
+	if errors.Is(err, runtime.ErrDeadLetterNotFound) {
+		statusCode = http.StatusNotFound
+	}
+
+	webserver.writeResponse(writer, fmt.Sprintf("Unable to %s dead letter '%s': %v", action, id, err), statusCode)
+}
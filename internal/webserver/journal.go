@@ -0,0 +1,115 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package webserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/student3671/app-functions-sdk-go/internal"
+	"github.com/student3671/app-functions-sdk-go/internal/journal"
+)
+
+// JournalReplayer replays the journal of received messages through the pipeline
+type JournalReplayer interface {
+	// StartJournalReplay starts replaying the journal file in the background, or all the journal files when file is
+	// empty, returning the number of entries to be replayed
+	StartJournalReplay(file string, speed float64) (int, error)
+}
+
+// JournalReplayRequest is the body of the request to replay the journal
+type JournalReplayRequest struct {
+	// File is the name of the journal file to replay. All the journal files are replayed when empty.
+	File string `json:"file"`
+	// Speed is the factor the time between the messages is divided by, i.e. 1 for the original speed and 10 for ten
+	// times faster. The messages are replayed without delay when 0.
+	Speed float64 `json:"speed"`
+}
+
+// SetupJournalRoutes adds the route to replay the journal
+func (webserver *WebServer) SetupJournalRoutes(replayer JournalReplayer) {
+	webserver.LoggingClient.Info("Registering journal routes...")
+
+	webserver.router.HandleFunc(internal.ApiJournalReplayRoute, func(writer http.ResponseWriter, req *http.Request) {
+		webserver.replayJournalHandler(writer, req, replayer)
+	}).Methods(http.MethodPost)
+}
+
+// swagger:operation POST /journal/replay Journal ReplayJournal
+//
+// Replay Journal
+//
+// Starts replaying the journal of received messages through the pipeline in the background, at the original or an
+// accelerated speed. The body is optional and replays all the journal files at the original speed when empty.
+//
+// ---
+// consumes:
+// - application/json
+// produces:
+// - application/json
+//
+// Schemes:
+//  - http
+//
+// Responses:
+//  '202':
+//    description: Replay started, returns the number of entries to be replayed
+//  '400':
+//    description: Invalid request
+//  '404':
+//    description: Journal file not found
+//  '409':
+//    description: A replay is already in progress
+//  '500':
+//    description: Internal Server Error
+//
+func (webserver *WebServer) replayJournalHandler(writer http.ResponseWriter, req *http.Request, replayer JournalReplayer) {
+	request := JournalReplayRequest{Speed: 1}
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil && err != io.EOF {
+		webserver.writeResponse(writer, fmt.Sprintf("Invalid journal replay request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if request.Speed < 0 {
+		webserver.writeResponse(writer, "Invalid journal replay request: speed can not be negative", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := replayer.StartJournalReplay(request.File, request.Speed)
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, journal.ErrInvalidFileName):
+			statusCode = http.StatusBadRequest
+		case errors.Is(err, os.ErrNotExist):
+			statusCode = http.StatusNotFound
+		case errors.Is(err, journal.ErrReplayInProgress):
+			statusCode = http.StatusConflict
+		}
+
+		webserver.writeResponse(writer, fmt.Sprintf("Unable to replay journal: %v", err), statusCode)
+		return
+	}
+
+	writer.Header().Add("Content-Type", "application/json")
+	writer.WriteHeader(http.StatusAccepted)
+	_ = json.NewEncoder(writer).Encode(map[string]int{"entries": entries})
+}
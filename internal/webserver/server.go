@@ -152,6 +152,30 @@ func (webserver *WebServer) metricsHandler(writer http.ResponseWriter, _ *http.R
 	return
 }
 
+// swagger:operation GET /metrics/prometheus System_Management_Agent PrometheusMetrics
+//
+// Prometheus Metrics
+//
+// Gets the pipeline function, trigger and Store and Forward metrics in the Prometheus text exposition format
+//
+// ---
+// produces:
+// - text/plain
+//
+// Schemes:
+//  - http
+//
+// Responses:
+//  '200':
+//    description: Get Prometheus metrics
+//
+func (webserver *WebServer) prometheusMetricsHandler(writer http.ResponseWriter, _ *http.Request) {
+	writer.Header().Set(clients.ContentType, telemetry.PrometheusContentType)
+	if err := telemetry.DefaultRegistry.WritePrometheus(writer); err != nil {
+		webserver.LoggingClient.Error("Error writing Prometheus metrics: " + err.Error())
+	}
+}
+
 // swagger:operation GET /version System_Management_Agent Version
 //
 // Version
@@ -292,6 +316,8 @@ func (webserver *WebServer) ConfigureStandardRoutes() {
 	// Metrics
 	webserver.router.HandleFunc(clients.ApiMetricsRoute, webserver.metricsHandler).Methods(http.MethodGet)
 
+	webserver.router.HandleFunc(internal.ApiPrometheusMetricsRoute, webserver.prometheusMetricsHandler).Methods(http.MethodGet)
+
 	// Version
 	webserver.router.HandleFunc(clients.ApiVersionRoute, webserver.versionHandler).Methods(http.MethodGet)
 
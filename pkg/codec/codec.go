@@ -0,0 +1,199 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package codec provides the registry of payload codecs, keyed by MIME type, used to decode the messages received
+// by the triggers into the TargetType and to encode the data output by the pipeline functions.
+package codec
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedContentType is returned, wrapped, when no codec is registered for the content type
+var ErrUnsupportedContentType = errors.New("content type not supported")
+
+// Codec decodes and encodes payloads of a MIME type
+type Codec interface {
+	// Name is the short name of the format used in log and error messages, i.e. JSON
+	Name() string
+	// ContentType is the MIME type handled by the codec, i.e. application/json
+	ContentType() string
+	// Marshal encodes the data
+	Marshal(data interface{}) ([]byte, error)
+	// Unmarshal decodes the payload into the target, which is a pointer
+	Unmarshal(payload []byte, target interface{}) error
+}
+
+// Sniffer is optionally implemented by a Codec to detect its format when a message is received without a content type
+type Sniffer interface {
+	// Sniff returns true if the payload is in the codec's format
+	Sniff(payload []byte) bool
+}
+
+// structuredSuffixes maps the structured syntax suffixes of RFC 6839, i.e. application/vnd.acme+json, to the content
+// type of the codec used when no codec is registered for the full content type
+var structuredSuffixes = map[string]string{
+	"+json": ContentTypeJSON,
+	"+cbor": ContentTypeCBOR,
+	"+xml":  ContentTypeXML,
+}
+
+// Registry holds the codecs keyed by content type. It is safe for concurrent use.
+type Registry struct {
+	lock   sync.RWMutex
+	codecs map[string]Codec
+	// order is the order the content types were registered, in which the codecs are sniffed
+	order []string
+}
+
+// NewRegistry creates a Registry with the JSON, CBOR and XML codecs registered
+func NewRegistry() *Registry {
+	registry := &Registry{codecs: make(map[string]Codec)}
+	_ = registry.Register(JSONCodec{})
+	_ = registry.Register(CBORCodec{})
+	_ = registry.Register(XMLCodec{})
+
+	return registry
+}
+
+// Register adds the codec for its content type, replacing the codec previously registered for the content type
+func (registry *Registry) Register(codec Codec) error {
+	if codec == nil {
+		return errors.New("codec can not be nil")
+	}
+
+	contentType := Normalize(codec.ContentType())
+	if len(contentType) == 0 {
+		return fmt.Errorf("codec '%s' must have a content type", codec.Name())
+	}
+
+	registry.lock.Lock()
+	defer registry.lock.Unlock()
+
+	if _, exists := registry.codecs[contentType]; !exists {
+		registry.order = append(registry.order, contentType)
+	}
+	registry.codecs[contentType] = codec
+
+	return nil
+}
+
+// Lookup returns the codec registered for the content type. Parameters, i.e. charset, and case are ignored. A content
+// type with a structured syntax suffix, i.e. application/vnd.acme+json, falls back to the codec for the suffix.
+func (registry *Registry) Lookup(contentType string) (Codec, bool) {
+	contentType = Normalize(contentType)
+	if len(contentType) == 0 {
+		return nil, false
+	}
+
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	if codec, ok := registry.codecs[contentType]; ok {
+		return codec, true
+	}
+
+	for suffix, fallback := range structuredSuffixes {
+		if strings.HasSuffix(contentType, suffix) {
+			codec, ok := registry.codecs[fallback]
+			return codec, ok
+		}
+	}
+
+	return nil, false
+}
+
+// Sniff returns the codec whose format the payload is in, trying the codecs implementing Sniffer in the order they
+// were registered. False is returned if none of the codecs recognise the payload.
+func (registry *Registry) Sniff(payload []byte) (Codec, bool) {
+	if len(payload) == 0 {
+		return nil, false
+	}
+
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	for _, contentType := range registry.order {
+		sniffer, ok := registry.codecs[contentType].(Sniffer)
+		if ok && sniffer.Sniff(payload) {
+			return registry.codecs[contentType], true
+		}
+	}
+
+	return nil, false
+}
+
+// Decode decodes the payload into the target using the codec for the content type. The payload is sniffed when
+// the content type is empty. An error wrapping ErrUnsupportedContentType is returned if there is no codec.
+func (registry *Registry) Decode(contentType string, payload []byte, target interface{}) error {
+	codec, err := registry.resolve(contentType, payload)
+	if err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(payload, target)
+}
+
+// Encode encodes the data using the codec for the content type. An error wrapping ErrUnsupportedContentType is
+// returned if there is no codec.
+func (registry *Registry) Encode(contentType string, data interface{}) ([]byte, error) {
+	codec, ok := registry.Lookup(contentType)
+	if !ok {
+		return nil, fmt.Errorf("'%s' %w", contentType, ErrUnsupportedContentType)
+	}
+
+	return codec.Marshal(data)
+}
+
+// ContentTypes returns the registered content types in the order they were registered
+func (registry *Registry) ContentTypes() []string {
+	registry.lock.RLock()
+	defer registry.lock.RUnlock()
+
+	contentTypes := make([]string, len(registry.order))
+	copy(contentTypes, registry.order)
+	return contentTypes
+}
+
+func (registry *Registry) resolve(contentType string, payload []byte) (Codec, error) {
+	if len(Normalize(contentType)) == 0 {
+		codec, ok := registry.Sniff(payload)
+		if !ok {
+			return nil, fmt.Errorf("unrecognised payload %w", ErrUnsupportedContentType)
+		}
+		return codec, nil
+	}
+
+	codec, ok := registry.Lookup(contentType)
+	if !ok {
+		return nil, fmt.Errorf("'%s' %w", contentType, ErrUnsupportedContentType)
+	}
+
+	return codec, nil
+}
+
+// Normalize returns the content type without parameters, in lower case, i.e. 'application/json; charset=utf-8'
+// is normalized to 'application/json'
+func Normalize(contentType string) string {
+	if index := strings.Index(contentType, ";"); index >= 0 {
+		contentType = contentType[:index]
+	}
+
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
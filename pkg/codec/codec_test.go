@@ -0,0 +1,180 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reading struct {
+	Device string `json:"device" xml:"device" cbor:"device"`
+	Value  int    `json:"value" xml:"value" cbor:"value"`
+}
+
+// textCodec is a minimal custom codec which encodes the reading as 'device=value'
+type textCodec struct{}
+
+func (textCodec) Name() string        { return "Text" }
+func (textCodec) ContentType() string { return "text/x-reading" }
+
+func (textCodec) Marshal(data interface{}) ([]byte, error) {
+	value, ok := data.(reading)
+	if !ok {
+		return nil, errors.New("not a reading")
+	}
+	return []byte(fmt.Sprintf("%s=%d", value.Device, value.Value)), nil
+}
+
+func (textCodec) Unmarshal(payload []byte, target interface{}) error {
+	value, ok := target.(*reading)
+	if !ok {
+		return errors.New("not a reading")
+	}
+	parts := bytes.SplitN(payload, []byte("="), 2)
+	if len(parts) != 2 {
+		return errors.New("missing '='")
+	}
+	value.Device = string(parts[0])
+	_, err := fmt.Sscanf(string(parts[1]), "%d", &value.Value)
+	return err
+}
+
+func (textCodec) Sniff(payload []byte) bool {
+	return bytes.Contains(payload, []byte("=")) && !bytes.ContainsAny(payload, "{<")
+}
+
+func TestRegistryDefaults(t *testing.T) {
+	registry := NewRegistry()
+	assert.Equal(t, []string{ContentTypeJSON, ContentTypeCBOR, ContentTypeXML}, registry.ContentTypes())
+
+	expected := reading{Device: "thermostat", Value: 21}
+	for _, contentType := range registry.ContentTypes() {
+		t.Run(contentType, func(t *testing.T) {
+			payload, err := registry.Encode(contentType, expected)
+			require.NoError(t, err)
+
+			var actual reading
+			require.NoError(t, registry.Decode(contentType, payload, &actual))
+			assert.Equal(t, expected, actual)
+
+			// The same payload is decoded when the content type is missing
+			actual = reading{}
+			require.NoError(t, registry.Decode("", payload, &actual))
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry := NewRegistry()
+
+	tests := []struct {
+		Name        string
+		ContentType string
+		Expected    string
+		Found       bool
+	}{
+		{"Exact", "application/json", ContentTypeJSON, true},
+		{"Parameters and case", "Application/JSON; charset=utf-8", ContentTypeJSON, true},
+		{"JSON suffix", "application/vnd.acme.reading+json", ContentTypeJSON, true},
+		{"CBOR suffix", "application/senml+cbor", ContentTypeCBOR, true},
+		{"XML suffix", "application/atom+xml", ContentTypeXML, true},
+		{"Unknown", "application/x-msgpack", "", false},
+		{"Empty", "", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			codec, found := registry.Lookup(test.ContentType)
+			require.Equal(t, test.Found, found)
+			if found {
+				assert.Equal(t, test.Expected, codec.ContentType())
+			}
+		})
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+
+	assert.Error(t, registry.Register(nil))
+
+	_, err := registry.Encode("text/x-reading", reading{})
+	assert.True(t, errors.Is(err, ErrUnsupportedContentType))
+
+	require.NoError(t, registry.Register(textCodec{}))
+
+	payload, err := registry.Encode("text/x-reading", reading{Device: "meter", Value: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "meter=7", string(payload))
+
+	var actual reading
+	require.NoError(t, registry.Decode("", payload, &actual))
+	assert.Equal(t, reading{Device: "meter", Value: 7}, actual)
+
+	// Registering a codec for an existing content type replaces it without changing the sniffing order
+	require.NoError(t, registry.Register(textCodec{}))
+	assert.Equal(t, []string{ContentTypeJSON, ContentTypeCBOR, ContentTypeXML, "text/x-reading"},
+		registry.ContentTypes())
+}
+
+func TestRegistrySniff(t *testing.T) {
+	registry := NewRegistry()
+
+	cborMap, err := cbor.Marshal(map[string]int{"value": 1})
+	require.NoError(t, err)
+
+	tests := []struct {
+		Name     string
+		Payload  []byte
+		Expected string
+	}{
+		{"JSON object", []byte(` {"value": 1}`), ContentTypeJSON},
+		{"JSON array", []byte(`[1, 2]`), ContentTypeJSON},
+		{"Invalid JSON", []byte(`{"value": `), ""},
+		{"CBOR map", cborMap, ContentTypeCBOR},
+		{"CBOR self-describe", append([]byte{0xd9, 0xd9, 0xf7}, cborMap...), ContentTypeCBOR},
+		{"MessagePack map", []byte{0x81, 0xa5, 'v', 'a', 'l', 'u', 'e', 0x01}, ""},
+		{"XML declaration", []byte(`<?xml version="1.0"?><reading/>`), ContentTypeXML},
+		{"XML element", []byte(`<reading><value>1</value></reading>`), ContentTypeXML},
+		{"Text", []byte("hello"), ""},
+		{"Empty", nil, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			codec, found := registry.Sniff(test.Payload)
+			if len(test.Expected) == 0 {
+				assert.False(t, found)
+				return
+			}
+
+			require.True(t, found)
+			assert.Equal(t, test.Expected, codec.ContentType())
+		})
+	}
+
+	err = registry.Decode("", []byte("hello"), &reading{})
+	assert.True(t, errors.Is(err, ErrUnsupportedContentType))
+}
@@ -0,0 +1,141 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Content types of the codecs registered by NewRegistry
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeCBOR = "application/cbor"
+	ContentTypeXML  = "application/xml"
+)
+
+// cborSelfDescribeTag is the optional tag 55799 which marks the data as CBOR
+var cborSelfDescribeTag = []byte{0xd9, 0xd9, 0xf7}
+
+// JSONCodec decodes and encodes JSON using encoding/json
+type JSONCodec struct{}
+
+// Name returns JSON
+func (JSONCodec) Name() string {
+	return "JSON"
+}
+
+// ContentType returns application/json
+func (JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+// Marshal encodes the data as JSON
+func (JSONCodec) Marshal(data interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// Unmarshal decodes the JSON payload into the target
+func (JSONCodec) Unmarshal(payload []byte, target interface{}) error {
+	return json.Unmarshal(payload, target)
+}
+
+// Sniff returns true if the payload is a JSON object or array
+func (JSONCodec) Sniff(payload []byte) bool {
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return false
+	}
+
+	return json.Valid(trimmed)
+}
+
+// CBORCodec decodes and encodes CBOR using fxamacker/cbor
+type CBORCodec struct{}
+
+// Name returns CBOR
+func (CBORCodec) Name() string {
+	return "CBOR"
+}
+
+// ContentType returns application/cbor
+func (CBORCodec) ContentType() string {
+	return ContentTypeCBOR
+}
+
+// Marshal encodes the data as CBOR
+func (CBORCodec) Marshal(data interface{}) ([]byte, error) {
+	return cbor.Marshal(data)
+}
+
+// Unmarshal decodes the CBOR payload into the target
+func (CBORCodec) Unmarshal(payload []byte, target interface{}) error {
+	return cbor.Unmarshal(payload, target)
+}
+
+// Sniff returns true if the payload is a well-formed CBOR map or starts with the self-describe tag. Arrays aren't
+// sniffed since their initial bytes overlap with those of other binary formats, i.e. MessagePack.
+func (CBORCodec) Sniff(payload []byte) bool {
+	if len(payload) == 0 {
+		return false
+	}
+
+	isMap := payload[0] >= 0xa0 && payload[0] <= 0xbf
+	if !isMap && !bytes.HasPrefix(payload, cborSelfDescribeTag) {
+		return false
+	}
+
+	// Decoding into a RawMessage checks the payload is well-formed without decoding its content
+	var raw cbor.RawMessage
+	return cbor.Unmarshal(payload, &raw) == nil
+}
+
+// XMLCodec decodes and encodes XML using encoding/xml
+type XMLCodec struct{}
+
+// Name returns XML
+func (XMLCodec) Name() string {
+	return "XML"
+}
+
+// ContentType returns application/xml
+func (XMLCodec) ContentType() string {
+	return ContentTypeXML
+}
+
+// Marshal encodes the data as XML
+func (XMLCodec) Marshal(data interface{}) ([]byte, error) {
+	return xml.Marshal(data)
+}
+
+// Unmarshal decodes the XML payload into the target
+func (XMLCodec) Unmarshal(payload []byte, target interface{}) error {
+	return xml.Unmarshal(payload, target)
+}
+
+// Sniff returns true if the payload starts with an XML declaration or element
+func (XMLCodec) Sniff(payload []byte) bool {
+	trimmed := bytes.TrimSpace(payload)
+	return len(trimmed) > 1 && trimmed[0] == '<' && (trimmed[1] == '?' || isNameStart(trimmed[1]))
+}
+
+func isNameStart(char byte) bool {
+	return char == '_' || char == ':' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || char >= 0x80
+}
@@ -0,0 +1,74 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package testing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+)
+
+// RecordedEnvelope is a message recorded to a JSON file along with the topic it was received on
+type RecordedEnvelope struct {
+	types.MessageEnvelope
+	// ReceivedTopic is the topic the message was received on, empty for triggers that are not topic based
+	ReceivedTopic string
+}
+
+// LoadEnvelope reads the message recorded to the file. A file with the .cbor extension holds a CBOR payload. Any
+// other file holds JSON, which is either a RecordedEnvelope, when it has a Payload field, or the JSON payload itself,
+// i.e. an Event.
+func LoadEnvelope(path string) (RecordedEnvelope, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RecordedEnvelope{}, fmt.Errorf("unable to read recorded message: %w", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".cbor") {
+		return RecordedEnvelope{
+			MessageEnvelope: types.MessageEnvelope{Payload: contents, ContentType: codec.ContentTypeCBOR},
+		}, nil
+	}
+
+	if !json.Valid(contents) {
+		return RecordedEnvelope{}, fmt.Errorf("recorded message '%s' is not valid JSON", path)
+	}
+
+	// Payloads which aren't JSON objects, i.e. arrays, can't be envelopes
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(contents, &fields)
+
+	for name := range fields {
+		if strings.EqualFold(name, "payload") {
+			var recorded RecordedEnvelope
+			if err := json.Unmarshal(contents, &recorded); err != nil {
+				return RecordedEnvelope{}, fmt.Errorf("unable to decode recorded envelope '%s': %w", path, err)
+			}
+			return recorded, nil
+		}
+	}
+
+	return RecordedEnvelope{
+		MessageEnvelope: types.MessageEnvelope{Payload: contents, ContentType: codec.ContentTypeJSON},
+	}, nil
+}
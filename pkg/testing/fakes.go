@@ -0,0 +1,274 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package testing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/coredata"
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/notifications"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/google/uuid"
+
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/store/db"
+)
+
+// FakeEventClient records the events added to and marked as pushed in Core Data. Only the methods used by the
+// Context are implemented, calling any other method panics. It is safe for concurrent use.
+type FakeEventClient struct {
+	coredata.EventClient
+
+	// Err, when set, is returned by all the methods to simulate Core Data failing
+	Err error
+
+	lock            sync.Mutex
+	added           []models.Event
+	pushedIDs       []string
+	pushedChecksums []string
+}
+
+// NewFakeEventClient creates a FakeEventClient
+func NewFakeEventClient() *FakeEventClient {
+	return &FakeEventClient{}
+}
+
+// Add records the event and returns a new ID for it
+func (client *FakeEventClient) Add(_ context.Context, event *models.Event) (string, error) {
+	if client.Err != nil {
+		return "", client.Err
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	added := *event
+	added.ID = uuid.New().String()
+	client.added = append(client.added, added)
+
+	return added.ID, nil
+}
+
+// MarkPushed records the ID of the event marked as pushed
+func (client *FakeEventClient) MarkPushed(_ context.Context, id string) error {
+	if client.Err != nil {
+		return client.Err
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.pushedIDs = append(client.pushedIDs, id)
+	return nil
+}
+
+// MarkPushedByChecksum records the checksum of the event marked as pushed
+func (client *FakeEventClient) MarkPushedByChecksum(_ context.Context, checksum string) error {
+	if client.Err != nil {
+		return client.Err
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.pushedChecksums = append(client.pushedChecksums, checksum)
+	return nil
+}
+
+// Added returns the events added, with their new IDs, in the order they were added
+func (client *FakeEventClient) Added() []models.Event {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	return append([]models.Event(nil), client.added...)
+}
+
+// PushedIDs returns the IDs of the events marked as pushed
+func (client *FakeEventClient) PushedIDs() []string {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	return append([]string(nil), client.pushedIDs...)
+}
+
+// PushedChecksums returns the checksums of the events marked as pushed
+func (client *FakeEventClient) PushedChecksums() []string {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	return append([]string(nil), client.pushedChecksums...)
+}
+
+// Reset clears the recorded calls
+func (client *FakeEventClient) Reset() {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.added = nil
+	client.pushedIDs = nil
+	client.pushedChecksums = nil
+}
+
+// FakeNotificationsClient records the notifications sent. It is safe for concurrent use.
+type FakeNotificationsClient struct {
+	notifications.NotificationsClient
+
+	// Err, when set, is returned when sending a notification to simulate Support Notifications failing
+	Err error
+
+	lock sync.Mutex
+	sent []notifications.Notification
+}
+
+// NewFakeNotificationsClient creates a FakeNotificationsClient
+func NewFakeNotificationsClient() *FakeNotificationsClient {
+	return &FakeNotificationsClient{}
+}
+
+// SendNotification records the notification
+func (client *FakeNotificationsClient) SendNotification(_ context.Context, notification notifications.Notification) error {
+	if client.Err != nil {
+		return client.Err
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.sent = append(client.sent, notification)
+	return nil
+}
+
+// Sent returns the notifications sent, in the order they were sent
+func (client *FakeNotificationsClient) Sent() []notifications.Notification {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	return append([]notifications.Notification(nil), client.sent...)
+}
+
+// Reset clears the recorded notifications
+func (client *FakeNotificationsClient) Reset() {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	client.sent = nil
+}
+
+// FakeSecretProvider holds secrets in memory, keyed by path. It is safe for concurrent use.
+type FakeSecretProvider struct {
+	lock        sync.Mutex
+	secrets     map[string]map[string]string
+	lastUpdated time.Time
+}
+
+// NewFakeSecretProvider creates a FakeSecretProvider without any secrets
+func NewFakeSecretProvider() *FakeSecretProvider {
+	return &FakeSecretProvider{
+		secrets:     make(map[string]map[string]string),
+		lastUpdated: time.Now(),
+	}
+}
+
+// Initialize does nothing and returns true
+func (provider *FakeSecretProvider) Initialize(_ context.Context) bool {
+	return true
+}
+
+// StoreSecrets adds the secrets to those at the path
+func (provider *FakeSecretProvider) StoreSecrets(path string, secrets map[string]string) error {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	if provider.secrets[path] == nil {
+		provider.secrets[path] = make(map[string]string)
+	}
+	for key, value := range secrets {
+		provider.secrets[path][key] = value
+	}
+	provider.lastUpdated = time.Now()
+
+	return nil
+}
+
+// GetSecrets returns the secrets at the path for the keys, or all the secrets at the path when no keys are
+// specified. An error is returned if the path or any of the keys don't exist.
+func (provider *FakeSecretProvider) GetSecrets(path string, keys ...string) (map[string]string, error) {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	stored, ok := provider.secrets[path]
+	if !ok {
+		return nil, fmt.Errorf("path (%s) doesn't exist in secret store", path)
+	}
+
+	secrets := make(map[string]string)
+	if len(keys) == 0 {
+		for key, value := range stored {
+			secrets[key] = value
+		}
+		return secrets, nil
+	}
+
+	var missingKeys []string
+	for _, key := range keys {
+		value, ok := stored[key]
+		if !ok {
+			missingKeys = append(missingKeys, key)
+			continue
+		}
+		secrets[key] = value
+	}
+
+	if len(missingKeys) > 0 {
+		sort.Strings(missingKeys)
+		return nil, fmt.Errorf("no value for the keys: [%s] exists", strings.Join(missingKeys, ","))
+	}
+
+	return secrets, nil
+}
+
+// GetDatabaseCredentials returns the username and password secrets at the path of the database's type, or the
+// database's own credentials when there are no secrets at the path
+func (provider *FakeSecretProvider) GetDatabaseCredentials(database db.DatabaseInfo) (common.Credentials, error) {
+	secrets, err := provider.GetSecrets(database.Type, "username", "password")
+	if err != nil {
+		return common.Credentials{Username: database.Username, Password: database.Password}, nil
+	}
+
+	return common.Credentials{Username: secrets["username"], Password: secrets["password"]}, nil
+}
+
+// InsecureSecretsUpdated records that the secrets have been updated
+func (provider *FakeSecretProvider) InsecureSecretsUpdated() {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	provider.lastUpdated = time.Now()
+}
+
+// SecretsLastUpdated returns when the secrets were last updated
+func (provider *FakeSecretProvider) SecretsLastUpdated() time.Time {
+	provider.lock.Lock()
+	defer provider.lock.Unlock()
+
+	return provider.lastUpdated
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package testing provides a harness which runs a pipeline with the same runtime used by the service, against
+// messages built in code or recorded to files, without the message bus, Consul, Redis or the EdgeX core services.
+// The EdgeX clients and the SecretProvider are replaced with fakes which record their calls.
+package testing
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/edgexfoundry/go-mod-messaging/pkg/types"
+	"github.com/google/uuid"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+	"github.com/student3671/app-functions-sdk-go/pkg/codec"
+)
+
+// Harness executes the messages through the pipeline's functions. It is not safe for concurrent use.
+type Harness struct {
+	// LoggingClient is the logger passed to the functions, which discards the log messages by default
+	LoggingClient logger.LoggingClient
+	// EventClient records the events pushed to and marked as pushed in Core Data
+	EventClient *FakeEventClient
+	// NotificationsClient records the notifications sent
+	NotificationsClient *FakeNotificationsClient
+	// SecretProvider holds the secrets returned by the Context's GetSecrets
+	SecretProvider *FakeSecretProvider
+
+	runtime       *runtime.GolangRuntime
+	configuration *common.ConfigurationStruct
+}
+
+// NewHarness creates a Harness which executes the functions, in order, for each message. The messages are decoded
+// into an Event unless another target type is set with SetTargetType.
+func NewHarness(transforms ...appcontext.AppFunction) *Harness {
+	harness := &Harness{
+		LoggingClient:       logger.NewMockClient(),
+		EventClient:         NewFakeEventClient(),
+		NotificationsClient: NewFakeNotificationsClient(),
+		SecretProvider:      NewFakeSecretProvider(),
+		runtime:             &runtime.GolangRuntime{},
+		configuration:       &common.ConfigurationStruct{},
+	}
+
+	harness.runtime.Initialize(nil, harness.SecretProvider)
+	harness.runtime.SetTransforms(transforms)
+
+	return harness
+}
+
+// SetTargetType sets the type the received payload is decoded into before it is passed to the first function, as
+// AppFunctionsSDK.TargetType does. The type must be a pointer, i.e. &[]byte{} for the raw payload.
+func (harness *Harness) SetTargetType(target interface{}) {
+	harness.runtime.TargetType = target
+}
+
+// SetApplicationSettings sets the ApplicationSettings of the Configuration passed to the functions in the Context
+func (harness *Harness) SetApplicationSettings(settings map[string]string) {
+	harness.configuration.ApplicationSettings = settings
+}
+
+// Codecs returns the registry of codecs used to decode the messages, to which custom codecs can be registered
+func (harness *Harness) Codecs() *codec.Registry {
+	return harness.runtime.Codecs()
+}
+
+// Process executes the pipeline for the message as if it was received on the topic, which may be empty. A CBOR
+// message without a checksum is given the checksum Core Data publishes it with.
+func (harness *Harness) Process(envelope types.MessageEnvelope, topic string) *Result {
+	if len(envelope.CorrelationID) == 0 {
+		envelope.CorrelationID = uuid.New().String()
+	}
+	// Core Data publishes CBOR events with the checksum used to mark them as pushed
+	if len(envelope.Checksum) == 0 && codec.Normalize(envelope.ContentType) == codec.ContentTypeCBOR {
+		envelope.Checksum = fmt.Sprintf("%x", sha256.Sum256(envelope.Payload))
+	}
+
+	edgexcontext := &appcontext.Context{
+		CorrelationID:       envelope.CorrelationID,
+		ReceivedTopic:       topic,
+		Configuration:       harness.configuration,
+		LoggingClient:       harness.LoggingClient,
+		EventClient:         harness.EventClient,
+		NotificationsClient: harness.NotificationsClient,
+		SecretProvider:      harness.SecretProvider,
+	}
+	if len(topic) > 0 {
+		_ = edgexcontext.SetValue(appcontext.ValueKeyReceivedTopic, topic)
+	}
+	edgexcontext.SetRequestContext(context.Background())
+
+	return newResult(edgexcontext, harness.runtime.ProcessMessage(edgexcontext, envelope))
+}
+
+// ProcessPayload executes the pipeline for the payload. The content type is sniffed from the payload when empty.
+func (harness *Harness) ProcessPayload(payload []byte, contentType string) *Result {
+	return harness.Process(types.MessageEnvelope{Payload: payload, ContentType: contentType}, "")
+}
+
+// ProcessFile executes the pipeline for the message recorded to the file, see LoadEnvelope for the file formats
+func (harness *Harness) ProcessFile(path string) (*Result, error) {
+	recorded, err := LoadEnvelope(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return harness.Process(recorded.MessageEnvelope, recorded.ReceivedTopic), nil
+}
@@ -0,0 +1,162 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package testing_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	apptesting "github.com/student3671/app-functions-sdk-go/pkg/testing"
+	"github.com/student3671/app-functions-sdk-go/pkg/transforms"
+)
+
+func TestHarnessProcessFile(t *testing.T) {
+	harness := apptesting.NewHarness(
+		transforms.NewFilter([]string{"Random-Float-Device", "Random-Binary-Device"}).FilterByDeviceName,
+		transforms.NewConversion().TransformToJSON,
+		transforms.NewCoreData().MarkAsPushed,
+		transforms.NewOutputData().SetOutputData,
+	)
+
+	tests := []struct {
+		Name           string
+		Path           string
+		ExpectedDevice string
+	}{
+		{"JSON payload", "testdata/event.json", "Random-Float-Device"},
+		{"CBOR payload", "testdata/event.cbor", "Random-Binary-Device"},
+		{"Recorded envelope", "testdata/envelope.json", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result, err := harness.ProcessFile(test.Path)
+			require.NoError(t, err)
+			result.AssertSucceeded(t)
+
+			if len(test.ExpectedDevice) == 0 {
+				result.AssertNoOutput(t)
+				return
+			}
+
+			require.Len(t, result.Outputs, 1)
+			assert.Contains(t, string(result.Output()), test.ExpectedDevice)
+		})
+	}
+
+	assert.Equal(t, []string{"5ea8e5b7-5e8e-4ac0-a2b4-5e3b3c2a6a1f"}, harness.EventClient.PushedIDs())
+	assert.Len(t, harness.EventClient.PushedChecksums(), 1, "CBOR event should be marked as pushed by checksum")
+}
+
+func TestHarnessRecordedEnvelope(t *testing.T) {
+	recorded, err := apptesting.LoadEnvelope("testdata/envelope.json")
+	require.NoError(t, err)
+	assert.Equal(t, "4f8a7e2c-3b1d-4c9e-8a6f-2d5e7b9c1a3f", recorded.CorrelationID)
+	assert.Equal(t, "edgex/events/Random-Integer-Device", recorded.ReceivedTopic)
+
+	harness := apptesting.NewHarness(func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		event := params[0].(models.Event)
+		topic, err := edgexcontext.ExpandTopic("alerts/{devicename}", event)
+		if err != nil {
+			return false, err
+		}
+		edgexcontext.SetOutputTopic(topic)
+		edgexcontext.Complete([]byte(event.Readings[0].Value))
+		return true, nil
+	})
+
+	result := harness.Process(recorded.MessageEnvelope, recorded.ReceivedTopic)
+	result.AssertSucceeded(t)
+	result.AssertOutput(t, []byte("42"))
+	assert.Equal(t, "alerts/Random-Integer-Device", result.Outputs[0].Topic)
+	assert.Equal(t, recorded.CorrelationID, result.Context.CorrelationID)
+
+	topic, _ := result.Context.StringValue(appcontext.ValueKeyReceivedTopic)
+	assert.Equal(t, recorded.ReceivedTopic, topic)
+
+	_, err = apptesting.LoadEnvelope("testdata/missing.json")
+	assert.Error(t, err)
+}
+
+func TestHarnessFailure(t *testing.T) {
+	harness := apptesting.NewHarness(
+		transforms.NewConversion().TransformToXML,
+		func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+			edgexcontext.SetRetryData([]byte(params[0].(string)))
+			return false, appcontext.NewRetryableError(errors.New("endpoint unavailable"))
+		},
+	)
+
+	result, err := harness.ProcessFile("testdata/event.json")
+	require.NoError(t, err)
+	result.AssertFailed(t, 1, appcontext.ErrorKindRetryable)
+	assert.Contains(t, string(result.RetryData), "Random-Float-Device")
+	result.AssertNoOutput(t)
+
+	result = harness.ProcessPayload([]byte("not an event"), "")
+	assert.False(t, result.Succeeded())
+	assert.Equal(t, -1, result.FunctionIndex)
+}
+
+func TestHarnessFakes(t *testing.T) {
+	harness := apptesting.NewHarness(
+		func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+			secrets, err := edgexcontext.GetSecrets("mqtt", "username")
+			if err != nil {
+				return false, err
+			}
+
+			event, err := edgexcontext.PushToCoreData("Virtual-Device", "User", secrets["username"])
+			if err != nil {
+				return false, err
+			}
+
+			return true, event
+		},
+		transforms.NewOutputData().SetOutputData,
+	)
+	harness.SetApplicationSettings(map[string]string{"DeviceName": "Virtual-Device"})
+
+	result, err := harness.ProcessFile("testdata/event.json")
+	require.NoError(t, err)
+	result.AssertFailed(t, 0, appcontext.ErrorKindUnclassified)
+	assert.Empty(t, harness.EventClient.Added())
+
+	require.NoError(t, harness.SecretProvider.StoreSecrets("mqtt", map[string]string{"username": "edgex"}))
+	result, err = harness.ProcessFile("testdata/event.json")
+	require.NoError(t, err)
+	result.AssertSucceeded(t)
+	assert.Equal(t, "Virtual-Device", result.Context.Configuration.ApplicationSettings["DeviceName"])
+
+	added := harness.EventClient.Added()
+	require.Len(t, added, 1)
+	assert.Equal(t, "Virtual-Device", added[0].Device)
+	assert.Equal(t, "edgex", added[0].Readings[0].Value)
+	assert.Contains(t, string(result.Output()), added[0].ID)
+
+	harness.EventClient.Reset()
+	harness.EventClient.Err = errors.New("core data unavailable")
+	result, err = harness.ProcessFile("testdata/event.json")
+	require.NoError(t, err)
+	assert.False(t, result.Succeeded())
+	assert.Empty(t, harness.EventClient.Added())
+}
@@ -0,0 +1,118 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package testing
+
+import (
+	"github.com/stretchr/testify/assert"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/internal/runtime"
+)
+
+// Result captures the outcome of executing the pipeline for a message
+type Result struct {
+	// Context is the Context the functions were executed with, i.e. to inspect the values they set
+	Context *appcontext.Context
+	// Outputs are the messages output by the pipeline, which the trigger would publish or return
+	Outputs []appcontext.Output
+	// RetryData is the data the failed function set to be stored for later retry, nil if none
+	RetryData []byte
+	// Err is the error which stopped the pipeline, nil if the pipeline succeeded
+	Err error
+	// ErrorKind is the classification of Err
+	ErrorKind appcontext.ErrorKind
+	// ErrorCode is the HTTP status code the HTTP trigger would respond with when Err is set
+	ErrorCode int
+	// FunctionIndex is the position of the function which returned Err, -1 if the message failed before the
+	// pipeline was executed, i.e. it could not be decoded
+	FunctionIndex int
+}
+
+func newResult(edgexcontext *appcontext.Context, messageError *runtime.MessageError) *Result {
+	result := &Result{
+		Context:       edgexcontext,
+		Outputs:       edgexcontext.Outputs(),
+		RetryData:     edgexcontext.RetryData,
+		FunctionIndex: -1,
+	}
+
+	if messageError != nil {
+		result.Err = messageError.Err
+		result.ErrorKind = messageError.Kind
+		result.ErrorCode = messageError.ErrorCode
+		result.FunctionIndex = messageError.FunctionIndex
+	}
+
+	return result
+}
+
+// Succeeded returns true if the pipeline did not return an error
+func (result *Result) Succeeded() bool {
+	return result.Err == nil
+}
+
+// Output returns the data of the first output, nil if the pipeline didn't output any data
+func (result *Result) Output() []byte {
+	if len(result.Outputs) == 0 {
+		return nil
+	}
+
+	return result.Outputs[0].Data
+}
+
+// AssertSucceeded asserts the pipeline did not return an error
+func (result *Result) AssertSucceeded(t assert.TestingT) bool {
+	return assert.NoError(t, result.Err, "Pipeline should have succeeded")
+}
+
+// AssertFailed asserts the function at functionIndex returned an error of the kind
+func (result *Result) AssertFailed(t assert.TestingT, functionIndex int, kind appcontext.ErrorKind) bool {
+	if !assert.Error(t, result.Err, "Pipeline should have failed") {
+		return false
+	}
+
+	return assert.Equal(t, functionIndex, result.FunctionIndex, "Unexpected function failed") &&
+		assert.Equal(t, kind.String(), result.ErrorKind.String(), "Unexpected kind of error")
+}
+
+// AssertFiltered asserts a function filtered out the data
+func (result *Result) AssertFiltered(t assert.TestingT) bool {
+	return assert.Equal(t, appcontext.ErrorKindFiltered.String(), result.ErrorKind.String(),
+		"Data should have been filtered")
+}
+
+// AssertNoOutput asserts the pipeline didn't output any data
+func (result *Result) AssertNoOutput(t assert.TestingT) bool {
+	return assert.Empty(t, result.Outputs, "Pipeline should not have output any data")
+}
+
+// AssertOutput asserts the pipeline output a single message with the data
+func (result *Result) AssertOutput(t assert.TestingT, expected []byte) bool {
+	return assert.Len(t, result.Outputs, 1, "Pipeline should have output a single message") &&
+		assert.Equal(t, expected, result.Output())
+}
+
+// AssertOutputJSON asserts the pipeline output a single message with JSON equivalent to the expected JSON
+func (result *Result) AssertOutputJSON(t assert.TestingT, expected string) bool {
+	return assert.Len(t, result.Outputs, 1, "Pipeline should have output a single message") &&
+		assert.JSONEq(t, expected, string(result.Output()))
+}
+
+// AssertRetryData asserts the failed function set the data to be stored for later retry
+func (result *Result) AssertRetryData(t assert.TestingT, expected []byte) bool {
+	return assert.Equal(t, expected, result.RetryData, "Unexpected retry data")
+}
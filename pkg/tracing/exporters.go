@@ -0,0 +1,233 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+const otlpScopeName = "github.com/student3671/app-functions-sdk-go"
+
+// FileExporter writes each span as a line of JSON to a local file, for sites without a tracing backend
+type FileExporter struct {
+	lock sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter creates a FileExporter which appends to the file at path, creating it if needed
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open trace file '%s': %w", path, err)
+	}
+
+	return &FileExporter{file: file}, nil
+}
+
+// ExportSpans appends the spans to the file
+func (exporter *FileExporter) ExportSpans(_ context.Context, spans []SpanData) error {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	for _, span := range spans {
+		if err := encoder.Encode(span); err != nil {
+			return err
+		}
+	}
+
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+
+	_, err := exporter.file.Write(buffer.Bytes())
+	return err
+}
+
+// Shutdown closes the file
+func (exporter *FileExporter) Shutdown(_ context.Context) error {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+
+	return exporter.file.Close()
+}
+
+// OTLPHTTPExporter sends the spans to an OpenTelemetry collector using OTLP/HTTP with JSON encoding
+type OTLPHTTPExporter struct {
+	endpoint    string
+	serviceName string
+	headers     map[string]string
+	client      *http.Client
+}
+
+// NewOTLPHTTPExporter creates an OTLPHTTPExporter which posts to the endpoint, i.e. http://localhost:4318/v1/traces.
+// The serviceName is set as the service.name resource attribute and the headers are added to each request.
+func NewOTLPHTTPExporter(endpoint string, serviceName string, headers map[string]string) *OTLPHTTPExporter {
+	return &OTLPHTTPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		headers:     headers,
+		client:      &http.Client{},
+	}
+}
+
+// ExportSpans posts the spans to the collector
+func (exporter *OTLPHTTPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	body, err := json.Marshal(exporter.toRequest(spans))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, exporter.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range exporter.headers {
+		req.Header.Set(name, value)
+	}
+
+	response, err := exporter.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		responseBody, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("collector responded with %d HTTP status code: %s", response.StatusCode, string(responseBody))
+	}
+
+	return nil
+}
+
+// Shutdown does nothing since there are no resources to release
+func (exporter *OTLPHTTPExporter) Shutdown(_ context.Context) error {
+	return nil
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    StatusCode `json:"code"`
+	Message string     `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (exporter *OTLPHTTPExporter) toRequest(spans []SpanData) otlpRequest {
+	otlpSpans := make([]otlpSpan, len(spans))
+	for index, span := range spans {
+		otlpSpans[index] = otlpSpan{
+			TraceID:           span.TraceID,
+			SpanID:            span.SpanID,
+			ParentSpanID:      span.ParentSpanID,
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+			Attributes:        toKeyValues(span.Attributes),
+			Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+		}
+	}
+
+	return otlpRequest{
+		ResourceSpans: []otlpResourceSpans{
+			{
+				Resource:   otlpResource{Attributes: toKeyValues(map[string]interface{}{"service.name": exporter.serviceName})},
+				ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: otlpScopeName}, Spans: otlpSpans}},
+			},
+		},
+	}
+}
+
+func toKeyValues(attributes map[string]interface{}) []otlpKeyValue {
+	keyValues := make([]otlpKeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		keyValues = append(keyValues, otlpKeyValue{Key: key, Value: toValue(value)})
+	}
+
+	return keyValues
+}
+
+func toValue(value interface{}) otlpValue {
+	switch typed := value.(type) {
+	case string:
+		return otlpValue{StringValue: &typed}
+	case bool:
+		return otlpValue{BoolValue: &typed}
+	case int:
+		intValue := strconv.FormatInt(int64(typed), 10)
+		return otlpValue{IntValue: &intValue}
+	case int64:
+		intValue := strconv.FormatInt(typed, 10)
+		return otlpValue{IntValue: &intValue}
+	case float64:
+		return otlpValue{DoubleValue: &typed}
+	default:
+		stringValue := fmt.Sprintf("%v", value)
+		return otlpValue{StringValue: &stringValue}
+	}
+}
@@ -0,0 +1,131 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testSpans() []SpanData {
+	start := time.Unix(0, 1000)
+	return []SpanData{
+		{
+			TraceID:    testTraceID,
+			SpanID:     testSpanID,
+			Name:       "test",
+			Kind:       SpanKindInternal,
+			StartTime:  start,
+			EndTime:    start.Add(time.Microsecond),
+			Attributes: map[string]interface{}{AttributePayloadSize: 10},
+			Status:     StatusError,
+		},
+		{
+			TraceID:      testTraceID,
+			SpanID:       "00f067aa0ba902b8",
+			ParentSpanID: testSpanID,
+			Name:         "child",
+			Kind:         SpanKindClient,
+			StartTime:    start,
+			EndTime:      start,
+		},
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "spans.json")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+
+	require.NoError(t, exporter.ExportSpans(context.Background(), testSpans()))
+	require.NoError(t, exporter.Shutdown(context.Background()))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span SpanData
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &span))
+		assert.Equal(t, testTraceID, span.TraceID)
+		names = append(names, span.Name)
+	}
+	assert.Equal(t, []string{"test", "child"}, names)
+}
+
+func TestFileExporterInvalidPath(t *testing.T) {
+	_, err := NewFileExporter(filepath.Join("does", "not", "exist", "spans.json"))
+	assert.Error(t, err)
+}
+
+func TestOTLPHTTPExporter(t *testing.T) {
+	var received otlpRequest
+	var header http.Header
+	statusCode := http.StatusOK
+
+	handler := func(writer http.ResponseWriter, request *http.Request) {
+		header = request.Header
+		body, _ := ioutil.ReadAll(request.Body)
+		_ = json.Unmarshal(body, &received)
+		writer.WriteHeader(statusCode)
+	}
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	exporter := NewOTLPHTTPExporter(server.URL+"/v1/traces", "test-service", map[string]string{"Api-Key": "secret"})
+	require.NoError(t, exporter.ExportSpans(context.Background(), testSpans()))
+
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "secret", header.Get("Api-Key"))
+	require.Equal(t, 1, len(received.ResourceSpans))
+	resource := received.ResourceSpans[0]
+	require.Equal(t, 1, len(resource.Resource.Attributes))
+	assert.Equal(t, "service.name", resource.Resource.Attributes[0].Key)
+	assert.Equal(t, "test-service", *resource.Resource.Attributes[0].Value.StringValue)
+
+	require.Equal(t, 1, len(resource.ScopeSpans))
+	spans := resource.ScopeSpans[0].Spans
+	require.Equal(t, 2, len(spans))
+	assert.Equal(t, testTraceID, spans[0].TraceID)
+	assert.Equal(t, "1000", spans[0].StartTimeUnixNano)
+	assert.Equal(t, "2000", spans[0].EndTimeUnixNano)
+	assert.Equal(t, StatusError, spans[0].Status.Code)
+	require.Equal(t, 1, len(spans[0].Attributes))
+	assert.Equal(t, "10", *spans[0].Attributes[0].Value.IntValue)
+	assert.Equal(t, testSpanID, spans[1].ParentSpanID)
+	assert.Equal(t, SpanKindClient, spans[1].Kind)
+
+	statusCode = http.StatusBadRequest
+	assert.Error(t, exporter.ExportSpans(context.Background(), testSpans()))
+}
@@ -0,0 +1,127 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TraceParentHeader is the W3C Trace Context header used to propagate the trace to other services
+const TraceParentHeader = "traceparent"
+
+const (
+	traceParentVersion = "00"
+	traceParentSampled = "01"
+	zeroTraceID        = "00000000000000000000000000000000"
+	zeroSpanID         = "0000000000000000"
+)
+
+// FormatTraceParent formats the SpanContext as the value of the W3C traceparent header
+func FormatTraceParent(spanContext SpanContext) string {
+	return strings.Join([]string{traceParentVersion, spanContext.TraceID, spanContext.SpanID, traceParentSampled}, "-")
+}
+
+// ParseTraceParent parses the value of the W3C traceparent header
+func ParseTraceParent(value string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("traceparent '%s' must have 4 parts", value)
+	}
+
+	if parts[0] != traceParentVersion {
+		return SpanContext{}, fmt.Errorf("traceparent version '%s' is not supported", parts[0])
+	}
+
+	traceID := parts[1]
+	spanID := parts[2]
+	if !isHex(traceID, 32) || traceID == zeroTraceID {
+		return SpanContext{}, fmt.Errorf("traceparent trace-id '%s' is invalid", traceID)
+	}
+
+	if !isHex(spanID, 16) || spanID == zeroSpanID {
+		return SpanContext{}, fmt.Errorf("traceparent parent-id '%s' is invalid", spanID)
+	}
+
+	return SpanContext{TraceID: traceID, SpanID: spanID}, nil
+}
+
+// Inject sets the traceparent header for the span carried by ctx, if any
+func Inject(ctx context.Context, header http.Header) {
+	spanContext := SpanContextFromContext(ctx)
+	if !spanContext.IsValid() || len(spanContext.SpanID) == 0 {
+		return
+	}
+
+	header.Set(TraceParentHeader, FormatTraceParent(spanContext))
+}
+
+// Extract returns the SpanContext from the traceparent header. False is returned if the header is missing or invalid.
+func Extract(header http.Header) (SpanContext, bool) {
+	value := header.Get(TraceParentHeader)
+	if len(value) == 0 {
+		return SpanContext{}, false
+	}
+
+	spanContext, err := ParseTraceParent(value)
+	return spanContext, err == nil
+}
+
+// TraceIDFromCorrelationID derives the trace ID from the correlation ID so that all the spans for a message are in
+// the same trace, even across services which don't propagate the traceparent header. A UUID correlation ID is used as
+// is, any other value is hashed. An empty string is returned for an empty correlation ID.
+func TraceIDFromCorrelationID(correlationID string) string {
+	if len(correlationID) == 0 {
+		return ""
+	}
+
+	traceID := strings.ToLower(strings.Replace(correlationID, "-", "", -1))
+	if isHex(traceID, 32) && traceID != zeroTraceID {
+		return traceID
+	}
+
+	hash := sha256.Sum256([]byte(correlationID))
+	return hex.EncodeToString(hash[:16])
+}
+
+// ContextWithCorrelationID returns a copy of ctx whose spans are in the trace derived from the correlation ID, unless
+// ctx already carries a span or remote parent.
+func ContextWithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	if SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	return ContextWithRemoteParent(ctx, SpanContext{TraceID: TraceIDFromCorrelationID(correlationID)})
+}
+
+func isHex(value string, length int) bool {
+	if len(value) != length {
+		return false
+	}
+
+	for _, char := range value {
+		if !(char >= '0' && char <= '9') && !(char >= 'a' && char <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
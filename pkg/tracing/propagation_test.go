@@ -0,0 +1,93 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expectError bool
+	}{
+		{"Valid", "00-" + testTraceID + "-" + testSpanID + "-01", false},
+		{"Valid not sampled", "00-" + testTraceID + "-" + testSpanID + "-00", false},
+		{"Missing parts", "00-" + testTraceID, true},
+		{"Unsupported version", "ff-" + testTraceID + "-" + testSpanID + "-01", true},
+		{"Zero trace ID", "00-" + zeroTraceID + "-" + testSpanID + "-01", true},
+		{"Upper case trace ID", "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + testSpanID + "-01", true},
+		{"Short span ID", "00-" + testTraceID + "-00f067aa-01", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			spanContext, err := ParseTraceParent(test.value)
+			if test.expectError {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, SpanContext{TraceID: testTraceID, SpanID: testSpanID}, spanContext)
+		})
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	header := http.Header{}
+
+	Inject(context.Background(), header)
+	assert.Empty(t, header.Get(TraceParentHeader), "Expected no header without a span")
+	_, ok := Extract(header)
+	assert.False(t, ok)
+
+	expected := SpanContext{TraceID: testTraceID, SpanID: testSpanID}
+	Inject(ContextWithRemoteParent(context.Background(), expected), header)
+	assert.Equal(t, "00-"+testTraceID+"-"+testSpanID+"-01", header.Get(TraceParentHeader))
+
+	actual, ok := Extract(header)
+	require.True(t, ok)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTraceIDFromCorrelationID(t *testing.T) {
+	assert.Empty(t, TraceIDFromCorrelationID(""))
+	assert.Equal(t, testTraceID, TraceIDFromCorrelationID("4BF92F35-77B3-4DA6-A3CE-929D0E0E4736"))
+
+	hashed := TraceIDFromCorrelationID("not-a-uuid")
+	assert.True(t, SpanContext{TraceID: hashed}.IsValid())
+	assert.Equal(t, hashed, TraceIDFromCorrelationID("not-a-uuid"), "Expected the same trace ID for the same correlation ID")
+
+	ctx := ContextWithCorrelationID(context.Background(), "4bf92f35-77b3-4da6-a3ce-929d0e0e4736")
+	assert.Equal(t, SpanContext{TraceID: testTraceID}, SpanContextFromContext(ctx))
+
+	parent := SpanContext{TraceID: hashed, SpanID: testSpanID}
+	ctx = ContextWithCorrelationID(ContextWithRemoteParent(context.Background(), parent), "4bf92f35-77b3-4da6-a3ce-929d0e0e4736")
+	assert.Equal(t, parent, SpanContextFromContext(ctx), "Expected the remote parent to take precedence")
+}
@@ -0,0 +1,188 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+)
+
+const (
+	defaultQueueSize     = 2048
+	defaultBatchSize     = 256
+	defaultFlushInterval = 5 * time.Second
+	exportTimeout        = 10 * time.Second
+)
+
+// Exporter sends the ended spans to a tracing backend
+type Exporter interface {
+	// ExportSpans exports a batch of ended spans
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	// Shutdown releases any resources held by the exporter once the last batch has been exported
+	Shutdown(ctx context.Context) error
+}
+
+// Tracer queues ended spans and exports them in batches in the background so that exporting doesn't slow down the
+// pipeline. Spans are dropped, rather than blocking the pipeline, when the queue is full.
+type Tracer struct {
+	exporter      Exporter
+	lc            logger.LoggingClient
+	queue         chan SpanData
+	batchSize     int
+	flushInterval time.Duration
+	stop          chan struct{}
+	stopOnce      sync.Once
+	done          chan struct{}
+	droppedLock   sync.Mutex
+	dropped       int
+}
+
+// NewTracer creates a Tracer and starts exporting spans with the exporter
+func NewTracer(exporter Exporter, lc logger.LoggingClient) *Tracer {
+	return newTracer(exporter, lc, defaultQueueSize, defaultBatchSize, defaultFlushInterval)
+}
+
+func newTracer(exporter Exporter, lc logger.LoggingClient, queueSize int, batchSize int,
+	flushInterval time.Duration) *Tracer {
+
+	tracer := &Tracer{
+		exporter:      exporter,
+		lc:            lc,
+		queue:         make(chan SpanData, queueSize),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+
+	go tracer.exportLoop()
+
+	return tracer
+}
+
+// Shutdown exports the queued spans and shuts down the exporter. Spans ended after Shutdown are dropped.
+func (tracer *Tracer) Shutdown() {
+	tracer.stopOnce.Do(func() {
+		close(tracer.stop)
+		<-tracer.done
+
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		defer cancel()
+		if err := tracer.exporter.Shutdown(ctx); err != nil {
+			tracer.lc.Error("Unable to shut down tracing exporter", "error", err.Error())
+		}
+	})
+}
+
+func (tracer *Tracer) enqueue(data SpanData) {
+	select {
+	case <-tracer.stop:
+		return
+	default:
+	}
+
+	select {
+	case tracer.queue <- data:
+	default:
+		tracer.droppedLock.Lock()
+		tracer.dropped++
+		tracer.droppedLock.Unlock()
+	}
+}
+
+func (tracer *Tracer) exportLoop() {
+	defer close(tracer.done)
+
+	ticker := time.NewTicker(tracer.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]SpanData, 0, tracer.batchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			tracer.export(batch)
+			batch = make([]SpanData, 0, tracer.batchSize)
+		}
+	}
+
+	for {
+		select {
+		case data := <-tracer.queue:
+			batch = append(batch, data)
+			if len(batch) >= tracer.batchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-tracer.stop:
+			for {
+				select {
+				case data := <-tracer.queue:
+					batch = append(batch, data)
+					if len(batch) >= tracer.batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (tracer *Tracer) export(batch []SpanData) {
+	tracer.droppedLock.Lock()
+	dropped := tracer.dropped
+	tracer.dropped = 0
+	tracer.droppedLock.Unlock()
+
+	if dropped > 0 {
+		tracer.lc.Warn(fmt.Sprintf("Dropped %d spans since the tracing queue was full", dropped))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	if err := tracer.exporter.ExportSpans(ctx, batch); err != nil {
+		tracer.lc.Error(fmt.Sprintf("Unable to export %d spans", len(batch)), "error", err.Error())
+	}
+}
+
+var globalTracer struct {
+	lock   sync.RWMutex
+	tracer *Tracer
+}
+
+// SetTracer sets the Tracer used to record spans. Setting nil disables tracing.
+func SetTracer(tracer *Tracer) {
+	globalTracer.lock.Lock()
+	defer globalTracer.lock.Unlock()
+	globalTracer.tracer = tracer
+}
+
+// GetTracer returns the Tracer used to record spans, or nil if tracing is disabled
+func GetTracer() *Tracer {
+	globalTracer.lock.RLock()
+	defer globalTracer.lock.RUnlock()
+	return globalTracer.tracer
+}
@@ -0,0 +1,235 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+// Package tracing records spans for the execution of the function pipelines and exports them with a pluggable
+// Exporter. Spans are only recorded once a Tracer has been set with SetTracer, otherwise all operations are no-ops.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship of the span to its parent, using the OpenTelemetry values
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// StatusCode is the outcome of the span's operation, using the OpenTelemetry values
+type StatusCode int
+
+const (
+	StatusUnset StatusCode = 0
+	StatusOk    StatusCode = 1
+	StatusError StatusCode = 2
+)
+
+// Common attribute keys
+const (
+	AttributeCorrelationID    = "correlation.id"
+	AttributePipelineID       = "pipeline.id"
+	AttributeFunctionName     = "function.name"
+	AttributeFunctionPosition = "function.position"
+	AttributePayloadSize      = "payload.size"
+	AttributeOutcome          = "outcome"
+	AttributeTopic            = "messaging.destination"
+	AttributeHTTPURL          = "http.url"
+	AttributeHTTPMethod       = "http.method"
+	AttributeHTTPStatusCode   = "http.status_code"
+)
+
+// SpanContext identifies a span within a trace. TraceID and SpanID are lower case hex strings of 16 and 8 bytes.
+type SpanContext struct {
+	TraceID string
+	SpanID  string
+}
+
+// IsValid returns true if the SpanContext has a TraceID. A SpanContext without SpanID only identifies the trace.
+func (spanContext SpanContext) IsValid() bool {
+	return len(spanContext.TraceID) == 32
+}
+
+// SpanData is the recorded span passed to the Exporter
+type SpanData struct {
+	TraceID       string                 `json:"traceId"`
+	SpanID        string                 `json:"spanId"`
+	ParentSpanID  string                 `json:"parentSpanId,omitempty"`
+	Name          string                 `json:"name"`
+	Kind          SpanKind               `json:"kind"`
+	StartTime     time.Time              `json:"startTime"`
+	EndTime       time.Time              `json:"endTime"`
+	Attributes    map[string]interface{} `json:"attributes,omitempty"`
+	Status        StatusCode             `json:"status"`
+	StatusMessage string                 `json:"statusMessage,omitempty"`
+}
+
+// Span is an operation being traced. All methods are safe to call on a nil Span, which is returned when
+// tracing is disabled.
+type Span struct {
+	tracer *Tracer
+	mutex  sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+type spanKey struct{}
+type remoteParentKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying the span, which becomes the parent of spans started from it
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by ctx, or nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithRemoteParent returns a copy of ctx carrying the SpanContext received from another service, which becomes
+// the parent of spans started from it when ctx doesn't carry a span.
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	if !parent.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteParentKey{}, parent)
+}
+
+// SpanContextFromContext returns the SpanContext of the span carried by ctx, otherwise the remote parent
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+
+	parent, _ := ctx.Value(remoteParentKey{}).(SpanContext)
+	return parent
+}
+
+// StartSpan starts a span which is a child of the span, or remote parent, carried by ctx. A new trace is started
+// when ctx doesn't carry either. The returned context carries the new span. The span is nil when tracing is disabled.
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	tracer := GetTracer()
+	if tracer == nil {
+		return ctx, nil
+	}
+
+	parent := SpanContextFromContext(ctx)
+	traceID := parent.TraceID
+	if !parent.IsValid() {
+		traceID = newID(16)
+	}
+
+	span := &Span{
+		tracer: tracer,
+		data: SpanData{
+			TraceID:      traceID,
+			SpanID:       newID(8),
+			ParentSpanID: parent.SpanID,
+			Name:         name,
+			Kind:         kind,
+			StartTime:    time.Now(),
+			Attributes:   make(map[string]interface{}),
+		},
+	}
+
+	return ContextWithSpan(ctx, span), span
+}
+
+// SpanContext returns the identity of the span
+func (span *Span) SpanContext() SpanContext {
+	if span == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: span.data.TraceID, SpanID: span.data.SpanID}
+}
+
+// SetAttribute sets the attribute on the span. Values should be strings, booleans or numbers.
+// Attributes set after the span has ended are ignored.
+func (span *Span) SetAttribute(key string, value interface{}) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	if !span.ended {
+		span.data.Attributes[key] = value
+	}
+}
+
+// SetStatus sets the outcome of the span's operation
+func (span *Span) SetStatus(code StatusCode, message string) {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	if span.ended {
+		return
+	}
+	span.data.Status = code
+	span.data.StatusMessage = message
+}
+
+// SetError sets the span's status to error with the error's message. Nothing is set for a nil error.
+func (span *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	span.SetStatus(StatusError, err.Error())
+}
+
+// End completes the span and queues it for export. Calls after the first have no effect.
+func (span *Span) End() {
+	if span == nil {
+		return
+	}
+
+	span.mutex.Lock()
+	if span.ended {
+		span.mutex.Unlock()
+		return
+	}
+	span.ended = true
+	span.data.EndTime = time.Now()
+	data := span.data
+	span.mutex.Unlock()
+
+	span.tracer.enqueue(data)
+}
+
+func newID(size int) string {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		// Only fails if the OS random source is unavailable, so fall back to the time since an all zero ID is invalid
+		binary.BigEndian.PutUint64(id[size-8:], uint64(time.Now().UnixNano()))
+	}
+	return hex.EncodeToString(id)
+}
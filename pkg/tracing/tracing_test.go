@@ -0,0 +1,142 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var lc = logger.NewMockClient()
+
+type recordingExporter struct {
+	lock     sync.Mutex
+	batches  [][]SpanData
+	shutdown bool
+}
+
+func (exporter *recordingExporter) ExportSpans(_ context.Context, spans []SpanData) error {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	exporter.batches = append(exporter.batches, spans)
+	return nil
+}
+
+func (exporter *recordingExporter) Shutdown(_ context.Context) error {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+	exporter.shutdown = true
+	return nil
+}
+
+func (exporter *recordingExporter) spans() []SpanData {
+	exporter.lock.Lock()
+	defer exporter.lock.Unlock()
+
+	var spans []SpanData
+	for _, batch := range exporter.batches {
+		spans = append(spans, batch...)
+	}
+	return spans
+}
+
+func TestStartSpanDisabled(t *testing.T) {
+	SetTracer(nil)
+
+	ctx, span := StartSpan(context.Background(), "disabled", SpanKindInternal)
+
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+	// Must be safe to use the nil span
+	span.SetAttribute("key", "value")
+	span.SetError(errors.New("failed"))
+	span.End()
+	assert.False(t, span.SpanContext().IsValid())
+}
+
+func TestStartSpan(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := NewTracer(exporter, lc)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	parentCtx, parent := StartSpan(context.Background(), "parent", SpanKindServer)
+	_, child := StartSpan(parentCtx, "child", SpanKindInternal)
+	child.SetAttribute(AttributePayloadSize, 10)
+	child.SetError(errors.New("failed"))
+	child.End()
+	child.SetAttribute("ignored", true)
+	parent.End()
+
+	remoteCtx := ContextWithRemoteParent(context.Background(), parent.SpanContext())
+	_, remoteChild := StartSpan(remoteCtx, "remote child", SpanKindConsumer)
+	remoteChild.End()
+
+	tracer.Shutdown()
+	require.True(t, exporter.shutdown)
+
+	spans := exporter.spans()
+	require.Equal(t, 3, len(spans))
+
+	childData := spans[0]
+	parentData := spans[1]
+	remoteData := spans[2]
+
+	assert.Equal(t, "parent", parentData.Name)
+	assert.True(t, parent.SpanContext().IsValid())
+	assert.Empty(t, parentData.ParentSpanID)
+	assert.Equal(t, SpanKindServer, parentData.Kind)
+	assert.Equal(t, parentData.TraceID, childData.TraceID)
+	assert.Equal(t, parentData.SpanID, childData.ParentSpanID)
+	assert.Equal(t, 10, childData.Attributes[AttributePayloadSize])
+	assert.NotContains(t, childData.Attributes, "ignored")
+	assert.Equal(t, StatusError, childData.Status)
+	assert.Equal(t, "failed", childData.StatusMessage)
+	assert.False(t, childData.EndTime.Before(childData.StartTime))
+	assert.Equal(t, parentData.TraceID, remoteData.TraceID)
+	assert.Equal(t, parentData.SpanID, remoteData.ParentSpanID)
+}
+
+func TestTracerBatching(t *testing.T) {
+	exporter := &recordingExporter{}
+	tracer := newTracer(exporter, lc, 10, 2, time.Hour)
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	for i := 0; i < 5; i++ {
+		_, span := StartSpan(context.Background(), "span", SpanKindInternal)
+		span.End()
+	}
+
+	tracer.Shutdown()
+
+	// Full batches are exported as they fill and the remaining span when shut down
+	require.Equal(t, 3, len(exporter.batches))
+	assert.Equal(t, 2, len(exporter.batches[0]))
+	assert.Equal(t, 1, len(exporter.batches[2]))
+
+	_, span := StartSpan(context.Background(), "after shutdown", SpanKindInternal)
+	span.End()
+	assert.Equal(t, 5, len(exporter.spans()), "Spans ended after shutdown must be dropped")
+}
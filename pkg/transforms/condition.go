@@ -0,0 +1,472 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/diegoholiveira/jsonlogic"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/util"
+)
+
+// Names of the values a condition is evaluated over
+const (
+	// ConditionResult is the current result, decoded from JSON when it is JSON, i.e. 'result.device'
+	ConditionResult = "result"
+	// ConditionSize is the size in bytes of the current result
+	ConditionSize = "size"
+	// ConditionContentType is the content type of the received message
+	ConditionContentType = "contenttype"
+	// ConditionCorrelationID is the correlation id of the received message
+	ConditionCorrelationID = "correlationid"
+	// ConditionReceivedTopic is the topic the message was received on
+	ConditionReceivedTopic = "receivedtopic"
+	// ConditionValues are the Context's values keyed by their key, i.e. 'values.level'
+	ConditionValues = "values"
+)
+
+var conditionRoots = map[string]bool{
+	ConditionResult:        true,
+	ConditionSize:          true,
+	ConditionContentType:   true,
+	ConditionCorrelationID: true,
+	ConditionReceivedTopic: true,
+	ConditionValues:        true,
+}
+
+// Condition determines whether a pipeline function is executed. The expression is either a JSONLogic rule, when it
+// is a JSON object, or a simple expression such as 'size > 1024 && values.level == "high"'. Both are evaluated over
+// the result of the previous function and the Context, see the Condition* names.
+//
+// A simple expression compares a name with a literal using ==, !=, >, >=, < or <=, or tests that the name's value is
+// truthy when used alone. Comparisons are combined with && and ||, where && takes precedence. Literals are numbers,
+// quoted strings, true, false and null.
+type Condition struct {
+	Expression string
+	rule       []byte
+	anyOf      [][]conditionTerm
+}
+
+// conditionTerm is a comparison of a simple expression
+type conditionTerm struct {
+	path     []string
+	operator string
+	literal  interface{}
+}
+
+// NewCondition parses the expression and returns the Condition, or an error if the expression is invalid
+func NewCondition(expression string) (Condition, error) {
+	condition := Condition{Expression: expression}
+
+	trimmed := strings.TrimSpace(expression)
+	if len(trimmed) == 0 {
+		return condition, errors.New("condition expression can not be empty")
+	}
+
+	if strings.HasPrefix(trimmed, "{") {
+		if !json.Valid([]byte(trimmed)) {
+			return condition, errors.New("condition JSONLogic rule is not valid JSON")
+		}
+		condition.rule = []byte(trimmed)
+		return condition, nil
+	}
+
+	anyOf, err := parseCondition(trimmed)
+	if err != nil {
+		return condition, fmt.Errorf("invalid condition '%s': %w", expression, err)
+	}
+	condition.anyOf = anyOf
+
+	return condition, nil
+}
+
+// Wrap returns a function which only executes the function when the condition is true. When the condition is false
+// the function is skipped and its input is passed on unchanged to the next function in the pipeline. The function's
+// name is reported in the metrics and spans of the returned function.
+func (condition Condition) Wrap(function appcontext.AppFunction) appcontext.AppFunction {
+	return appcontext.WrapFunction(func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		if len(params) < 1 {
+			return function(edgexcontext, params...)
+		}
+
+		matches, err := condition.Matches(edgexcontext, params[0])
+		if err != nil {
+			return false, fmt.Errorf("unable to evaluate condition: %w", err)
+		}
+
+		if !matches {
+			edgexcontext.LoggingClient.Debug(fmt.Sprintf("Condition '%s' not met, skipping function",
+				condition.Expression))
+			return true, params[0]
+		}
+
+		return function(edgexcontext, params...)
+	}, function)
+}
+
+// Matches returns true if the condition is true for the data and the Context
+func (condition Condition) Matches(edgexcontext *appcontext.Context, data interface{}) (bool, error) {
+	values, err := conditionValues(edgexcontext, data)
+	if err != nil {
+		return false, err
+	}
+
+	if condition.rule != nil {
+		return condition.matchesRule(values)
+	}
+
+	for _, allOf := range condition.anyOf {
+		matches := true
+		for _, term := range allOf {
+			if !term.matches(values) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (condition Condition) matchesRule(values map[string]interface{}) (bool, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return false, err
+	}
+
+	var output bytes.Buffer
+	if err := jsonlogic.Apply(bytes.NewReader(condition.rule), bytes.NewReader(data), &output); err != nil {
+		return false, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(output.Bytes(), &result); err != nil {
+		return false, err
+	}
+
+	return isTruthy(result), nil
+}
+
+// conditionValues returns the values the condition is evaluated over
+func conditionValues(edgexcontext *appcontext.Context, data interface{}) (map[string]interface{}, error) {
+	payload, err := util.CoerceType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(payload, &result); err != nil {
+		result = string(payload)
+	}
+
+	values := make(map[string]interface{})
+	for _, key := range edgexcontext.ValueKeys() {
+		var value interface{}
+		if _, err := edgexcontext.GetValue(key, &value); err == nil {
+			values[key] = value
+		}
+	}
+
+	_, contentType := edgexcontext.ReceivedMessage()
+
+	return map[string]interface{}{
+		ConditionResult:        result,
+		ConditionSize:          len(payload),
+		ConditionContentType:   contentType,
+		ConditionCorrelationID: edgexcontext.CorrelationID,
+		ConditionReceivedTopic: edgexcontext.ReceivedTopic,
+		ConditionValues:        values,
+	}, nil
+}
+
+func (term conditionTerm) matches(values map[string]interface{}) bool {
+	value := lookupPath(values, term.path)
+
+	switch term.operator {
+	case "":
+		return isTruthy(value)
+	case "==":
+		return conditionEquals(value, term.literal)
+	case "!=":
+		return !conditionEquals(value, term.literal)
+	}
+
+	if left, ok := toNumber(value); ok {
+		right, ok := toNumber(term.literal)
+		return ok && compareOrdered(term.operator, left < right, left == right)
+	}
+
+	left, leftOk := value.(string)
+	right, rightOk := term.literal.(string)
+	return leftOk && rightOk && compareOrdered(term.operator, left < right, left == right)
+}
+
+// lookupPath returns the value at the path, or nil if there is none. The key of a Context value is the rest of the
+// path, since keys may contain dots, i.e. 'values.header.content-type'.
+func lookupPath(values map[string]interface{}, path []string) interface{} {
+	if path[0] == ConditionValues && len(path) > 1 {
+		contextValues, _ := values[ConditionValues].(map[string]interface{})
+		return contextValues[strings.Join(path[1:], ".")]
+	}
+
+	var current interface{} = values
+	for _, name := range path {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			current = typed[name]
+		case []interface{}:
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil
+			}
+			current = typed[index]
+		default:
+			return nil
+		}
+	}
+
+	return current
+}
+
+func compareOrdered(operator string, less bool, equal bool) bool {
+	switch operator {
+	case ">":
+		return !less && !equal
+	case ">=":
+		return !less
+	case "<":
+		return less
+	case "<=":
+		return less || equal
+	}
+
+	return false
+}
+
+func conditionEquals(value interface{}, literal interface{}) bool {
+	if left, ok := toNumber(value); ok {
+		right, ok := toNumber(literal)
+		return ok && left == right
+	}
+
+	return value == literal
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch typed := value.(type) {
+	case float64:
+		return typed, true
+	case int:
+		return float64(typed), true
+	}
+
+	return 0, false
+}
+
+// isTruthy follows the JSONLogic definition of truth, where false, null, 0, "" and empty arrays are false
+func isTruthy(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case float64:
+		return typed != 0
+	case int:
+		return typed != 0
+	case string:
+		return len(typed) > 0
+	case []interface{}:
+		return len(typed) > 0
+	}
+
+	return true
+}
+
+// parseCondition parses the simple expression into the groups of terms joined by ||, each of which are joined by &&
+func parseCondition(expression string) ([][]conditionTerm, error) {
+	tokens, err := tokenizeCondition(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	anyOf := [][]conditionTerm{nil}
+	for index := 0; index < len(tokens); {
+		name := tokens[index]
+		if name.kind != conditionName {
+			return nil, fmt.Errorf("expected a name but found '%s'", name.text)
+		}
+
+		path := strings.Split(name.text, ".")
+		if !conditionRoots[path[0]] {
+			return nil, fmt.Errorf("unknown name '%s'", path[0])
+		}
+
+		term := conditionTerm{path: path}
+		index++
+
+		if index < len(tokens) && tokens[index].kind == conditionComparison {
+			term.operator = tokens[index].text
+			index++
+			if index >= len(tokens) || tokens[index].kind != conditionLiteral {
+				return nil, fmt.Errorf("expected a literal after '%s'", term.operator)
+			}
+			term.literal = tokens[index].literal
+			index++
+		}
+
+		last := len(anyOf) - 1
+		anyOf[last] = append(anyOf[last], term)
+
+		if index == len(tokens) {
+			break
+		}
+
+		switch tokens[index].text {
+		case "&&":
+		case "||":
+			anyOf = append(anyOf, nil)
+		default:
+			return nil, fmt.Errorf("expected && or || but found '%s'", tokens[index].text)
+		}
+
+		index++
+		if index == len(tokens) {
+			return nil, fmt.Errorf("expected a name after '%s'", tokens[index-1].text)
+		}
+	}
+
+	return anyOf, nil
+}
+
+type conditionTokenKind int
+
+const (
+	conditionName conditionTokenKind = iota
+	conditionLiteral
+	conditionComparison
+	conditionLogical
+)
+
+type conditionToken struct {
+	kind    conditionTokenKind
+	text    string
+	literal interface{}
+}
+
+func tokenizeCondition(expression string) ([]conditionToken, error) {
+	var tokens []conditionToken
+
+	for position := 0; position < len(expression); {
+		char := expression[position]
+		rest := expression[position:]
+
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			position++
+
+		case strings.HasPrefix(rest, "&&") || strings.HasPrefix(rest, "||"):
+			tokens = append(tokens, conditionToken{kind: conditionLogical, text: rest[:2]})
+			position += 2
+
+		case strings.HasPrefix(rest, "==") || strings.HasPrefix(rest, "!=") ||
+			strings.HasPrefix(rest, ">=") || strings.HasPrefix(rest, "<="):
+			tokens = append(tokens, conditionToken{kind: conditionComparison, text: rest[:2]})
+			position += 2
+
+		case char == '>' || char == '<':
+			tokens = append(tokens, conditionToken{kind: conditionComparison, text: rest[:1]})
+			position++
+
+		case char == '"' || char == '\'':
+			end := 1
+			for end < len(rest) && rest[end] != char {
+				if char == '"' && rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, errors.New("unterminated string")
+			}
+
+			text := rest[:end+1]
+			value := text[1 : len(text)-1]
+			if char == '"' {
+				unquoted, err := strconv.Unquote(text)
+				if err != nil {
+					return nil, fmt.Errorf("invalid string %s", text)
+				}
+				value = unquoted
+			}
+			tokens = append(tokens, conditionToken{kind: conditionLiteral, text: text, literal: value})
+			position += len(text)
+
+		case char == '-' || (char >= '0' && char <= '9'):
+			end := 1
+			for end < len(rest) && strings.IndexByte("0123456789.eE+-", rest[end]) >= 0 {
+				end++
+			}
+			number, err := strconv.ParseFloat(rest[:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number '%s'", rest[:end])
+			}
+			tokens = append(tokens, conditionToken{kind: conditionLiteral, text: rest[:end], literal: number})
+			position += end
+
+		case isConditionNameChar(char) && char != '.' && char != '-':
+			end := 1
+			for end < len(rest) && isConditionNameChar(rest[end]) {
+				end++
+			}
+			text := rest[:end]
+			switch text {
+			case "true", "false":
+				tokens = append(tokens, conditionToken{kind: conditionLiteral, text: text, literal: text == "true"})
+			case "null":
+				tokens = append(tokens, conditionToken{kind: conditionLiteral, text: text})
+			default:
+				tokens = append(tokens, conditionToken{kind: conditionName, text: text})
+			}
+			position += end
+
+		default:
+			return nil, fmt.Errorf("unexpected character '%c'", char)
+		}
+	}
+
+	if len(tokens) == 0 {
+		return nil, errors.New("expression is empty")
+	}
+
+	return tokens, nil
+}
+
+func isConditionNameChar(char byte) bool {
+	return char == '_' || char == '.' || char == '-' || (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') ||
+		(char >= '0' && char <= '9')
+}
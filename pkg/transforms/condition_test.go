@@ -0,0 +1,123 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+)
+
+func TestConditionMatches(t *testing.T) {
+	edgexcontext := &appcontext.Context{
+		LoggingClient: context.LoggingClient,
+		CorrelationID: "123-abc",
+	}
+	require.NoError(t, edgexcontext.SetValue("level", "high"))
+	require.NoError(t, edgexcontext.SetValue(appcontext.ValueKeyHTTPHeaderPrefix+"x-priority", 3))
+
+	data := `{"device":"Random-Float-Device","readings":[{"name":"Float32","value":"12.5"}],"origin":1500}`
+
+	tests := []struct {
+		Name       string
+		Expression string
+		Expected   bool
+	}{
+		{"Size", "size > 64", true},
+		{"Size too small", "size > 1024", false},
+		{"Result field", `result.device == "Random-Float-Device"`, true},
+		{"Result array", `result.readings.0.name == 'Float32'`, true},
+		{"Result number", "result.origin >= 1500 && result.origin < 2000", true},
+		{"Missing result field", "result.missing == null", true},
+		{"Truthy", "result.device", true},
+		{"Falsy", "result.missing", false},
+		{"Context value", `values.level != "low"`, true},
+		{"Header value", "values.header.x-priority > 2", true},
+		{"Correlation ID", `correlationid == "123-abc"`, true},
+		{"And", `size > 64 && values.level == "low"`, false},
+		{"Or", `size > 1024 || values.level == "high"`, true},
+		{"JSONLogic", `{"==": [{"var": "result.device"}, "Random-Float-Device"]}`, true},
+		{"JSONLogic false", `{">": [{"var": "size"}, 1024]}`, false},
+		{"JSONLogic context value", `{"in": [{"var": "values.level"}, ["high", "critical"]]}`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			condition, err := NewCondition(test.Expression)
+			require.NoError(t, err)
+
+			actual, err := condition.Matches(edgexcontext, data)
+			require.NoError(t, err)
+			assert.Equal(t, test.Expected, actual)
+		})
+	}
+}
+
+func TestNewConditionInvalid(t *testing.T) {
+	tests := []struct {
+		Name       string
+		Expression string
+	}{
+		{"Empty", " "},
+		{"Unknown name", "bogus == 1"},
+		{"Missing literal", "size >"},
+		{"Missing operator", "size 1024"},
+		{"Trailing logical", "size > 1 &&"},
+		{"Unterminated string", `result.device == "Random`},
+		{"Unsupported operator", "size ~ 1"},
+		{"Invalid JSONLogic", `{"==": [1, 1]`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			_, err := NewCondition(test.Expression)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestConditionWrap(t *testing.T) {
+	called := false
+	function := func(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+		called = true
+		return true, "compressed"
+	}
+
+	condition, err := NewCondition("size > 10")
+	require.NoError(t, err)
+	wrapped := condition.Wrap(function)
+
+	continuePipeline, result := wrapped(context, "small")
+	assert.True(t, continuePipeline)
+	assert.Equal(t, "small", result, "Input should be passed through when the condition is false")
+	assert.False(t, called)
+
+	continuePipeline, result = wrapped(context, "much larger than ten bytes")
+	assert.True(t, continuePipeline)
+	assert.Equal(t, "compressed", result)
+	assert.True(t, called)
+
+	continuePipeline, result = wrapped(context, make(chan int))
+	assert.False(t, continuePipeline)
+	assert.Equal(t, appcontext.ErrorKindUnclassified, appcontext.ErrorKindOf(result.(error)))
+
+	assert.Equal(t, appcontext.FunctionName(function), appcontext.FunctionName(wrapped),
+		"Wrapped function's name should be reported in the metrics and spans")
+}
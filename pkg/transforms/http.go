@@ -26,6 +26,7 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
 	"github.com/student3671/app-functions-sdk-go/pkg/util"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
@@ -77,7 +78,8 @@ func NewHTTPSSender(url string, mimeType string, persistOnError bool, certfile s
 // HTTPPost will send data from the previous function to the specified Endpoint via http POST.
 // If no previous function exists, then the event that triggered the pipeline will be used.
 // An empty string for the mimetype will default to application/json.
-func (sender HTTPSender) HTTPPost(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+// When tracing is enabled the trace is propagated to the Endpoint with the W3C traceparent header.
+func (sender HTTPSender) HTTPPost(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
 		return false, errors.New("No Data Received")
@@ -98,10 +100,16 @@ func (sender HTTPSender) HTTPPost(edgexcontext *appcontext.Context, params ...in
 	}
 
 	client := &http.Client{}
-	req, err := http.NewRequest(http.MethodPost, sender.URL, bytes.NewReader(exportData))
+	ctx, span := startExportSpan(edgexcontext, "http export", tracing.SpanKindClient, exportData)
+	defer func() { endExportSpan(span, result) }()
+	span.SetAttribute(tracing.AttributeHTTPURL, sender.URL)
+	span.SetAttribute(tracing.AttributeHTTPMethod, http.MethodPost)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.URL, bytes.NewReader(exportData))
 	if err != nil {
 		return false, err
 	}
+	tracing.Inject(ctx, req.Header)
 	var theSecrets map[string]string
 	if usingSecrets {
 		theSecrets, err = edgexcontext.GetSecrets(sender.SecretPath, sender.SecretHeaderName)
@@ -117,15 +125,16 @@ func (sender HTTPSender) HTTPPost(edgexcontext *appcontext.Context, params ...in
 	response, err := client.Do(req)
 	if err != nil {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, err
+		return false, appcontext.NewRetryableError(err)
 	}
 	defer response.Body.Close()
+	span.SetAttribute(tracing.AttributeHTTPStatusCode, response.StatusCode)
 	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Response: %s", response.Status))
 	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Sent data: %s", string(exportData)))
 	bodyBytes, errReadingBody := ioutil.ReadAll(response.Body)
 	if errReadingBody != nil {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, errReadingBody
+		return false, appcontext.NewRetryableError(errReadingBody)
 	}
 
 	edgexcontext.LoggingClient.Trace("Data exported", "Transport", "HTTP", clients.CorrelationHeader, edgexcontext.CorrelationID)
@@ -133,7 +142,8 @@ func (sender HTTPSender) HTTPPost(edgexcontext *appcontext.Context, params ...in
 	// continues the pipeline if we get a 2xx response, stops pipeline if non-2xx response
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, fmt.Errorf("export failed with %d HTTP status code", response.StatusCode)
+		return false, classifyStatusCode(response.StatusCode,
+			fmt.Errorf("export failed with %d HTTP status code", response.StatusCode))
 	}
 
 	return true, bodyBytes
@@ -166,7 +176,8 @@ func (sender HTTPSender) setRetryData(ctx *appcontext.Context, exportData []byte
 // HTTPPost will send data from the previous function to the specified Endpoint via http POST.
 // If no previous function exists, then the event that triggered the pipeline will be used.
 // An empty string for the mimetype will default to application/json.
-func (sender HTTPSender) HTTPSPost(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+// When tracing is enabled the trace is propagated to the Endpoint with the W3C traceparent header.
+func (sender HTTPSender) HTTPSPost(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
 		return false, errors.New("No Data Received")
@@ -209,10 +220,16 @@ func (sender HTTPSender) HTTPSPost(edgexcontext *appcontext.Context, params ...i
 	transport := &http.Transport{TLSClientConfig: tlsConfig}
 
 	client := &http.Client{Transport: transport}
-	req, err := http.NewRequest(http.MethodPost, sender.URL, bytes.NewReader(exportData))
+	ctx, span := startExportSpan(edgexcontext, "http export", tracing.SpanKindClient, exportData)
+	defer func() { endExportSpan(span, result) }()
+	span.SetAttribute(tracing.AttributeHTTPURL, sender.URL)
+	span.SetAttribute(tracing.AttributeHTTPMethod, http.MethodPost)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.URL, bytes.NewReader(exportData))
 	if err != nil {
 		return false, err
 	}
+	tracing.Inject(ctx, req.Header)
 	var theSecrets map[string]string
 	if usingSecrets {
 		theSecrets, err = edgexcontext.GetSecrets(sender.SecretPath, sender.SecretHeaderName)
@@ -228,15 +245,16 @@ func (sender HTTPSender) HTTPSPost(edgexcontext *appcontext.Context, params ...i
 	response, err := client.Do(req)
 	if err != nil {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, err
+		return false, appcontext.NewRetryableError(err)
 	}
 	defer response.Body.Close()
+	span.SetAttribute(tracing.AttributeHTTPStatusCode, response.StatusCode)
 	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Response: %s", response.Status))
 	edgexcontext.LoggingClient.Debug(fmt.Sprintf("Sent data: %s", string(exportData)))
 	bodyBytes, errReadingBody := ioutil.ReadAll(response.Body)
 	if errReadingBody != nil {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, errReadingBody
+		return false, appcontext.NewRetryableError(errReadingBody)
 	}
 
 	edgexcontext.LoggingClient.Trace("Data exported", "Transport", "HTTP", clients.CorrelationHeader, edgexcontext.CorrelationID)
@@ -244,9 +262,21 @@ func (sender HTTPSender) HTTPSPost(edgexcontext *appcontext.Context, params ...i
 	// continues the pipeline if we get a 2xx response, stops pipeline if non-2xx response
 	if response.StatusCode < 200 || response.StatusCode >= 300 {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, fmt.Errorf("export failed with %d HTTP status code", response.StatusCode)
+		return false, classifyStatusCode(response.StatusCode,
+			fmt.Errorf("export failed with %d HTTP status code", response.StatusCode))
 	}
 
 	return true, bodyBytes
 
 }
+
+// classifyStatusCode classifies the export failure by the response's status code. Client errors are permanent,
+// except for timeouts and rate limiting, since sending the same data again will fail again.
+func classifyStatusCode(statusCode int, err error) error {
+	if statusCode >= 400 && statusCode < 500 &&
+		statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests {
+		return appcontext.NewPermanentError(err)
+	}
+
+	return appcontext.NewRetryableError(err)
+}
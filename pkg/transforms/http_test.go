@@ -17,6 +17,7 @@
 package transforms
 
 import (
+	syscontext "context"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -25,11 +26,13 @@ import (
 	"strings"
 	"testing"
 
-	"github.com/edgexfoundry/app-functions-sdk-go/internal/common"
-	"github.com/edgexfoundry/app-functions-sdk-go/internal/security"
+	"github.com/student3671/app-functions-sdk-go/internal/common"
+	"github.com/student3671/app-functions-sdk-go/internal/security"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/stretchr/testify/require"
 
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -208,3 +211,44 @@ func (s *mockSecretClient) GetSecrets(path string, keys ...string) (map[string]s
 func (s *mockSecretClient) StoreSecrets(path string, secrets map[string]string) error {
 	return nil
 }
+
+func TestHTTPPostTraceParent(t *testing.T) {
+	tracer := tracing.NewTracer(&tracingExporter{}, logClient)
+	tracing.SetTracer(tracer)
+	defer func() {
+		tracing.SetTracer(nil)
+		tracer.Shutdown()
+	}()
+
+	var traceParent string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		traceParent = r.Header.Get(tracing.TraceParentHeader)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(handler))
+	defer ts.Close()
+
+	functionSpan := tracing.SpanContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"}
+	context.SetRequestContext(tracing.ContextWithRemoteParent(syscontext.Background(), functionSpan))
+	defer context.SetRequestContext(nil)
+
+	sender := NewHTTPSender(ts.URL, "", false)
+	continuePipeline, _ := sender.HTTPPost(context, msgStr)
+	require.True(t, continuePipeline)
+
+	received, err := tracing.ParseTraceParent(traceParent)
+	require.NoError(t, err)
+	assert.Equal(t, functionSpan.TraceID, received.TraceID)
+	assert.NotEqual(t, functionSpan.SpanID, received.SpanID, "Expected the export span to be the parent")
+}
+
+type tracingExporter struct{}
+
+func (exporter *tracingExporter) ExportSpans(_ syscontext.Context, _ []tracing.SpanData) error {
+	return nil
+}
+
+func (exporter *tracingExporter) Shutdown(_ syscontext.Context) error {
+	return nil
+}
@@ -17,20 +17,26 @@
 package transforms
 
 import (
+	syscontext "context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients/logger"
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
 	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
 	"github.com/student3671/app-functions-sdk-go/pkg/util"
 )
 
+// tokenPollInterval is how often the request context is checked while waiting for an MQTT operation to complete
+const tokenPollInterval = 100 * time.Millisecond
+
 // MqttConfig contains mqtt client parameters
 type MqttConfig struct {
 	Qos            byte
@@ -108,7 +114,9 @@ func NewMQTTSender(logging logger.LoggingClient, addr models.Addressable, keyCer
 
 // MQTTSend sends data from the previous function to the specified MQTT broker.
 // If no previous function exists, then the event that triggered the pipeline will be used.
-func (sender MQTTSender) MQTTSend(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+// When tracing is enabled a span is recorded for the export. The trace isn't propagated to subscribers
+// since MQTT 3.1.1 messages have no headers to carry it.
+func (sender MQTTSender) MQTTSend(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
 		return false, errors.New("No Data Received")
@@ -119,24 +127,28 @@ func (sender MQTTSender) MQTTSend(edgexcontext *appcontext.Context, params ...in
 		return false, err
 	}
 
+	_, span := startExportSpan(edgexcontext, "mqtt export", tracing.SpanKindProducer, exportData)
+	defer func() { endExportSpan(span, result) }()
+	span.SetAttribute(tracing.AttributeTopic, sender.topic)
+
 	if !sender.client.IsConnected() {
 		edgexcontext.LoggingClient.Info("Connecting to mqtt server")
-		if token := sender.client.Connect(); token.Wait() && token.Error() != nil {
+		if err := waitForToken(edgexcontext.RequestContext(), sender.client.Connect()); err != nil {
 			sender.setRetryData(edgexcontext, exportData)
 			subMessage := "drop event"
 			if sender.persistOnError {
 				subMessage = "persisting Event for later retry"
 			}
-			return false, fmt.Errorf("Could not connect to mqtt server, %s. Error: %s", subMessage, token.Error().Error())
+			return false, appcontext.NewRetryableError(
+				fmt.Errorf("Could not connect to mqtt server, %s. Error: %s", subMessage, err.Error()))
 		}
 		edgexcontext.LoggingClient.Info("Connected to mqtt server")
 	}
 
 	token := sender.client.Publish(sender.topic, sender.opts.Qos, sender.opts.Retain, exportData)
-	token.Wait()
-	if token.Error() != nil {
+	if err := waitForToken(edgexcontext.RequestContext(), token); err != nil {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, token.Error()
+		return false, appcontext.NewRetryableError(err)
 	}
 
 	edgexcontext.LoggingClient.Debug("Sent data to MQTT Broker")
@@ -145,6 +157,17 @@ func (sender MQTTSender) MQTTSend(edgexcontext *appcontext.Context, params ...in
 	return true, nil
 }
 
+// waitForToken waits for the MQTT operation to complete, returning the context's error if it is done first
+func waitForToken(ctx syscontext.Context, token MQTT.Token) error {
+	for !token.WaitTimeout(tokenPollInterval) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+
+	return token.Error()
+}
+
 func (sender MQTTSender) setRetryData(ctx *appcontext.Context, exportData []byte) {
 	if sender.persistOnError {
 		ctx.RetryData = exportData
@@ -27,6 +27,7 @@ import (
 	MQTT "github.com/eclipse/paho.mqtt.golang"
 	"github.com/edgexfoundry/go-mod-core-contracts/clients"
 	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
 	"github.com/student3671/app-functions-sdk-go/pkg/util"
 )
 
@@ -110,15 +111,37 @@ func (sender *MQTTSecretSender) getSecrets(edgexcontext *appcontext.Context) (*m
 	if err != nil {
 		return nil, err
 	}
-	mqttSecrets := &mqttSecrets{
+	mqttSecrets := newMQTTSecrets(secrets)
+
+	return &mqttSecrets, nil
+}
+
+func newMQTTSecrets(secrets map[string]string) mqttSecrets {
+	return mqttSecrets{
 		username:     secrets[MQTTSecretUsername],
 		password:     secrets[MQTTSecretPassword],
 		keypemblock:  []byte(secrets[MQTTSecretClientKey]),
 		certpemblock: []byte(secrets[MQTTSecretClientCert]),
 		capemblock:   []byte(secrets[MQTTSecretCACert]),
 	}
+}
+
+// ConfigureMQTTClientAuth configures the client options to connect to the broker with the auth mode, the same way as
+// the MQTTSecretSender, using the secrets retrieved from its secret path. See MQTTSecretConfig for the auth modes.
+func ConfigureMQTTClientAuth(opts *MQTT.ClientOptions, authMode string, skipCertVerify bool, secrets map[string]string) error {
+	sender := MQTTSecretSender{
+		mqttConfig: MQTTSecretConfig{AuthMode: strings.ToLower(authMode), SkipCertVerify: skipCertVerify},
+		opts:       opts,
+	}
+	if sender.mqttConfig.AuthMode == AuthModeNone {
+		return nil
+	}
 
-	return mqttSecrets, nil
+	mqttSecrets := newMQTTSecrets(secrets)
+	if err := sender.validateSecrets(mqttSecrets); err != nil {
+		return err
+	}
+	return sender.configureMQTTClientForAuth(mqttSecrets)
 }
 func (sender *MQTTSecretSender) validateSecrets(secrets mqttSecrets) error {
 	caCertPool := x509.NewCertPool()
@@ -212,13 +235,14 @@ func (sender *MQTTSecretSender) initializeMQTTClient(edgexcontext *appcontext.Co
 
 func (sender *MQTTSecretSender) connectToBroker(edgexcontext *appcontext.Context, exportData []byte) error {
 	edgexcontext.LoggingClient.Info("Connecting to mqtt server for export")
-	if token := sender.client.Connect(); token.Wait() && token.Error() != nil {
+	if err := waitForToken(edgexcontext.RequestContext(), sender.client.Connect()); err != nil {
 		sender.setRetryData(edgexcontext, exportData)
 		subMessage := "dropping event"
 		if sender.persistOnError {
 			subMessage = "persisting Event for later retry"
 		}
-		return fmt.Errorf("Could not connect to mqtt server for export, %s. Error: %s", subMessage, token.Error().Error())
+		return appcontext.NewRetryableError(
+			fmt.Errorf("Could not connect to mqtt server for export, %s. Error: %s", subMessage, err.Error()))
 	}
 	edgexcontext.LoggingClient.Info("Connected to mqtt server for export")
 	return nil
@@ -226,7 +250,9 @@ func (sender *MQTTSecretSender) connectToBroker(edgexcontext *appcontext.Context
 
 // MQTTSend sends data from the previous function to the specified MQTT broker.
 // If no previous function exists, then the event that triggered the pipeline will be used.
-func (sender *MQTTSecretSender) MQTTSend(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+// When tracing is enabled a span is recorded for the export. The trace isn't propagated to subscribers
+// since MQTT 3.1.1 messages have no headers to carry it.
+func (sender *MQTTSecretSender) MQTTSend(edgexcontext *appcontext.Context, params ...interface{}) (continuePipeline bool, result interface{}) {
 	if len(params) < 1 {
 		// We didn't receive a result
 		return false, errors.New("No Data Received")
@@ -236,6 +262,10 @@ func (sender *MQTTSecretSender) MQTTSend(edgexcontext *appcontext.Context, param
 	if err != nil {
 		return false, err
 	}
+
+	_, span := startExportSpan(edgexcontext, "mqtt export", tracing.SpanKindProducer, exportData)
+	defer func() { endExportSpan(span, result) }()
+	span.SetAttribute(tracing.AttributeTopic, sender.mqttConfig.Topic)
 	// if we havent initialized the client yet OR the cache has been invalidated (due to new/updated secrets) we need to (re)initialize the client
 	if sender.client == nil || sender.secretsLastRetrieved.Before(edgexcontext.SecretProvider.SecretsLastUpdated()) {
 		err := sender.initializeMQTTClient(edgexcontext)
@@ -251,10 +281,9 @@ func (sender *MQTTSecretSender) MQTTSend(edgexcontext *appcontext.Context, param
 	}
 
 	token := sender.client.Publish(sender.mqttConfig.Topic, sender.mqttConfig.QoS, sender.mqttConfig.Retain, exportData)
-	token.Wait()
-	if token.Error() != nil {
+	if err := waitForToken(edgexcontext.RequestContext(), token); err != nil {
 		sender.setRetryData(edgexcontext, exportData)
-		return false, token.Error()
+		return false, appcontext.NewRetryableError(err)
 	}
 
 	edgexcontext.LoggingClient.Debug("Sent data to MQTT Broker")
@@ -17,6 +17,9 @@
 package transforms
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/student3671/app-functions-sdk-go/pkg/util"
 
 	"github.com/student3671/app-functions-sdk-go/appcontext"
@@ -24,6 +27,8 @@ import (
 
 // OutputData houses transform for outputting data to configured trigger response, i.e. message bus
 type OutputData struct {
+	// ResponseContentType is the content type of the output data, which is JSON if not set
+	ResponseContentType string
 }
 
 // NewOutputData creates, initializes and returns a new instance of OutputData
@@ -31,6 +36,12 @@ func NewOutputData() OutputData {
 	return OutputData{}
 }
 
+// NewOutputDataWithContentType creates, initializes and returns a new instance of OutputData which sets the content
+// type of the output data, i.e. application/xml when the data has been transformed to XML
+func NewOutputDataWithContentType(contentType string) OutputData {
+	return OutputData{ResponseContentType: contentType}
+}
+
 // SetOutputData sets the output data to that passed in from the previous function.
 // It will return an error and stop the pipeline if the input data is not of type []byte, string or json.Mashaler
 func (f OutputData) SetOutputData(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
@@ -48,6 +59,41 @@ func (f OutputData) SetOutputData(edgexcontext *appcontext.Context, params ...in
 	}
 	// By setting this the data will be posted back to to configured trigger response, i.e. message bus
 	edgexcontext.OutputData = data
+	if len(f.ResponseContentType) > 0 {
+		edgexcontext.SetOutputContentType(f.ResponseContentType)
+	}
+
+	return true, params[0]
+}
+
+// OutputTopic houses transform for overriding the topic the output data is published to by the message bus trigger
+type OutputTopic struct {
+	// TopicTemplate is the topic, which may contain placeholders replaced with the fields of the Event,
+	// i.e. 'events/{devicename}'. See appcontext.Context.ExpandTopic for the supported placeholders.
+	TopicTemplate string
+}
+
+// NewOutputTopic creates, initializes and returns a new instance of OutputTopic
+func NewOutputTopic(topicTemplate string) OutputTopic {
+	return OutputTopic{TopicTemplate: topicTemplate}
+}
+
+// SetOutputTopic sets the topic the output data is published to, replacing the placeholders in the topic template
+// with the fields of the data passed in from the previous function. The data is passed to the next function unchanged.
+// It will return an error and stop the pipeline if a placeholder can't be replaced.
+func (f OutputTopic) SetOutputTopic(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		// We didn't receive a result
+		return false, errors.New("No Data Received")
+	}
+
+	topic, err := edgexcontext.ExpandTopic(f.TopicTemplate, params[0])
+	if err != nil {
+		return false, appcontext.NewPermanentError(fmt.Errorf("unable to set output topic: %w", err))
+	}
+
+	edgexcontext.LoggingClient.Debug("Setting output topic", "topic", topic)
+	edgexcontext.SetOutputTopic(topic)
 
 	return true, params[0]
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/student3671/app-functions-sdk-go/appcontext"
 )
 
 func TestSetOutputDataString(t *testing.T) {
@@ -98,3 +99,53 @@ func TestSetOutputDataBadType(t *testing.T) {
 	require.NotNil(t, result)
 	assert.Contains(t, result.(error).Error(), "passed in data must be of type")
 }
+
+func TestSetOutputDataWithContentType(t *testing.T) {
+	ctx := &appcontext.Context{LoggingClient: context.LoggingClient}
+	target := NewOutputDataWithContentType("application/xml")
+
+	continuePipeline, result := target.SetOutputData(ctx, "<Event></Event>")
+	assert.True(t, continuePipeline)
+	assert.NotNil(t, result)
+	assert.Equal(t, "application/xml", ctx.OutputContentType())
+}
+
+func TestSetOutputTopic(t *testing.T) {
+	eventIn := models.Event{ID: "1234", Device: devID1}
+
+	tests := []struct {
+		Name          string
+		Template      string
+		Data          interface{}
+		ExpectedTopic string
+		ErrorExpected bool
+	}{
+		{"No placeholders", "events", eventIn, "events", false},
+		{"Device name", "events/{devicename}", eventIn, "events/id1", false},
+		{"Event pointer", "events/{DeviceName}/{eventid}", &eventIn, "events/id1/1234", false},
+		{"Correlation ID", "events/{correlationid}", "data", "events/123-234", false},
+		{"Not an Event", "events/{devicename}", "data", "", true},
+		{"No value", "events/{origin}", eventIn, "", true},
+		{"Unknown placeholder", "events/{unknown}", eventIn, "", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx := &appcontext.Context{LoggingClient: context.LoggingClient, CorrelationID: "123-234"}
+			target := NewOutputTopic(test.Template)
+
+			continuePipeline, result := target.SetOutputTopic(ctx, test.Data)
+			if test.ErrorExpected {
+				assert.False(t, continuePipeline)
+				require.IsType(t, &appcontext.PipelineError{}, result)
+				assert.Equal(t, appcontext.ErrorKindPermanent, appcontext.ErrorKindOf(result.(error)))
+				assert.Empty(t, ctx.OutputTopic())
+				return
+			}
+
+			assert.True(t, continuePipeline)
+			assert.Equal(t, test.Data, result)
+			assert.Equal(t, test.ExpectedTopic, ctx.OutputTopic())
+		})
+	}
+}
@@ -0,0 +1,312 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/clients"
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/util"
+)
+
+// Modes of the RateLimit function, which determine what is done with data exceeding the limit
+const (
+	// RateLimitModeDelay waits until the data is within the limit before continuing the pipeline
+	RateLimitModeDelay = "delay"
+	// RateLimitModeDrop stops the pipeline for the data without it being treated as a failure
+	RateLimitModeDrop = "drop"
+	// RateLimitModeStore stops the pipeline with a retryable error and sets the data as RetryData, so that it is
+	// retried by Store and Forward when enabled
+	RateLimitModeStore = "store"
+)
+
+// Keys the per-key limits of the RateLimiter can be applied by. Any other key is the key of a value on the Context,
+// i.e. 'receivedtopic', 'remoteaddress' or 'header.x-client-id'.
+const (
+	// RateLimitKeyDevice limits per device name of the Event
+	RateLimitKeyDevice = "device"
+	// RateLimitKeyCorrelationID limits per correlation ID of the message
+	RateLimitKeyCorrelationID = "correlationid"
+)
+
+// ErrRateLimitExceeded is returned when data exceeds the rate limit and isn't delayed
+var ErrRateLimitExceeded = errors.New("rate limit exceeded")
+
+// maxIdleKeyBuckets is the number of per-key buckets above which those which have refilled are removed
+const maxIdleKeyBuckets = 1024
+
+// tokenBucket holds up to burst tokens, refilled at rate tokens per second. Tokens may go negative when reserved
+// ahead of time, which delays the following reservations.
+type tokenBucket struct {
+	rate    float64
+	burst   float64
+	tokens  float64
+	updated time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), updated: now}
+}
+
+// refill adds the tokens accumulated since the last update, up to burst
+func (bucket *tokenBucket) refill(now time.Time) {
+	if now.After(bucket.updated) {
+		bucket.tokens = math.Min(bucket.burst, bucket.tokens+now.Sub(bucket.updated).Seconds()*bucket.rate)
+		bucket.updated = now
+	}
+}
+
+// wait returns how long until a token is available
+func (bucket *tokenBucket) wait() time.Duration {
+	if bucket.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - bucket.tokens) / bucket.rate * float64(time.Second))
+}
+
+// full returns true if the bucket has refilled, so it can be discarded without changing the limit
+func (bucket *tokenBucket) full() bool {
+	return bucket.tokens >= bucket.burst
+}
+
+// RateLimiter is a token bucket rate limiter with a global limit and an optional limit per key, i.e. per device.
+// Both limits must allow the data for it to pass. It is safe for concurrent use.
+type RateLimiter struct {
+	lock     sync.Mutex
+	global   *tokenBucket
+	keyRate  float64
+	keyBurst int
+	buckets  map[string]*tokenBucket
+	now      func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter allowing rate messages per second with bursts of up to burst messages, and
+// keyRate messages per second with bursts of up to keyBurst per key. A rate of 0 disables the corresponding limit.
+// The burst defaults to the rate, rounded up, when not set.
+func NewRateLimiter(rate float64, burst int, keyRate float64, keyBurst int) (*RateLimiter, error) {
+	if rate < 0 || keyRate < 0 {
+		return nil, errors.New("rate limit must not be negative")
+	}
+	if burst < 0 || keyBurst < 0 {
+		return nil, errors.New("rate limit burst must not be negative")
+	}
+	if rate == 0 && keyRate == 0 {
+		return nil, errors.New("rate limit requires a rate, a per key rate or both")
+	}
+
+	limiter := &RateLimiter{
+		keyRate:  keyRate,
+		keyBurst: defaultBurst(keyRate, keyBurst),
+		buckets:  make(map[string]*tokenBucket),
+		now:      time.Now,
+	}
+	if rate > 0 {
+		limiter.global = newTokenBucket(rate, defaultBurst(rate, burst), limiter.now())
+	}
+
+	return limiter, nil
+}
+
+func defaultBurst(rate float64, burst int) int {
+	if burst > 0 {
+		return burst
+	}
+
+	return int(math.Ceil(rate))
+}
+
+// Allow takes a token for the key when both the global and per key limits have one available. Otherwise false is
+// returned along with how long until they do. The per key limit isn't applied when the key is empty.
+func (limiter *RateLimiter) Allow(key string) (bool, time.Duration) {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	buckets := limiter.bucketsFor(key)
+	var retryAfter time.Duration
+	for _, bucket := range buckets {
+		if wait := bucket.wait(); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter > 0 {
+		return false, retryAfter
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return true, 0
+}
+
+// Reserve takes a token for the key from both the global and per key limits, whether or not available, and returns
+// how long to wait before the data is within the limits. The per key limit isn't applied when the key is empty.
+func (limiter *RateLimiter) Reserve(key string) time.Duration {
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	var delay time.Duration
+	for _, bucket := range limiter.bucketsFor(key) {
+		if wait := bucket.wait(); wait > delay {
+			delay = wait
+		}
+		bucket.tokens--
+	}
+
+	return delay
+}
+
+// bucketsFor returns the refilled buckets which apply to the key, creating the key's bucket if needed
+func (limiter *RateLimiter) bucketsFor(key string) []*tokenBucket {
+	now := limiter.now()
+	buckets := make([]*tokenBucket, 0, 2)
+	if limiter.global != nil {
+		limiter.global.refill(now)
+		buckets = append(buckets, limiter.global)
+	}
+
+	if limiter.keyRate == 0 || len(key) == 0 {
+		return buckets
+	}
+
+	bucket, ok := limiter.buckets[key]
+	if ok {
+		bucket.refill(now)
+	} else {
+		if len(limiter.buckets) >= maxIdleKeyBuckets {
+			limiter.removeFullBuckets(now)
+		}
+		bucket = newTokenBucket(limiter.keyRate, limiter.keyBurst, now)
+		limiter.buckets[key] = bucket
+	}
+
+	return append(buckets, bucket)
+}
+
+// removeFullBuckets removes the per key buckets which have refilled, since they are recreated full when needed
+func (limiter *RateLimiter) removeFullBuckets(now time.Time) {
+	for key, bucket := range limiter.buckets {
+		bucket.refill(now)
+		if bucket.full() {
+			delete(limiter.buckets, key)
+		}
+	}
+}
+
+// RateLimit limits the rate at which data continues through the pipeline
+type RateLimit struct {
+	Limiter *RateLimiter
+	// Key is what the per key limit of the Limiter is applied by. See RateLimitKeyDevice for the supported keys.
+	Key string
+	// Mode is what is done with data exceeding the limit. See RateLimitModeDelay for the supported modes.
+	Mode string
+}
+
+// NewRateLimit creates, initializes and returns a new instance of RateLimit
+func NewRateLimit(limiter *RateLimiter, key string, mode string) (RateLimit, error) {
+	if limiter == nil {
+		return RateLimit{}, errors.New("rate limiter is required")
+	}
+
+	mode = strings.ToLower(mode)
+	if len(mode) == 0 {
+		mode = RateLimitModeDelay
+	}
+	switch mode {
+	case RateLimitModeDelay, RateLimitModeDrop, RateLimitModeStore:
+	default:
+		return RateLimit{}, fmt.Errorf("rate limit mode '%s' is not supported", mode)
+	}
+
+	return RateLimit{Limiter: limiter, Key: strings.ToLower(key), Mode: mode}, nil
+}
+
+// Limit passes the data on unchanged once within the rate limit. Data exceeding the limit is delayed, dropped or
+// stored for later retry depending on the Mode. The device key requires an Event to be received.
+func (rateLimit RateLimit) Limit(edgexcontext *appcontext.Context, params ...interface{}) (bool, interface{}) {
+	if len(params) < 1 {
+		return false, errors.New("no data received")
+	}
+
+	key, err := rateLimitKey(edgexcontext, rateLimit.Key, params[0])
+	if err != nil {
+		return false, appcontext.NewPermanentError(err)
+	}
+
+	if rateLimit.Mode == RateLimitModeDelay {
+		delay := rateLimit.Limiter.Reserve(key)
+		if delay <= 0 {
+			return true, params[0]
+		}
+
+		edgexcontext.LoggingClient.Debug("Delaying data to within the rate limit", "delay", delay.String(),
+			clients.CorrelationHeader, edgexcontext.CorrelationID)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			return true, params[0]
+		case <-edgexcontext.RequestContext().Done():
+			return false, appcontext.NewRetryableError(edgexcontext.RequestContext().Err())
+		}
+	}
+
+	if allowed, _ := rateLimit.Limiter.Allow(key); allowed {
+		return true, params[0]
+	}
+
+	if rateLimit.Mode == RateLimitModeDrop {
+		return false, appcontext.NewFilteredError(ErrRateLimitExceeded.Error())
+	}
+
+	data, err := util.CoerceType(params[0])
+	if err != nil {
+		return false, err
+	}
+	edgexcontext.SetRetryData(data)
+	return false, appcontext.NewRetryableError(ErrRateLimitExceeded)
+}
+
+// rateLimitKey returns the value of the data or context the per key limit is applied by, empty if not set
+func rateLimitKey(edgexcontext *appcontext.Context, key string, data interface{}) (string, error) {
+	switch key {
+	case "":
+		return "", nil
+	case RateLimitKeyDevice:
+		switch event := data.(type) {
+		case models.Event:
+			return event.Device, nil
+		case *models.Event:
+			return event.Device, nil
+		default:
+			return "", errors.New("rate limit by device requires an Event to be received")
+		}
+	case RateLimitKeyCorrelationID:
+		return edgexcontext.CorrelationID, nil
+	default:
+		value, _ := edgexcontext.StringValue(key)
+		return value, nil
+	}
+}
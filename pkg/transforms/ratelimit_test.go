@@ -0,0 +1,207 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/edgexfoundry/go-mod-core-contracts/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+)
+
+// newTestRateLimiter returns a RateLimiter whose clock only advances when the returned function is called
+func newTestRateLimiter(t *testing.T, rate float64, burst int, keyRate float64, keyBurst int) (*RateLimiter, func(time.Duration)) {
+	limiter, err := NewRateLimiter(rate, burst, keyRate, keyBurst)
+	require.NoError(t, err)
+
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	return limiter, func(elapsed time.Duration) { now = now.Add(elapsed) }
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	_, err := NewRateLimiter(0, 0, 0, 0)
+	assert.Error(t, err, "A rate is required")
+	_, err = NewRateLimiter(-1, 0, 0, 0)
+	assert.Error(t, err)
+	_, err = NewRateLimiter(1, -1, 0, 0)
+	assert.Error(t, err)
+
+	limiter, err := NewRateLimiter(2.5, 0, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, float64(3), limiter.global.burst, "Burst should default to the rate rounded up")
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, 10, 2, 0, 0)
+
+	allowed, _ := limiter.Allow("")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow("")
+	assert.True(t, allowed)
+	allowed, retryAfter := limiter.Allow("")
+	assert.False(t, allowed, "Burst should be exhausted")
+	assert.Equal(t, 100*time.Millisecond, retryAfter)
+
+	advance(100 * time.Millisecond)
+	allowed, _ = limiter.Allow("")
+	assert.True(t, allowed, "Token should have been refilled")
+}
+
+func TestRateLimiterPerKey(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, 100, 3, 1, 1)
+
+	allowed, _ := limiter.Allow(devID1)
+	assert.True(t, allowed)
+	allowed, retryAfter := limiter.Allow(devID1)
+	assert.False(t, allowed, "Per key limit should apply")
+	assert.Equal(t, time.Second, retryAfter)
+	allowed, _ = limiter.Allow(devID2)
+	assert.True(t, allowed, "Other keys have their own limit")
+	allowed, _ = limiter.Allow("")
+	assert.True(t, allowed, "Empty key is only limited globally")
+	allowed, _ = limiter.Allow("")
+	assert.False(t, allowed, "Global limit should apply across keys")
+
+	advance(time.Second)
+	allowed, _ = limiter.Allow(devID1)
+	assert.True(t, allowed)
+}
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, 10, 1, 0, 0)
+
+	assert.Equal(t, time.Duration(0), limiter.Reserve(""))
+	assert.Equal(t, 100*time.Millisecond, limiter.Reserve(""))
+	assert.Equal(t, 200*time.Millisecond, limiter.Reserve(""), "Reservations should queue behind each other")
+
+	advance(300 * time.Millisecond)
+	assert.Equal(t, time.Duration(0), limiter.Reserve(""))
+}
+
+func TestRateLimiterRemovesFullBuckets(t *testing.T) {
+	limiter, advance := newTestRateLimiter(t, 0, 0, 1, 1)
+
+	for index := 0; index < maxIdleKeyBuckets; index++ {
+		limiter.Reserve(fmt.Sprintf("device-%d", index))
+	}
+	require.Len(t, limiter.buckets, maxIdleKeyBuckets)
+
+	advance(time.Second)
+	limiter.Reserve("new")
+	assert.Len(t, limiter.buckets, 1, "Refilled buckets should be removed")
+}
+
+func TestNewRateLimit(t *testing.T) {
+	limiter, _ := newTestRateLimiter(t, 1, 1, 0, 0)
+
+	rateLimit, err := NewRateLimit(limiter, "Device", "")
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitModeDelay, rateLimit.Mode)
+	assert.Equal(t, RateLimitKeyDevice, rateLimit.Key)
+
+	_, err = NewRateLimit(limiter, "", "discard")
+	assert.Error(t, err)
+	_, err = NewRateLimit(nil, "", RateLimitModeDrop)
+	assert.Error(t, err)
+}
+
+func TestRateLimitModes(t *testing.T) {
+	event := models.Event{Device: devID1}
+
+	tests := []struct {
+		Name          string
+		Mode          string
+		ExpectedKind  appcontext.ErrorKind
+		ExpectedRetry bool
+	}{
+		{"Drop", RateLimitModeDrop, appcontext.ErrorKindFiltered, false},
+		{"Store", RateLimitModeStore, appcontext.ErrorKindRetryable, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			limiter, _ := newTestRateLimiter(t, 0, 0, 1, 1)
+			rateLimit, err := NewRateLimit(limiter, RateLimitKeyDevice, test.Mode)
+			require.NoError(t, err)
+
+			edgexcontext := &appcontext.Context{LoggingClient: context.LoggingClient}
+			continuePipeline, result := rateLimit.Limit(edgexcontext, event)
+			require.True(t, continuePipeline)
+			assert.Equal(t, event, result)
+
+			continuePipeline, _ = rateLimit.Limit(edgexcontext, models.Event{Device: devID2})
+			assert.True(t, continuePipeline, "Other devices should not be limited")
+
+			continuePipeline, result = rateLimit.Limit(edgexcontext, event)
+			require.False(t, continuePipeline)
+			err, ok := result.(error)
+			require.True(t, ok)
+			assert.Contains(t, err.Error(), ErrRateLimitExceeded.Error())
+			assert.Equal(t, test.ExpectedKind, appcontext.ErrorKindOf(err))
+			assert.Equal(t, test.ExpectedRetry, edgexcontext.RetryData != nil)
+		})
+	}
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	limiter, err := NewRateLimiter(20, 1, 0, 0)
+	require.NoError(t, err)
+	rateLimit, err := NewRateLimit(limiter, "", RateLimitModeDelay)
+	require.NoError(t, err)
+
+	edgexcontext := &appcontext.Context{LoggingClient: context.LoggingClient}
+	start := time.Now()
+	for index := 0; index < 3; index++ {
+		continuePipeline, result := rateLimit.Limit(edgexcontext, []byte("data"))
+		require.True(t, continuePipeline)
+		assert.Equal(t, []byte("data"), result)
+	}
+	assert.True(t, time.Since(start) >= 90*time.Millisecond, "Data exceeding the limit should have been delayed")
+
+	ctx, cancel := syscontext.WithCancel(syscontext.Background())
+	cancel()
+	edgexcontext.SetRequestContext(ctx)
+	continuePipeline, result := rateLimit.Limit(edgexcontext, []byte("data"))
+	require.False(t, continuePipeline, "Delay should stop when the request context is done")
+	assert.Equal(t, appcontext.ErrorKindRetryable, appcontext.ErrorKindOf(result.(error)))
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	edgexcontext := &appcontext.Context{LoggingClient: context.LoggingClient, CorrelationID: "correlation-1"}
+	require.NoError(t, edgexcontext.SetValue(appcontext.ValueKeyReceivedTopic, "events/id1"))
+
+	key, err := rateLimitKey(edgexcontext, RateLimitKeyDevice, &models.Event{Device: devID1})
+	require.NoError(t, err)
+	assert.Equal(t, devID1, key)
+	_, err = rateLimitKey(edgexcontext, RateLimitKeyDevice, []byte("data"))
+	assert.Error(t, err)
+
+	key, _ = rateLimitKey(edgexcontext, RateLimitKeyCorrelationID, nil)
+	assert.Equal(t, "correlation-1", key)
+	key, _ = rateLimitKey(edgexcontext, appcontext.ValueKeyReceivedTopic, nil)
+	assert.Equal(t, "events/id1", key)
+	key, _ = rateLimitKey(edgexcontext, "header.x-api-key", nil)
+	assert.Empty(t, key)
+}
@@ -0,0 +1,43 @@
+//
+// Copyright (c) 2020 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package transforms
+
+import (
+	syscontext "context"
+
+	"github.com/student3671/app-functions-sdk-go/appcontext"
+	"github.com/student3671/app-functions-sdk-go/pkg/tracing"
+)
+
+// startExportSpan starts the span for exporting the data as a child of the pipeline function's span
+func startExportSpan(edgexcontext *appcontext.Context, name string, kind tracing.SpanKind,
+	exportData []byte) (syscontext.Context, *tracing.Span) {
+
+	ctx, span := tracing.StartSpan(edgexcontext.RequestContext(), name, kind)
+	span.SetAttribute(tracing.AttributeCorrelationID, edgexcontext.CorrelationID)
+	span.SetAttribute(tracing.AttributePayloadSize, len(exportData))
+
+	return ctx, span
+}
+
+// endExportSpan ends the export's span, setting the error status if the export function's result is an error
+func endExportSpan(span *tracing.Span, result interface{}) {
+	if err, ok := result.(error); ok {
+		span.SetError(err)
+	}
+	span.End()
+}